@@ -2,15 +2,25 @@ package auth
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
-	"shopping_list/db"
+	"shopping_list/config"
+	"shopping_list/invitations"
 	"shopping_list/middleware"
+	"shopping_list/store"
+	"shopping_list/tokens"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var stores *store.Stores
+
+// SetStores injects the storage backends used by the auth handlers
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
 type UserLogin struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
@@ -24,42 +34,51 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var hashedPassword string
-	var name string
-	err := db.DB.QueryRow("SELECT password, name FROM users WHERE email = ? AND deleted_at IS NULL", user.Email).Scan(&hashedPassword, &name)
+	storedUser, err := stores.Users.GetUserByEmail(r.Context(), user.Email)
 	if err != nil {
 		http.Error(w, "Invalid email or password", http.StatusUnauthorized)
 		return
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(user.Password))
+	err = bcrypt.CompareHashAndPassword([]byte(storedUser.PasswordHash), []byte(user.Password))
 	if err != nil {
 		http.Error(w, "Invalid email or password", http.StatusUnauthorized)
 		return
 	}
 
-	// Generate JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"email": user.Email,
-		"exp":   time.Now().Add(time.Hour * 72).Unix(), // Token expires in 72 hours
-	})
+	// Start a new refresh token family for this login
+	familyID, err := tokens.NewID()
+	if err != nil {
+		http.Error(w, "Error generating token", http.StatusInternalServerError)
+		return
+	}
 
-	tokenString, err := token.SignedString([]byte("your_secret_key")) // Replace with your secret key
+	refreshToken, hash, err := tokens.NewRefreshToken()
 	if err != nil {
 		http.Error(w, "Error generating token", http.StatusInternalServerError)
 		return
 	}
 
-	// Store token in the database (you may need to create a new column for tokens)
-	_, err = db.DB.Exec("UPDATE users SET token = ? WHERE email = ?", tokenString, user.Email)
+	err = stores.Tokens.CreateRefreshToken(r.Context(), &store.RefreshToken{
+		UserID:    storedUser.ID,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(config.JWT.RefreshExpiry),
+	})
 	if err != nil {
 		http.Error(w, "Error storing token", http.StatusInternalServerError)
 		return
 	}
 
-	// Respond with the token
+	tokenString, err := issueAccessToken(r, storedUser, familyID)
+	if err != nil {
+		http.Error(w, "Error generating token", http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the tokens
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"token": tokenString, "name": name, "email": user.Email})
+	json.NewEncoder(w).Encode(map[string]string{"token": tokenString, "refresh_token": refreshToken, "name": storedUser.Name, "email": user.Email})
 }
 
 type UserRegister struct {
@@ -81,26 +100,46 @@ func Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = db.DB.Exec("INSERT INTO users (email, password, name) VALUES (?, ?, ?)", user.Email, hashedPassword, user.Name)
+	newUser := store.User{Email: user.Email, PasswordHash: string(hashedPassword), Name: user.Name}
+	err = stores.Users.CreateUser(r.Context(), &newUser)
 	if err != nil {
 		http.Error(w, "Error registering user", http.StatusInternalServerError)
 		return
 	}
 
+	// Join the workspaces the email was invited to before the account existed.
+	// The account is already created, so a failure here must not fail the registration.
+	if _, err := stores.Invitations.AcceptPendingInvitations(r.Context(), invitations.NormalizeEmail(user.Email), newUser.ID); err != nil {
+		log.Printf("error accepting invitations for user %d: %v", newUser.ID, err)
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode("User registered successfully")
 }
 
 func Logout(w http.ResponseWriter, r *http.Request) {
-	// Get user ID from the token
-	loggedInUserID, err := middleware.ExtractUserIDFromToken(r)
-	if err != nil {
+	// Get the authenticated user from the request context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
+	// Revoke this access token and the refresh tokens issued with this session
+	err := stores.Tokens.RevokeAccessToken(r.Context(), principal.TokenID, principal.UserID, principal.ExpiresAt.Add(config.JWT.Leeway))
+	if err != nil {
+		http.Error(w, "Error logging out", http.StatusInternalServerError)
+		return
+	}
+	if principal.SessionID != "" {
+		if err := stores.Tokens.RevokeRefreshTokenFamily(r.Context(), principal.SessionID); err != nil {
+			http.Error(w, "Error logging out", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	// Update the token to NULL
-	_, err = db.DB.Exec("UPDATE users SET token = NULL WHERE id = ?", loggedInUserID)
+	err = stores.Users.SetUserToken(r.Context(), principal.UserID, nil)
 	if err != nil {
 		http.Error(w, "Error logging out", http.StatusInternalServerError)
 		return
@@ -109,3 +148,21 @@ func Logout(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode("Logged out successfully")
 }
+
+// LogoutAll invalidates every access and refresh token issued to the user
+func LogoutAll(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated user from the request context
+	loggedInUserID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := stores.Tokens.RevokeUserTokens(r.Context(), loggedInUserID); err != nil {
+		http.Error(w, "Error logging out", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Logged out of all devices successfully")
+}
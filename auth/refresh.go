@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"shopping_list/config"
+	"shopping_list/store"
+	"shopping_list/tokens"
+	"time"
+)
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh token.
+// Presenting a refresh token that was already rotated revokes its whole family,
+// since it means the token has leaked.
+func Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	current, err := stores.Tokens.GetRefreshTokenByHash(r.Context(), tokens.HashRefreshToken(req.RefreshToken))
+	if err != nil {
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	if current.RevokedAt != nil {
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	// Reuse of a rotated token, revoke the whole chain
+	if current.UsedAt != nil {
+		revokeFamily(w, r, current.FamilyID)
+		return
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		http.Error(w, "Refresh token expired", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := stores.Users.GetUserByID(r.Context(), current.UserID)
+	if err != nil {
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	refreshToken, hash, err := tokens.NewRefreshToken()
+	if err != nil {
+		http.Error(w, "Error generating token", http.StatusInternalServerError)
+		return
+	}
+
+	next := &store.RefreshToken{
+		UserID:    current.UserID,
+		FamilyID:  current.FamilyID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(config.JWT.RefreshExpiry),
+	}
+	err = stores.Tokens.RotateRefreshToken(r.Context(), current.ID, next)
+	if errors.Is(err, store.ErrTokenUsed) {
+		// A concurrent request rotated the same token
+		revokeFamily(w, r, current.FamilyID)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error storing token", http.StatusInternalServerError)
+		return
+	}
+
+	tokenString, err := issueAccessToken(r, user, current.FamilyID)
+	if err != nil {
+		http.Error(w, "Error generating token", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"token": tokenString, "refresh_token": refreshToken})
+}
+
+// issueAccessToken signs an access token for the user and stores it as the user's current token
+func issueAccessToken(r *http.Request, user *store.User, familyID string) (string, error) {
+	tokenString, err := tokens.Sign(user.ID, user.Email, familyID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := stores.Users.SetUserToken(r.Context(), user.ID, &tokenString); err != nil {
+		return "", err
+	}
+	return tokenString, nil
+}
+
+// revokeFamily revokes every refresh token of a family after a reuse was detected
+func revokeFamily(w http.ResponseWriter, r *http.Request, familyID string) {
+	if err := stores.Tokens.RevokeRefreshTokenFamily(r.Context(), familyID); err != nil {
+		http.Error(w, "Error revoking tokens", http.StatusInternalServerError)
+		return
+	}
+	http.Error(w, "Refresh token reuse detected, please log in again", http.StatusUnauthorized)
+}
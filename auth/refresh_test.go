@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shopping_list/config"
+	"shopping_list/store"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// setup injects a memory store holding a user who logs in with password "secret"
+func setup(t *testing.T) {
+	t.Helper()
+	config.JWT = config.JWTConfig{
+		Secret:        []byte(strings.Repeat("k", 32)),
+		Issuer:        "shopping_list",
+		Audience:      "shopping_list",
+		Expiry:        time.Minute,
+		RefreshExpiry: time.Hour,
+	}
+	s := store.NewMemory()
+	SetStores(s)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %s", err)
+	}
+	if err := s.Users.CreateUser(context.Background(), &store.User{Email: "a@example.com", Name: "A", PasswordHash: string(hash)}); err != nil {
+		t.Fatalf("CreateUser: %s", err)
+	}
+}
+
+// post sends a JSON body to a handler and decodes the tokens of a successful response
+func post(t *testing.T, handler http.HandlerFunc, body string) (*httptest.ResponseRecorder, map[string]string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	var response map[string]string
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("decoding response: %s", err)
+		}
+	}
+	return w, response
+}
+
+// login returns the refresh token of a new login
+func login(t *testing.T) string {
+	t.Helper()
+	w, response := post(t, Login, `{"email":"a@example.com","password":"secret"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login status = %d: %s", w.Code, w.Body)
+	}
+	return response["refresh_token"]
+}
+
+// refresh exchanges a refresh token and returns the status and the next refresh token
+func refresh(t *testing.T, refreshToken string) (int, string) {
+	t.Helper()
+	body, err := json.Marshal(RefreshRequest{RefreshToken: refreshToken})
+	if err != nil {
+		t.Fatalf("encoding request: %s", err)
+	}
+	w, response := post(t, Refresh, string(body))
+	if w.Code == http.StatusOK && (response["token"] == "" || response["refresh_token"] == "") {
+		t.Fatalf("refresh response = %v, want both tokens", response)
+	}
+	return w.Code, response["refresh_token"]
+}
+
+func TestRefreshRotates(t *testing.T) {
+	setup(t)
+	first := login(t)
+
+	status, second := refresh(t, first)
+	if status != http.StatusOK {
+		t.Fatalf("refresh status = %d, want %d", status, http.StatusOK)
+	}
+	if second == first {
+		t.Fatal("the refresh token was not rotated")
+	}
+	status, third := refresh(t, second)
+	if status != http.StatusOK {
+		t.Fatalf("refresh of the rotated token status = %d, want %d", status, http.StatusOK)
+	}
+	if third == second || third == first {
+		t.Fatal("the refresh token was not rotated")
+	}
+}
+
+func TestRefreshReuseRevokesTheFamily(t *testing.T) {
+	setup(t)
+	first := login(t)
+	other := login(t)
+
+	_, second := refresh(t, first)
+	_, third := refresh(t, second)
+
+	// The first token leaked and is presented again after it was rotated
+	if status, _ := refresh(t, first); status != http.StatusUnauthorized {
+		t.Fatalf("reuse status = %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status, _ := refresh(t, third); status != http.StatusUnauthorized {
+		t.Errorf("latest token of the family status = %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	// Logins of other devices are other families
+	if status, _ := refresh(t, other); status != http.StatusOK {
+		t.Errorf("token of another login status = %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestRefreshRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"empty body", "", http.StatusBadRequest},
+		{"missing token", "{}", http.StatusBadRequest},
+		{"unknown token", `{"refresh_token":"unknown"}`, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setup(t)
+			login(t)
+			w, _ := post(t, Refresh, tt.body)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
+
+func TestRefreshExpired(t *testing.T) {
+	setup(t)
+	config.JWT.RefreshExpiry = -time.Minute
+	if status, _ := refresh(t, login(t)); status != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,189 @@
+package categories
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"shopping_list/store"
+
+	"github.com/gorilla/mux"
+)
+
+type Category = store.Category
+
+var stores *store.Stores
+
+// SetStores injects the storage backends used by the category handlers
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
+type defaultResponse struct {
+	Status string      `json:"status"`
+	Data   interface{} `json:"data"`
+}
+
+// CategoryRequest represents the request body for creating or updating a category
+type CategoryRequest struct {
+	Name     string `json:"name"`
+	Position *int   `json:"position"`
+}
+
+// ListCategories returns the categories of the workspace in their default order
+func ListCategories(w http.ResponseWriter, r *http.Request) {
+	// Get workspace ID from URL parameters
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	categories, err := stores.Categories.ListCategories(r.Context(), workspaceID)
+	if err != nil {
+		http.Error(w, "Error fetching categories", http.StatusInternalServerError)
+		return
+	}
+	if categories == nil {
+		categories = []Category{}
+	}
+
+	response := defaultResponse{
+		Data:   categories,
+		Status: "Success",
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateCategory adds a category to the workspace
+func CreateCategory(w http.ResponseWriter, r *http.Request) {
+	// Get workspace ID from URL parameters
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	var req CategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	category := Category{WorkspaceID: workspaceID, Name: name}
+	if req.Position != nil {
+		category.Position = *req.Position
+	}
+
+	err = stores.Categories.CreateCategory(r.Context(), &category)
+	if errors.Is(err, store.ErrDuplicate) {
+		http.Error(w, "A category with this name already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error creating category", http.StatusInternalServerError)
+		return
+	}
+
+	response := defaultResponse{
+		Data:   category,
+		Status: "Success",
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateCategory renames a category or changes its position, omitted fields are kept
+func UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	category, ok := categoryFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req CategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if name := strings.TrimSpace(req.Name); name != "" {
+		category.Name = name
+	}
+	if req.Position != nil {
+		category.Position = *req.Position
+	}
+
+	err := stores.Categories.UpdateCategory(r.Context(), category)
+	if errors.Is(err, store.ErrDuplicate) {
+		http.Error(w, "A category with this name already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error updating category", http.StatusInternalServerError)
+		return
+	}
+
+	response := defaultResponse{
+		Data:   category,
+		Status: "Success",
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteCategory removes a category, its products become uncategorized
+func DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	category, ok := categoryFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := stores.Categories.DeleteCategory(r.Context(), category.ID); err != nil {
+		http.Error(w, "Error deleting category", http.StatusInternalServerError)
+		return
+	}
+
+	response := defaultResponse{
+		Data:   "Category successfully deleted",
+		Status: "Success",
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// categoryFromRequest loads the category of the URL and checks it belongs to the workspace,
+// writing the error response if it does not
+func categoryFromRequest(w http.ResponseWriter, r *http.Request) (*Category, bool) {
+	// Get workspace ID and category ID from URL parameters
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return nil, false
+	}
+	categoryID, err := strconv.Atoi(vars["category_id"])
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	category, err := stores.Categories.GetCategory(r.Context(), categoryID)
+	if err != nil || category.WorkspaceID != workspaceID {
+		http.Error(w, "Category not found", http.StatusNotFound)
+		return nil, false
+	}
+	return category, true
+}
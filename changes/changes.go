@@ -0,0 +1,35 @@
+// Package changes implements the sync protocol of offline clients: a feed of the changes made
+// to a workspace since a cursor and a batch endpoint applying the mutations made offline
+package changes
+
+import (
+	"log"
+	"net/http"
+
+	"shopping_list/events"
+	"shopping_list/middleware"
+	"shopping_list/store"
+)
+
+var (
+	stores *store.Stores
+	broker events.Broker
+)
+
+// SetStores injects the storage backends used by the sync handlers
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
+// SetBroker injects the broker notified of the mutations applied by sync
+func SetBroker(b events.Broker) {
+	broker = b
+}
+
+// publish notifies the subscribers of the workspace of a change made by the request
+func publish(r *http.Request, event events.Event) {
+	event.UserID, _ = middleware.UserIDFromContext(r.Context())
+	if err := broker.Publish(r.Context(), event); err != nil {
+		log.Printf("error publishing %s event: %s", event.Type, err)
+	}
+}
@@ -0,0 +1,69 @@
+package changes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"shopping_list/store"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultFeedSize = 500
+	maxFeedSize     = 1000
+)
+
+// Feed is a page of the changes of a workspace
+type Feed struct {
+	Changes []store.Change `json:"changes"`
+	// Cursor is passed as since to get the changes made after this page
+	Cursor  string `json:"cursor"`
+	HasMore bool   `json:"has_more"`
+}
+
+// GetChanges returns the current state of the products, lists and list items of a workspace
+// changed after the since cursor, oldest change first, including soft deleted records. Without
+// since it returns every record, which is how a client starts syncing. Clients keep the
+// returned cursor and ask again while has_more is true.
+func GetChanges(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultFeedSize
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxFeedSize {
+			http.Error(w, "Invalid limit, expected a number between 1 and "+strconv.Itoa(maxFeedSize), http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := stores.Sync.ListChanges(r.Context(), workspaceID, r.URL.Query().Get("since"), limit)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		http.Error(w, "Invalid since cursor", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error fetching changes", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string `json:"status"`
+		Data   Feed   `json:"data"`
+	}{
+		Status: "Success",
+		Data:   Feed{Changes: page.Changes, Cursor: page.Cursor, HasMore: page.More},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,296 @@
+package changes
+
+import (
+	"errors"
+	"net/http"
+
+	"shopping_list/events"
+	"shopping_list/lists"
+	"shopping_list/money"
+	"shopping_list/store"
+)
+
+// ListData holds the fields of a list mutation, fields left out are not changed
+type ListData struct {
+	Title *string `json:"title"`
+	// Status is either active or completed, lists are deleted with list.delete
+	Status *int `json:"status"`
+	// Budget is an optional decimal amount in the workspace currency, only used when creating a list
+	Budget money.Decimal `json:"budget"`
+}
+
+// ItemData holds the fields of a list item mutation, fields left out are not changed
+type ItemData struct {
+	// Quantity replaces the quantity, QuantityDelta adds to it or, when negative, takes from it
+	Quantity      *int                   `json:"quantity"`
+	QuantityDelta int                    `json:"quantity_delta"`
+	Checked       *bool                  `json:"checked"`
+	Purchase      *lists.PurchaseRequest `json:"purchase"`
+}
+
+func (b *batch) createList(r *http.Request, m Mutation) MutationResult {
+	var data ListData
+	if err := decodeData(m, &data); err != nil {
+		return result(m, StatusRejected, "Invalid list data")
+	}
+	if data.Title == nil || *data.Title == "" {
+		return result(m, StatusRejected, "Title is required")
+	}
+
+	list := store.List{WorkspaceID: b.workspace.ID, UserID: b.userID, Title: *data.Title}
+	if data.Budget != "" {
+		budget, err := data.Budget.Money(b.workspace.Currency)
+		if err != nil || budget.Amount <= 0 {
+			return result(m, StatusRejected, "Invalid budget, expected a positive amount in "+b.workspace.Currency)
+		}
+		list.Budget = &budget
+	}
+	if err := stores.Lists.CreateList(r.Context(), &list, nil); err != nil {
+		return result(m, StatusFailed, "Error creating product list")
+	}
+
+	publish(r, events.Event{Type: events.ListCreated, WorkspaceID: b.workspace.ID, ListID: list.ID})
+	res := result(m, StatusApplied, "")
+	res.List = &list
+	return res
+}
+
+func (b *batch) updateList(r *http.Request, m Mutation) MutationResult {
+	id, res, ok := b.listID(m)
+	if !ok {
+		return res
+	}
+	var data ListData
+	if err := decodeData(m, &data); err != nil {
+		return result(m, StatusRejected, "Invalid list data")
+	}
+	if data.Title != nil && *data.Title == "" {
+		return result(m, StatusRejected, "Title is required")
+	}
+	if data.Status != nil && *data.Status != store.ListStatusActive && *data.Status != store.ListStatusCompleted {
+		return result(m, StatusRejected, "Invalid status, expected active or completed")
+	}
+	if _, res, ok := b.currentList(r, m, id); !ok {
+		return res
+	}
+
+	list, err := stores.Lists.PatchList(r.Context(), store.ListPatch{
+		ListID:  id,
+		Title:   data.Title,
+		Status:  data.Status,
+		Version: m.BaseVersion,
+	})
+	if errors.Is(err, store.ErrVersionConflict) || errors.Is(err, store.ErrNotFound) {
+		return b.listConflict(r, m, id)
+	}
+	if err != nil {
+		return result(m, StatusFailed, "Error updating product list")
+	}
+
+	publish(r, events.Event{Type: events.ListUpdated, WorkspaceID: b.workspace.ID, ListID: id})
+	res = result(m, StatusApplied, "")
+	res.List = list
+	return res
+}
+
+func (b *batch) deleteList(r *http.Request, m Mutation) MutationResult {
+	id, res, ok := b.listID(m)
+	if !ok {
+		return res
+	}
+
+	list, err := stores.Lists.GetList(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && list.WorkspaceID != b.workspace.ID) {
+		// Already deleted, or never visible to the client
+		return result(m, StatusApplied, "")
+	}
+	if err != nil {
+		return result(m, StatusFailed, "Error fetching product list")
+	}
+
+	list, err = stores.Lists.SetListStatus(r.Context(), id, store.ListStatusDeleted, m.BaseVersion)
+	if errors.Is(err, store.ErrVersionConflict) {
+		return b.listConflict(r, m, id)
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return result(m, StatusApplied, "")
+	}
+	if err != nil {
+		return result(m, StatusFailed, "Error deleting product list")
+	}
+
+	publish(r, events.Event{Type: events.ListDeleted, WorkspaceID: b.workspace.ID, ListID: id})
+	res = result(m, StatusApplied, "")
+	res.List = list
+	return res
+}
+
+// putItem adds a product to a list or sets its quantity, restoring it if it was removed
+func (b *batch) putItem(r *http.Request, m Mutation) MutationResult {
+	listID, productID, res, ok := b.itemTarget(r, m)
+	if !ok {
+		return res
+	}
+	var data ItemData
+	if err := decodeData(m, &data); err != nil {
+		return result(m, StatusRejected, "Invalid item data")
+	}
+	if data.QuantityDelta != 0 {
+		return result(m, StatusRejected, "quantity_delta can only be used with item.update")
+	}
+	quantity := 1
+	if data.Quantity != nil {
+		if *data.Quantity <= 0 {
+			return result(m, StatusRejected, "Invalid quantity, expected a positive number")
+		}
+		quantity = *data.Quantity
+	}
+
+	product, err := stores.Products.GetProduct(r.Context(), productID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && product.WorkspaceID != b.workspace.ID) {
+		return result(m, StatusConflict, "The product was deleted")
+	}
+	if err != nil {
+		return result(m, StatusFailed, "Error fetching product")
+	}
+	check, message := b.itemCheck(r, data)
+	if message != "" {
+		return result(m, StatusRejected, message)
+	}
+
+	item, err := stores.Lists.PutListItem(r.Context(), listID, store.ItemQuantity{ProductID: productID, Quantity: quantity, Check: check})
+	if err != nil {
+		return result(m, StatusFailed, "Error adding product to list")
+	}
+
+	publish(r, events.Event{Type: itemEvent(data), WorkspaceID: b.workspace.ID, ListID: listID, ProductID: productID})
+	res = result(m, StatusApplied, "")
+	res.Item = item
+	return res
+}
+
+// updateItem changes an item still in a list, the changes are merged into its current state
+func (b *batch) updateItem(r *http.Request, m Mutation) MutationResult {
+	listID, productID, res, ok := b.itemTarget(r, m)
+	if !ok {
+		return res
+	}
+	var data ItemData
+	if err := decodeData(m, &data); err != nil {
+		return result(m, StatusRejected, "Invalid item data")
+	}
+	if data.Quantity == nil && data.QuantityDelta == 0 && data.Checked == nil {
+		return result(m, StatusRejected, "Nothing to update, expected quantity, quantity_delta or checked")
+	}
+	if data.Quantity != nil && data.QuantityDelta != 0 {
+		return result(m, StatusRejected, "Use either quantity or quantity_delta")
+	}
+	if data.Quantity != nil && *data.Quantity <= 0 {
+		return result(m, StatusRejected, "Invalid quantity, expected a positive number")
+	}
+	check, message := b.itemCheck(r, data)
+	if message != "" {
+		return result(m, StatusRejected, message)
+	}
+
+	item, err := stores.Lists.UpdateListItem(r.Context(), listID, productID, &store.ItemChange{
+		Quantity:      data.Quantity,
+		QuantityDelta: data.QuantityDelta,
+		Check:         check,
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		return result(m, StatusConflict, "The product was removed from the list")
+	}
+	if errors.Is(err, store.ErrInvalidQuantity) {
+		return result(m, StatusRejected, "The quantity would drop below one")
+	}
+	if err != nil {
+		return result(m, StatusFailed, "Error updating list product")
+	}
+
+	publish(r, events.Event{Type: itemEvent(data), WorkspaceID: b.workspace.ID, ListID: listID, ProductID: productID})
+	res = result(m, StatusApplied, "")
+	res.Item = item
+	return res
+}
+
+func (b *batch) deleteItem(r *http.Request, m Mutation) MutationResult {
+	listID, productID, res, ok := b.itemTarget(r, m)
+	if !ok {
+		if res.Status == StatusConflict {
+			// Deleting an item of a deleted list leaves nothing to do
+			return result(m, StatusApplied, "")
+		}
+		return res
+	}
+
+	_, err := stores.Lists.RemoveListItem(r.Context(), listID, productID, 0)
+	if errors.Is(err, store.ErrNotFound) {
+		// The list was deleted meanwhile
+		return result(m, StatusApplied, "")
+	}
+	if err != nil {
+		return result(m, StatusFailed, "Error removing product from list")
+	}
+
+	publish(r, events.Event{Type: events.ItemDeleted, WorkspaceID: b.workspace.ID, ListID: listID, ProductID: productID})
+	return result(m, StatusApplied, "")
+}
+
+// itemTarget resolves the list and product of an item mutation, the list must not be deleted
+func (b *batch) itemTarget(r *http.Request, m Mutation) (int, int, MutationResult, bool) {
+	listID, res, ok := b.listID(m)
+	if !ok {
+		return 0, 0, res, false
+	}
+	productID, res, ok := b.productID(m)
+	if !ok {
+		return 0, 0, res, false
+	}
+	if _, res, ok := b.currentList(r, m, listID); !ok {
+		return 0, 0, res, false
+	}
+	return listID, productID, MutationResult{}, true
+}
+
+// itemCheck converts the checked state and purchase of an item mutation with the rules of the
+// list handlers. It returns a message instead if they are invalid.
+func (b *batch) itemCheck(r *http.Request, data ItemData) (*store.ItemCheck, string) {
+	check, err := lists.CheckItem(r.Context(), data.Checked, data.Purchase, b.workspace, &b.userID)
+	if err != nil {
+		return nil, err.Error()
+	}
+	return check, ""
+}
+
+// itemEvent returns the type of the event published for an item mutation
+func itemEvent(data ItemData) string {
+	if data.Checked != nil {
+		return events.ItemChecked
+	}
+	return events.ItemUpdated
+}
+
+// currentList fetches a list of the workspace. If it was deleted the mutation changing
+// it loses, and the conflicting result is returned instead.
+func (b *batch) currentList(r *http.Request, m Mutation, id int) (*store.List, MutationResult, bool) {
+	list, err := stores.Lists.GetList(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && list.WorkspaceID != b.workspace.ID) {
+		return nil, result(m, StatusConflict, "The list was deleted"), false
+	}
+	if err != nil {
+		return nil, result(m, StatusFailed, "Error fetching product list"), false
+	}
+	return list, MutationResult{}, true
+}
+
+// listConflict returns the result of a mutation that lost to the current version of the list
+func (b *batch) listConflict(r *http.Request, m Mutation, id int) MutationResult {
+	current, res, ok := b.currentList(r, m, id)
+	if !ok {
+		return res
+	}
+	res = result(m, StatusConflict, "The list was changed since base_version")
+	res.List = current
+	return res
+}
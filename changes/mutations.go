@@ -0,0 +1,310 @@
+package changes
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"shopping_list/middleware"
+	"shopping_list/store"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxMutations         = 100
+	maxIdempotencyKeyLen = 255
+)
+
+// Mutation types accepted by ApplyMutations
+const (
+	ProductCreate = "product.create"
+	ProductUpdate = "product.update"
+	ProductDelete = "product.delete"
+	ListCreate    = "list.create"
+	ListUpdate    = "list.update"
+	ListDelete    = "list.delete"
+	ItemPut       = "item.put"
+	ItemUpdate    = "item.update"
+	ItemDelete    = "item.delete"
+)
+
+// Mutation statuses
+const (
+	// StatusApplied means the mutation was applied, or that there was nothing left to do
+	StatusApplied = "applied"
+	// StatusConflict means the server kept its own version, which is returned with the result
+	StatusConflict = "conflict"
+	// StatusRejected means the mutation is invalid and will never be applied
+	StatusRejected = "rejected"
+	// StatusFailed means the server could not apply the mutation, it may be retried with the same key
+	StatusFailed = "failed"
+)
+
+// Mutation is a change made by a client while offline
+type Mutation struct {
+	// IdempotencyKey identifies the mutation, a mutation sent again with the same key
+	// is not applied twice and gets the result of the first attempt
+	IdempotencyKey string `json:"idempotency_key"`
+	Type           string `json:"type"`
+	// Ref is the client's identifier of a record it creates, later mutations of the
+	// batch refer to the record with list_ref or product_ref
+	Ref        string `json:"ref"`
+	ListID     int    `json:"list_id"`
+	ListRef    string `json:"list_ref"`
+	ProductID  int    `json:"product_id"`
+	ProductRef string `json:"product_ref"`
+	// BaseVersion is the version of the product or list the client changed, 0 to
+	// overwrite whatever version the server has
+	BaseVersion int             `json:"base_version"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// MutationResult is the outcome of a mutation with the current state of the record it changed
+type MutationResult struct {
+	IdempotencyKey string `json:"idempotency_key"`
+	Type           string `json:"type"`
+	Ref            string `json:"ref,omitempty"`
+	Status         string `json:"status"`
+	Error          string `json:"error,omitempty"`
+	// Replayed is true if the mutation was applied by an earlier request with the same key
+	Replayed bool            `json:"replayed"`
+	Product  *store.Product  `json:"product,omitempty"`
+	List     *store.List     `json:"list,omitempty"`
+	Item     *store.ListItem `json:"item,omitempty"`
+}
+
+// ApplyMutationsRequest is the body of ApplyMutations
+type ApplyMutationsRequest struct {
+	Mutations []Mutation `json:"mutations"`
+}
+
+// batch is the state shared by the mutations of one request
+type batch struct {
+	workspace *store.Workspace
+	userID    int
+	// productRefs and listRefs map the refs of the batch and of replayed mutations to IDs
+	productRefs map[string]int
+	listRefs    map[string]int
+}
+
+// ApplyMutations applies a batch of offline mutations to a workspace in order and returns a
+// result for each of them. Conflicts are resolved the same way whatever order clients sync in:
+//   - a product or list changed since base_version keeps the server version;
+//   - deletes win, a deleted record is not changed or restored and deleting it again succeeds;
+//   - list items merge field by field, quantity_delta adds to the current quantity.
+//
+// Results are remembered per user and idempotency key, except failed ones, so a client that
+// lost the response can send the batch again.
+func ApplyMutations(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ApplyMutationsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Mutations) == 0 || len(req.Mutations) > maxMutations {
+		http.Error(w, "Expected between 1 and "+strconv.Itoa(maxMutations)+" mutations", http.StatusBadRequest)
+		return
+	}
+
+	workspace, err := stores.Workspaces.GetWorkspace(r.Context(), workspaceID)
+	if err != nil {
+		http.Error(w, "Error fetching workspace", http.StatusInternalServerError)
+		return
+	}
+
+	b := &batch{
+		workspace:   workspace,
+		userID:      userID,
+		productRefs: map[string]int{},
+		listRefs:    map[string]int{},
+	}
+	results := make([]MutationResult, 0, len(req.Mutations))
+	for _, mutation := range req.Mutations {
+		results = append(results, b.run(r, mutation))
+	}
+
+	response := struct {
+		Status string           `json:"status"`
+		Data   []MutationResult `json:"data"`
+	}{
+		Status: "Success",
+		Data:   results,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// run applies a mutation unless its key was used before, and remembers its result
+func (b *batch) run(r *http.Request, m Mutation) MutationResult {
+	if m.IdempotencyKey == "" || len(m.IdempotencyKey) > maxIdempotencyKeyLen {
+		return result(m, StatusRejected, "idempotency_key is required and at most "+strconv.Itoa(maxIdempotencyKeyLen)+" characters")
+	}
+	if !allowed(r, m.Type) {
+		// Not remembered, the user may be given the permission later
+		return result(m, StatusRejected, "You don't have permission to perform this action")
+	}
+
+	if res, ok := b.replay(r, m.IdempotencyKey); ok {
+		return res
+	}
+
+	res := b.apply(r, m)
+	if res.Status == StatusFailed {
+		return res
+	}
+
+	stored, err := json.Marshal(res)
+	if err != nil {
+		return result(m, StatusFailed, "Error saving the result")
+	}
+	err = stores.Sync.SaveMutationResult(r.Context(), b.workspace.ID, b.userID, m.IdempotencyKey, stored)
+	if errors.Is(err, store.ErrDuplicate) {
+		// Another request with the same key got there first
+		if res, ok := b.replay(r, m.IdempotencyKey); ok {
+			return res
+		}
+	}
+	if err != nil {
+		log.Printf("error saving the result of sync mutation %q: %s", m.IdempotencyKey, err)
+	}
+	return res
+}
+
+// replay returns the result remembered for a key and registers the ref it created
+func (b *batch) replay(r *http.Request, key string) (MutationResult, bool) {
+	stored, err := stores.Sync.GetMutationResult(r.Context(), b.workspace.ID, b.userID, key)
+	if err != nil {
+		return MutationResult{}, false
+	}
+
+	var res MutationResult
+	if err := json.Unmarshal(stored, &res); err != nil {
+		return MutationResult{}, false
+	}
+	res.Replayed = true
+	b.register(res)
+	return res, true
+}
+
+// register maps the ref of a created record to its ID
+func (b *batch) register(res MutationResult) {
+	if res.Ref == "" || res.Status != StatusApplied {
+		return
+	}
+	switch {
+	case res.Type == ProductCreate && res.Product != nil:
+		b.productRefs[res.Ref] = res.Product.ID
+	case res.Type == ListCreate && res.List != nil:
+		b.listRefs[res.Ref] = res.List.ID
+	}
+}
+
+// apply dispatches a mutation to the function applying its type
+func (b *batch) apply(r *http.Request, m Mutation) MutationResult {
+	var res MutationResult
+	switch m.Type {
+	case ProductCreate:
+		res = b.createProduct(r, m)
+	case ProductUpdate:
+		res = b.updateProduct(r, m)
+	case ProductDelete:
+		res = b.deleteProduct(r, m)
+	case ListCreate:
+		res = b.createList(r, m)
+	case ListUpdate:
+		res = b.updateList(r, m)
+	case ListDelete:
+		res = b.deleteList(r, m)
+	case ItemPut:
+		res = b.putItem(r, m)
+	case ItemUpdate:
+		res = b.updateItem(r, m)
+	case ItemDelete:
+		res = b.deleteItem(r, m)
+	default:
+		res = result(m, StatusRejected, "Unknown mutation type: "+m.Type)
+	}
+	b.register(res)
+	return res
+}
+
+// allowed reports whether the user's role lets them apply mutations of the type
+func allowed(r *http.Request, mutationType string) bool {
+	switch mutationType {
+	case ProductCreate, ProductUpdate, ProductDelete:
+		return middleware.Can(r.Context(), middleware.PermEditProducts)
+	case ListDelete:
+		return middleware.Can(r.Context(), middleware.PermEditLists) &&
+			middleware.Can(r.Context(), middleware.PermDeleteLists)
+	default:
+		return middleware.Can(r.Context(), middleware.PermEditLists)
+	}
+}
+
+// productID resolves the product a mutation refers to, by ID or by the ref of the batch that created it.
+// An unknown ref fails rather than being rejected, so the mutation can be sent again with its creator.
+func (b *batch) productID(m Mutation) (int, MutationResult, bool) {
+	if m.ProductRef != "" {
+		id, ok := b.productRefs[m.ProductRef]
+		if !ok {
+			return 0, result(m, StatusFailed, "Unknown product_ref: "+m.ProductRef), false
+		}
+		return id, MutationResult{}, true
+	}
+	if m.ProductID == 0 {
+		return 0, result(m, StatusRejected, "product_id or product_ref is required"), false
+	}
+	return m.ProductID, MutationResult{}, true
+}
+
+// listID resolves the list a mutation refers to, by ID or by the ref of the batch that created it
+func (b *batch) listID(m Mutation) (int, MutationResult, bool) {
+	if m.ListRef != "" {
+		id, ok := b.listRefs[m.ListRef]
+		if !ok {
+			return 0, result(m, StatusFailed, "Unknown list_ref: "+m.ListRef), false
+		}
+		return id, MutationResult{}, true
+	}
+	if m.ListID == 0 {
+		return 0, result(m, StatusRejected, "list_id or list_ref is required"), false
+	}
+	return m.ListID, MutationResult{}, true
+}
+
+// result returns the result of a mutation with the given status and error message
+func result(m Mutation, status, message string) MutationResult {
+	return MutationResult{
+		IdempotencyKey: m.IdempotencyKey,
+		Type:           m.Type,
+		Ref:            m.Ref,
+		Status:         status,
+		Error:          message,
+	}
+}
+
+// decodeData reads the data of a mutation into v, a mutation without data leaves v unchanged
+func decodeData(m Mutation, v interface{}) error {
+	if len(m.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(m.Data, v)
+}
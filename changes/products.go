@@ -0,0 +1,177 @@
+package changes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"shopping_list/events"
+	"shopping_list/money"
+	"shopping_list/products"
+	"shopping_list/store"
+)
+
+// ProductData holds the fields of a product mutation, fields left out are not changed
+type ProductData struct {
+	Title      *string `json:"title"`
+	AmountType *string `json:"amount_type"`
+	// Price is a decimal amount in the workspace currency
+	Price      money.Decimal `json:"price"`
+	CategoryID optionalID    `json:"category_id"`
+	// StoreID is the optional store where the price was seen
+	StoreID *int `json:"store_id"`
+}
+
+// optionalID is an ID that tells a null value, which clears the ID, from a missing one
+type optionalID struct {
+	Set bool
+	ID  *int
+}
+
+func (o *optionalID) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.ID)
+}
+
+func (b *batch) createProduct(r *http.Request, m Mutation) MutationResult {
+	var data ProductData
+	if err := decodeData(m, &data); err != nil {
+		return result(m, StatusRejected, "Invalid product data")
+	}
+
+	product := store.Product{WorkspaceID: b.workspace.ID, Price: money.New(0, b.workspace.Currency)}
+	source, message := b.mergeProduct(r, &product, data)
+	if message != "" {
+		return result(m, StatusRejected, message)
+	}
+	if err := stores.Products.CreateProduct(r.Context(), &product, source); err != nil {
+		return result(m, StatusFailed, "Failed to create the product")
+	}
+
+	publish(r, events.Event{Type: events.ProductCreated, WorkspaceID: b.workspace.ID, ProductID: product.ID, Data: product})
+	res := result(m, StatusApplied, "")
+	res.Product = &product
+	return res
+}
+
+func (b *batch) updateProduct(r *http.Request, m Mutation) MutationResult {
+	id, res, ok := b.productID(m)
+	if !ok {
+		return res
+	}
+	var data ProductData
+	if err := decodeData(m, &data); err != nil {
+		return result(m, StatusRejected, "Invalid product data")
+	}
+
+	product, res, ok := b.currentProduct(r, m, id)
+	if !ok {
+		return res
+	}
+	if m.BaseVersion != 0 && m.BaseVersion != product.Version {
+		return productConflict(m, product)
+	}
+
+	source, message := b.mergeProduct(r, product, data)
+	if message != "" {
+		return result(m, StatusRejected, message)
+	}
+	// The product is replaced with the merged fields only if nobody changed it since it was read
+	err := stores.Products.UpdateProduct(r.Context(), product, source)
+	if errors.Is(err, store.ErrVersionConflict) || errors.Is(err, store.ErrNotFound) {
+		current, res, ok := b.currentProduct(r, m, id)
+		if !ok {
+			return res
+		}
+		return productConflict(m, current)
+	}
+	if err != nil {
+		return result(m, StatusFailed, "Failed to update the product")
+	}
+
+	publish(r, events.Event{Type: events.ProductUpdated, WorkspaceID: b.workspace.ID, ProductID: id, Data: product})
+	res = result(m, StatusApplied, "")
+	res.Product = product
+	return res
+}
+
+func (b *batch) deleteProduct(r *http.Request, m Mutation) MutationResult {
+	id, res, ok := b.productID(m)
+	if !ok {
+		return res
+	}
+
+	product, err := stores.Products.GetProduct(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && product.WorkspaceID != b.workspace.ID) {
+		// Already deleted, or never visible to the client
+		return result(m, StatusApplied, "")
+	}
+	if err != nil {
+		return result(m, StatusFailed, "Failed to fetch the product")
+	}
+
+	err = stores.Products.DeleteProduct(r.Context(), id, m.BaseVersion)
+	if errors.Is(err, store.ErrVersionConflict) {
+		current, res, ok := b.currentProduct(r, m, id)
+		if !ok {
+			return res
+		}
+		return productConflict(m, current)
+	}
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return result(m, StatusFailed, "Failed to delete the product")
+	}
+
+	publish(r, events.Event{Type: events.ProductDeleted, WorkspaceID: b.workspace.ID, ProductID: id})
+	return result(m, StatusApplied, "")
+}
+
+// currentProduct fetches a product of the workspace. If it was deleted the mutation
+// changing it loses, and the conflicting result is returned instead.
+func (b *batch) currentProduct(r *http.Request, m Mutation, id int) (*store.Product, MutationResult, bool) {
+	product, err := stores.Products.GetProduct(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && product.WorkspaceID != b.workspace.ID) {
+		return nil, result(m, StatusConflict, "The product was deleted"), false
+	}
+	if err != nil {
+		return nil, result(m, StatusFailed, "Failed to fetch the product"), false
+	}
+	return product, MutationResult{}, true
+}
+
+// productConflict returns the result of a mutation that lost to the current version of the product
+func productConflict(m Mutation, current *store.Product) MutationResult {
+	res := result(m, StatusConflict, "The product was changed since base_version")
+	res.Product = current
+	return res
+}
+
+// mergeProduct sets the fields of the data on the product and returns who set its price. It
+// returns a message instead if the data is invalid, with the rules of the product handlers.
+func (b *batch) mergeProduct(r *http.Request, product *store.Product, data ProductData) (store.PriceSource, string) {
+	if data.Title != nil {
+		product.Title = *data.Title
+	}
+	if data.AmountType != nil {
+		product.AmountType = *data.AmountType
+	}
+	if data.Price != "" {
+		price, err := products.ParsePrice(data.Price, b.workspace.Currency)
+		if err != nil {
+			return store.PriceSource{}, err.Error()
+		}
+		product.Price = price
+	}
+	if data.CategoryID.Set {
+		if err := products.CheckCategory(r.Context(), data.CategoryID.ID, b.workspace.ID); err != nil {
+			return store.PriceSource{}, err.Error()
+		}
+		product.CategoryID = data.CategoryID.ID
+	}
+
+	source, err := products.PriceSource(r.Context(), &b.userID, data.StoreID, b.workspace.ID)
+	if err != nil {
+		return source, err.Error()
+	}
+	return source, ""
+}
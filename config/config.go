@@ -0,0 +1,202 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// minSecretLength is the minimum number of bytes accepted for the JWT signing secret
+const minSecretLength = 32
+
+// weakSecrets are placeholder values that must never be used to sign tokens
+var weakSecrets = map[string]bool{
+	"your_secret_key": true,
+	"secret":          true,
+	"changeme":        true,
+}
+
+// JWTConfig holds the settings used to sign and validate JWT tokens
+type JWTConfig struct {
+	Secret   []byte
+	Issuer   string
+	Audience string
+	Expiry   time.Duration
+	Leeway   time.Duration
+
+	// RefreshExpiry is the lifetime of the opaque refresh tokens
+	RefreshExpiry time.Duration
+	// RevocationCacheTTL is how long revocation lookups are cached in process
+	RevocationCacheTTL time.Duration
+}
+
+// JWT is the JWT configuration loaded by Load
+var JWT JWTConfig
+
+// MailConfig holds the settings of the outgoing mail sender
+type MailConfig struct {
+	// Driver selects the sender, "log" writes messages to the server log and "file" to Dir
+	Driver string
+	Dir    string
+	From   string
+}
+
+// Mail is the mail configuration loaded by Load
+var Mail MailConfig
+
+// InvitationConfig holds the settings of the workspace invitations
+type InvitationConfig struct {
+	// Expiry is how long an invitation can be accepted after it was sent
+	Expiry time.Duration
+	// AcceptURL is the page of the client the invitation token is appended to
+	AcceptURL string
+}
+
+// Invitations is the invitation configuration loaded by Load
+var Invitations InvitationConfig
+
+// IdempotencyConfig holds the settings of the Idempotency-Key header
+type IdempotencyConfig struct {
+	// Window is how long the response to a request is replayed to retries with its key
+	Window time.Duration
+}
+
+// Idempotency is the idempotency configuration loaded by Load
+var Idempotency IdempotencyConfig
+
+// Load reads the configuration from the environment and the .env file.
+// The server refuses to start if the configuration is invalid.
+func Load() {
+	// Variables already set in the environment take precedence over the .env file
+	godotenv.Load()
+
+	jwtConfig, err := loadJWT()
+	if err != nil {
+		log.Fatal(err)
+	}
+	JWT = jwtConfig
+
+	mailConfig, err := loadMail()
+	if err != nil {
+		log.Fatal(err)
+	}
+	Mail = mailConfig
+
+	invitationConfig, err := loadInvitations()
+	if err != nil {
+		log.Fatal(err)
+	}
+	Invitations = invitationConfig
+
+	idempotencyConfig, err := loadIdempotency()
+	if err != nil {
+		log.Fatal(err)
+	}
+	Idempotency = idempotencyConfig
+}
+
+func loadJWT() (JWTConfig, error) {
+	cfg := JWTConfig{
+		Secret:   []byte(os.Getenv("JWT_SECRET")),
+		Issuer:   getEnv("JWT_ISSUER", "shopping_list"),
+		Audience: getEnv("JWT_AUDIENCE", "shopping_list"),
+	}
+
+	if len(cfg.Secret) == 0 {
+		return cfg, fmt.Errorf("JWT_SECRET is required")
+	}
+	if len(cfg.Secret) < minSecretLength || weakSecrets[string(cfg.Secret)] {
+		return cfg, fmt.Errorf("JWT_SECRET is too weak, use a random value of at least %d characters", minSecretLength)
+	}
+
+	var err error
+	if cfg.Expiry, err = getDuration("JWT_EXPIRY", 15*time.Minute); err != nil {
+		return cfg, err
+	}
+	if cfg.RefreshExpiry, err = getDuration("JWT_REFRESH_EXPIRY", 30*24*time.Hour); err != nil {
+		return cfg, err
+	}
+	if cfg.Leeway, err = getDuration("JWT_LEEWAY", 30*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.RevocationCacheTTL, err = getDuration("JWT_REVOCATION_CACHE_TTL", 30*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.Expiry <= 0 {
+		return cfg, fmt.Errorf("JWT_EXPIRY must be positive")
+	}
+	if cfg.RefreshExpiry <= cfg.Expiry {
+		return cfg, fmt.Errorf("JWT_REFRESH_EXPIRY must be longer than JWT_EXPIRY")
+	}
+	if cfg.Leeway < 0 {
+		return cfg, fmt.Errorf("JWT_LEEWAY must not be negative")
+	}
+
+	return cfg, nil
+}
+
+func loadMail() (MailConfig, error) {
+	cfg := MailConfig{
+		Driver: getEnv("MAIL_DRIVER", "log"),
+		Dir:    getEnv("MAIL_DIR", "tmp/mail"),
+		From:   getEnv("MAIL_FROM", "no-reply@shopping-list.local"),
+	}
+
+	if cfg.Driver != "log" && cfg.Driver != "file" {
+		return cfg, fmt.Errorf("invalid MAIL_DRIVER %q, expected log or file", cfg.Driver)
+	}
+	return cfg, nil
+}
+
+func loadInvitations() (InvitationConfig, error) {
+	cfg := InvitationConfig{
+		AcceptURL: getEnv("INVITATION_ACCEPT_URL", "http://localhost:3000/invitations/accept"),
+	}
+
+	var err error
+	if cfg.Expiry, err = getDuration("INVITATION_EXPIRY", 7*24*time.Hour); err != nil {
+		return cfg, err
+	}
+	if cfg.Expiry <= 0 {
+		return cfg, fmt.Errorf("INVITATION_EXPIRY must be positive")
+	}
+	return cfg, nil
+}
+
+func loadIdempotency() (IdempotencyConfig, error) {
+	var cfg IdempotencyConfig
+
+	var err error
+	if cfg.Window, err = getDuration("IDEMPOTENCY_WINDOW", 24*time.Hour); err != nil {
+		return cfg, err
+	}
+	if cfg.Window <= 0 {
+		return cfg, fmt.Errorf("IDEMPOTENCY_WINDOW must be positive")
+	}
+	return cfg, nil
+}
+
+// getEnv returns the value of an environment variable or fallback when it is unset
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// getDuration parses an environment variable such as "72h" or "15m", falling back when it is unset
+func getDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return duration, nil
+}
@@ -25,6 +25,9 @@ func DbConnect() {
 		DBName:               os.Getenv("DB_NAME"),
 		AllowNativePasswords: os.Getenv("DB_ALLOWNATIVEPASSWORD") == "true",
 		ParseTime:            true,
+		// Times are sent and read in UTC, so TIMESTAMP columns and the SQL date functions such as
+		// DATE_FORMAT must use UTC as well
+		Params: map[string]string{"time_zone": "'+00:00'"},
 	}
 
 	// Get a database handle.
@@ -0,0 +1,445 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrDestructiveMigration is returned when a migration that drops or truncates
+// data would run against a database that already holds rows
+var ErrDestructiveMigration = errors.New("refusing to run destructive migration against a non-empty database")
+
+// ErrMigrationLocked is returned when another process kept the migration lock for too long
+var ErrMigrationLocked = errors.New("timed out waiting for another process to finish migrating")
+
+const (
+	// migrationLock is the MySQL named lock held while migrations are applied or reverted
+	migrationLock = "shopping_list.schema_migrations"
+	// migrationLockTimeout is how many seconds to wait for the lock
+	migrationLockTimeout = 60
+)
+
+var (
+	migrationFile    = regexp.MustCompile(`^(\d+)_?(.*?)(\.down)?\.sql$`)
+	destructiveQuery = regexp.MustCompile(`(?i)\b(DROP\s+(TABLE|DATABASE|SCHEMA|COLUMN)|TRUNCATE)\b`)
+)
+
+// Migration is a versioned schema change with an optional revert script
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator applies the migrations found in a file system and records them in schema_migrations
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+
+	// AllowDestructive skips the non-empty database check for destructive migrations
+	AllowDestructive bool
+}
+
+// NewMigrator loads the migrations from fsys, sorted by version
+func NewMigrator(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// LoadMigrations reads every "<version>_<name>.sql" file and its optional ".down.sql" counterpart
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+
+		if match[3] != "" {
+			migration.Down = string(content)
+		} else {
+			if migration.Up != "" {
+				return nil, fmt.Errorf("duplicate migration version %d", version)
+			}
+			migration.Up = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d has a down file but no up file", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up applies every pending migration in version order and returns the ones applied. It holds the
+// migration lock, so servers migrating at startup at the same time apply each migration once.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		err := m.run(ctx, migration.Up, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)", migration.Version, migration.Name, time.Now())
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns the ones reverted
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == "" {
+			return done, fmt.Errorf("migration %d %s has no down file", migration.Version, migration.Name)
+		}
+
+		err := m.run(ctx, migration.Down, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", migration.Version)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Status lists every known migration and when it was applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Migration: migration}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Baseline records every migration up to and including version as applied without running it.
+// It is meant for databases created before migrations were tracked.
+func (m *Migrator) Baseline(ctx context.Context, version int64) ([]Migration, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if migration.Version > version {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		_, err := m.db.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)", migration.Version, migration.Name, time.Now())
+		if err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// lock takes the migration lock and returns the function releasing it. MySQL named locks belong to
+// a session, so the lock is taken and released on a connection reserved for it.
+func (m *Migrator) lock(ctx context.Context) (func(), error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var locked sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", migrationLock, migrationLockTimeout).Scan(&locked)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if locked.Int64 != 1 {
+		conn.Close()
+		return nil, ErrMigrationLocked
+	}
+
+	return func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), "DO RELEASE_LOCK(?)", migrationLock); err != nil {
+			// Closing the connection ends the session and its lock
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}, nil
+}
+
+// run executes a migration script and records it inside a single transaction.
+// MySQL commits DDL statements implicitly, so the transaction only fully protects data changes.
+func (m *Migrator) run(ctx context.Context, script string, record func(tx *sql.Tx) error) error {
+	if destructive(script) && !m.AllowDestructive {
+		empty, err := m.databaseEmpty(ctx)
+		if err != nil {
+			return err
+		}
+		if !empty {
+			return ErrDestructiveMigration
+		}
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	for _, statement := range splitStatements(script) {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// appliedVersions creates the schema_migrations table if needed and returns the applied versions
+func (m *Migrator) appliedVersions(ctx context.Context) (map[int64]time.Time, error) {
+	_, err := m.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT NOT NULL PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// databaseEmpty reports whether no table other than schema_migrations holds any row
+func (m *Migrator) databaseEmpty(ctx context.Context) (bool, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT table_name FROM information_schema.tables
+		WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE' AND table_name <> 'schema_migrations'`)
+	if err != nil {
+		return false, err
+	}
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			rows.Close()
+			return false, err
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	for _, table := range tables {
+		var hasRows bool
+		query := "SELECT EXISTS(SELECT 1 FROM `" + strings.ReplaceAll(table, "`", "``") + "`)"
+		if err := m.db.QueryRowContext(ctx, query).Scan(&hasRows); err != nil {
+			return false, err
+		}
+		if hasRows {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// destructive reports whether a script drops or truncates data, ignoring comments
+func destructive(script string) bool {
+	return destructiveQuery.MatchString(stripComments(script))
+}
+
+// stripComments removes line comments from a script, leaving quoted strings and identifiers
+// alone. Like in MySQL, "--" only starts a comment when followed by a space or the end of a line.
+func stripComments(script string) string {
+	var b strings.Builder
+	for i := 0; i < len(script); {
+		switch {
+		case isQuote(script[i]):
+			end := quoteEnd(script, i)
+			b.WriteString(script[i:end])
+			i = end
+		case script[i] == '#' || isDashComment(script[i:]):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				return b.String()
+			}
+			i += end
+		default:
+			b.WriteByte(script[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+// splitStatements splits a script on the semicolons outside quotes so each statement can be
+// executed on its own
+func splitStatements(script string) []string {
+	script = stripComments(script)
+
+	var statements []string
+	add := func(statement string) {
+		if statement = strings.TrimSpace(statement); statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+	start := 0
+	for i := 0; i < len(script); {
+		switch {
+		case isQuote(script[i]):
+			i = quoteEnd(script, i)
+		case script[i] == ';':
+			add(script[start:i])
+			i++
+			start = i
+		default:
+			i++
+		}
+	}
+	add(script[start:])
+	return statements
+}
+
+// isQuote reports whether c starts a string or a quoted identifier
+func isQuote(c byte) bool {
+	return c == '\'' || c == '"' || c == '`'
+}
+
+// isDashComment reports whether s starts with a "--" comment
+func isDashComment(s string) bool {
+	if !strings.HasPrefix(s, "--") {
+		return false
+	}
+	return len(s) == 2 || strings.IndexByte(" \t\r\n", s[2]) >= 0
+}
+
+// quoteEnd returns the index just past the quoted string or identifier starting at script[start],
+// or the length of the script if it is not closed. A backslash escapes the next character in
+// strings, and a doubled quote reads as two strings in a row, which gives the same split.
+func quoteEnd(script string, start int) int {
+	quote := script[start]
+	for i := start + 1; i < len(script); i++ {
+		switch {
+		case script[i] == '\\' && quote != '`':
+			i++
+		case script[i] == quote:
+			return i + 1
+		}
+	}
+	return len(script)
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"shopping_list/sql/migrations"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{"single", "CREATE TABLE a (id INT)", []string{"CREATE TABLE a (id INT)"}},
+		{"trailing semicolon", "CREATE TABLE a (id INT);\n", []string{"CREATE TABLE a (id INT)"}},
+		{"several", "ALTER TABLE a ADD b INT;\nUPDATE a SET b = 1;", []string{"ALTER TABLE a ADD b INT", "UPDATE a SET b = 1"}},
+		{"comments", "-- drop it; later\nCREATE TABLE a (id INT); -- done;\n", []string{"CREATE TABLE a (id INT)"}},
+		{"hash comments", "# drop it; later\nCREATE TABLE a (id INT); # done;\n", []string{"CREATE TABLE a (id INT)"}},
+		{"comment at the end", "SELECT 1 --", []string{"SELECT 1"}},
+		{"dashes without a space", "UPDATE a SET b = c--1;", []string{"UPDATE a SET b = c--1"}},
+		{"semicolon in a string", "ALTER TABLE a MODIFY b INT COMMENT 'a; b';\nSELECT 1", []string{"ALTER TABLE a MODIFY b INT COMMENT 'a; b'", "SELECT 1"}},
+		{"dashes in a string", "ALTER TABLE a ADD b VARCHAR(2) DEFAULT '--';\nSELECT 1", []string{"ALTER TABLE a ADD b VARCHAR(2) DEFAULT '--'", "SELECT 1"}},
+		{"double quoted string", `INSERT INTO a VALUES ("x; -- y"); SELECT 1`, []string{`INSERT INTO a VALUES ("x; -- y")`, "SELECT 1"}},
+		{"quoted identifier", "CREATE TABLE `a;b` (`-- c` INT, `#d` INT)", []string{"CREATE TABLE `a;b` (`-- c` INT, `#d` INT)"}},
+		{"escaped quote", `INSERT INTO a VALUES ('it\'s; fine'); SELECT 1`, []string{`INSERT INTO a VALUES ('it\'s; fine')`, "SELECT 1"}},
+		{"doubled quote", "INSERT INTO a VALUES ('it''s; fine'); SELECT 1", []string{"INSERT INTO a VALUES ('it''s; fine')", "SELECT 1"}},
+		{"backslash in an identifier", "CREATE TABLE `a\\` (id INT); SELECT 1", []string{"CREATE TABLE `a\\` (id INT)", "SELECT 1"}},
+		{"unclosed quote", "SELECT 'a; b", []string{"SELECT 'a; b"}},
+		{"blank statements", ";\n  ;\n", nil},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitStatements(tt.script); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements(%q) = %q, want %q", tt.script, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDestructive(t *testing.T) {
+	tests := []struct {
+		script string
+		want   bool
+	}{
+		{"DROP TABLE IF EXISTS products;", true},
+		{"drop   table products", true},
+		{"ALTER TABLE products DROP COLUMN price", true},
+		{"TRUNCATE products", true},
+		{"DROP DATABASE shopping_list", true},
+		{"ALTER TABLE products DROP INDEX idx_title", false},
+		{"ALTER TABLE products DROP FOREIGN KEY fk_category", false},
+		{"CREATE TABLE dropped_items (id INT)", false},
+		{"-- DROP TABLE products\nCREATE TABLE products (id INT)", false},
+		{"# DROP TABLE products\nCREATE TABLE products (id INT)", false},
+		{"ALTER TABLE products ADD code VARCHAR(2) DEFAULT '--'; DROP TABLE products", true},
+	}
+	for _, tt := range tests {
+		if got := destructive(tt.script); got != tt.want {
+			t.Errorf("destructive(%q) = %v, want %v", tt.script, got, tt.want)
+		}
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"20250102000000_add_b.sql":          {Data: []byte("ALTER TABLE a ADD b INT")},
+		"20250101000000_create_a.sql":       {Data: []byte("CREATE TABLE a (id INT)")},
+		"20250101000000_create_a.down.sql":  {Data: []byte("DROP TABLE a")},
+		"20250103000000create_c.sql":        {Data: []byte("CREATE TABLE c (id INT)")},
+		"README.md":                         {Data: []byte("not a migration")},
+		"20250104000000_nested.sql/ignored": {Data: []byte("")},
+	}
+
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("LoadMigrations: %s", err)
+	}
+	want := []Migration{
+		{Version: 20250101000000, Name: "create_a", Up: "CREATE TABLE a (id INT)", Down: "DROP TABLE a"},
+		{Version: 20250102000000, Name: "add_b", Up: "ALTER TABLE a ADD b INT"},
+		{Version: 20250103000000, Name: "create_c", Up: "CREATE TABLE c (id INT)"},
+	}
+	if !reflect.DeepEqual(migrations, want) {
+		t.Errorf("LoadMigrations = %+v, want %+v", migrations, want)
+	}
+}
+
+func TestLoadMigrationsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"down without up", fstest.MapFS{"20250101000000_a.down.sql": {Data: []byte("DROP TABLE a")}}},
+		{"duplicate version", fstest.MapFS{
+			"20250101000000_a.sql": {Data: []byte("CREATE TABLE a (id INT)")},
+			"20250101000000_b.sql": {Data: []byte("CREATE TABLE b (id INT)")},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadMigrations(tt.fsys); err == nil {
+				t.Error("LoadMigrations succeeded, want an error")
+			}
+		})
+	}
+}
+
+var (
+	// repeatableStatement matches the statements that can run again once they succeeded
+	repeatableStatement = regexp.MustCompile(`(?is)^(CREATE\s+TABLE\s+IF\s+NOT\s+EXISTS|DROP\s+TABLE\s+IF\s+EXISTS|INSERT|UPDATE|ALTER\s+TABLE\s+\S+\s+MODIFY)\b`)
+	// alterClause matches a clause of ALTER TABLE other than the first one
+	alterClause = regexp.MustCompile(`(?i),\s*(ADD|DROP|CHANGE|RENAME|ALTER)\b`)
+)
+
+// TestMigrationsCanBeRerun checks that a migration that failed halfway can be run again, which
+// needs every statement but the last one to be repeatable. The migrations before 2026 ran before
+// the migrations were tracked.
+func TestMigrationsCanBeRerun(t *testing.T) {
+	loaded, err := LoadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatalf("LoadMigrations: %s", err)
+	}
+	for _, migration := range loaded {
+		if migration.Version < 20260000000000 {
+			continue
+		}
+		for direction, script := range map[string]string{"up": migration.Up, "down": migration.Down} {
+			statements := splitStatements(script)
+			for _, statement := range statements[:max(len(statements)-1, 0)] {
+				if !repeatableStatement.MatchString(statement) || alterClause.MatchString(statement) {
+					t.Errorf("migration %d %s %s: %q cannot run again and is not the last statement", migration.Version, migration.Name, direction, statement)
+				}
+			}
+		}
+	}
+}
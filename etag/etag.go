@@ -0,0 +1,39 @@
+// Package etag implements the ETag and If-Match headers of versioned resources
+package etag
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Format returns the strong ETag of a version
+func Format(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// Set writes the ETag header of a version
+func Set(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", Format(version))
+}
+
+// IfMatch returns the version required by the If-Match header, or 0 for "*" which matches any version.
+// It writes 428 Precondition Required if the header is missing, and 412 Precondition Failed if it is
+// not a strong ETag of this package, which no version can match.
+func IfMatch(w http.ResponseWriter, r *http.Request) (int, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		http.Error(w, "The If-Match header is required, use the ETag of the current version", http.StatusPreconditionRequired)
+		return 0, false
+	}
+	if header == "*" {
+		return 0, true
+	}
+
+	version, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(header, `"`), `"`))
+	if err != nil || version < 1 || Format(version) != header {
+		http.Error(w, "Invalid If-Match header, expected an ETag such as \"1\"", http.StatusPreconditionFailed)
+		return 0, false
+	}
+	return version, true
+}
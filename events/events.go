@@ -0,0 +1,45 @@
+// Package events delivers the changes made in a workspace to the clients subscribed to it
+package events
+
+import (
+	"context"
+	"time"
+)
+
+// Event types
+const (
+	ListCreated    = "list.created"
+	ListUpdated    = "list.updated"
+	ListDeleted    = "list.deleted"
+	ItemUpdated    = "item.updated"
+	ItemChecked    = "item.checked"
+	ItemDeleted    = "item.deleted"
+	ProductCreated = "product.created"
+	ProductUpdated = "product.updated"
+	ProductDeleted = "product.deleted"
+)
+
+// Event is a change made in a workspace. Data holds the changed record as returned by the
+// API, it is left out for deletions and for the list changes applied by sync, whose clients
+// fetch the list again.
+type Event struct {
+	// ID is set by the broker and increases with every event it publishes
+	ID          int64  `json:"id"`
+	Type        string `json:"type"`
+	WorkspaceID int    `json:"workspace_id"`
+	ListID      int    `json:"list_id,omitempty"`
+	ProductID   int    `json:"product_id,omitempty"`
+	// UserID is the user who made the change
+	UserID     int         `json:"user_id"`
+	Data       interface{} `json:"data,omitempty"`
+	OccurredAt time.Time   `json:"occurred_at"`
+}
+
+// Broker fans the events of a workspace out to its subscribers. Implementations must be
+// safe for concurrent use and Publish must not wait for slow subscribers.
+type Broker interface {
+	Publish(ctx context.Context, event Event) error
+	// Subscribe returns the events published to the workspace from now on. The channel is
+	// closed when ctx is done, or earlier if the subscriber falls too far behind.
+	Subscribe(ctx context.Context, workspaceID int) (<-chan Event, error)
+}
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// subscriberBuffer is the number of events a subscriber may fall behind before it is dropped
+const subscriberBuffer = 64
+
+// MemoryBroker delivers events to the subscribers connected to this process only. Running
+// several instances of the server needs a broker backed by a shared bus instead.
+type MemoryBroker struct {
+	mu          sync.Mutex
+	nextID      int64
+	subscribers map[int]map[chan Event]struct{}
+}
+
+// NewMemoryBroker returns a broker without subscribers
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subscribers: make(map[int]map[chan Event]struct{})}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, event Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	event.ID = b.nextID
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	// A subscriber whose buffer is full has missed events, closing its channel lets the
+	// client reconnect and refetch rather than silently diverge
+	for ch := range b.subscribers[event.WorkspaceID] {
+		select {
+		case ch <- event:
+		default:
+			b.remove(event.WorkspaceID, ch)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, workspaceID int) (<-chan Event, error) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[workspaceID] == nil {
+		b.subscribers[workspaceID] = make(map[chan Event]struct{})
+	}
+	b.subscribers[workspaceID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(workspaceID, ch)
+	}()
+	return ch, nil
+}
+
+// remove unsubscribes and closes a channel unless it is already gone, the caller must hold b.mu
+func (b *MemoryBroker) remove(workspaceID int, ch chan Event) {
+	if _, ok := b.subscribers[workspaceID][ch]; !ok {
+		return
+	}
+	delete(b.subscribers[workspaceID], ch)
+	if len(b.subscribers[workspaceID]) == 0 {
+		delete(b.subscribers, workspaceID)
+	}
+	close(ch)
+}
@@ -0,0 +1,104 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"shopping_list/middleware"
+	"shopping_list/store"
+
+	"github.com/gorilla/mux"
+)
+
+// heartbeatInterval keeps idle connections open through proxies and rechecks the access and token of the user
+const heartbeatInterval = 25 * time.Second
+
+var (
+	stores *store.Stores
+	broker Broker
+)
+
+// SetStores injects the storage backends used by the event stream
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
+// SetBroker injects the broker the event stream subscribes to
+func SetBroker(b Broker) {
+	broker = b
+}
+
+// Stream sends the changes made in a workspace as Server-Sent Events, named after the event
+// type with the JSON encoded event as data. The stream ends when the token expires or is revoked,
+// when the user loses access to the workspace, and when the client falls too far behind; clients
+// should then reconnect and refetch the lists.
+func Stream(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, err := broker.Subscribe(r.Context(), workspaceID)
+	if err != nil {
+		http.Error(w, "Error subscribing to workspace events", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	expired := time.NewTimer(time.Until(principal.ExpiresAt))
+	defer expired.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-expired.C:
+			return
+		case <-heartbeat.C:
+			if _, err := stores.Workspaces.GetWorkspaceRole(r.Context(), workspaceID, principal.UserID); err != nil {
+				return
+			}
+			if revoked, err := middleware.TokenRevoked(r.Context(), principal); err != nil || revoked {
+				return
+			}
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
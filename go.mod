@@ -3,10 +3,11 @@ module shopping_list
 go 1.24.0
 
 require (
-	filippo.io/edwards25519 v1.1.0 // indirect
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
-	github.com/go-sql-driver/mysql v1.9.0 // indirect
+	github.com/go-sql-driver/mysql v1.9.0
 	github.com/gorilla/mux v1.8.1
 	github.com/joho/godotenv v1.5.1
 	golang.org/x/crypto v0.36.0
 )
+
+require filippo.io/edwards25519 v1.1.0 // indirect
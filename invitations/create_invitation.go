@@ -0,0 +1,156 @@
+package invitations
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	netmail "net/mail"
+	"net/url"
+	"strconv"
+	"time"
+
+	"shopping_list/config"
+	"shopping_list/mail"
+	"shopping_list/middleware"
+	"shopping_list/store"
+	"shopping_list/tokens"
+
+	"github.com/gorilla/mux"
+)
+
+// CreateInvitationRequest represents the request body for inviting someone to a workspace
+type CreateInvitationRequest struct {
+	Email string     `json:"email"`
+	Role  store.Role `json:"role"`
+}
+
+// CreateInvitation invites an email address to the workspace, whether or not it belongs to a registered user
+func CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated user from the request context
+	loggedInUserID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get workspace ID from URL parameters
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	// The role is optional and defaults to editor
+	req := CreateInvitationRequest{Role: store.RoleEditor}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	addr, err := netmail.ParseAddress(req.Email)
+	if err != nil {
+		http.Error(w, "Invalid email", http.StatusBadRequest)
+		return
+	}
+	if !store.ValidMemberRole(req.Role) {
+		http.Error(w, "Invalid role", http.StatusBadRequest)
+		return
+	}
+	// Keep only the address of "Jane <jane@example.com>", it is what users register and log in with
+	email := NormalizeEmail(addr.Address)
+
+	workspace, err := stores.Workspaces.GetWorkspace(r.Context(), workspaceID)
+	if err != nil {
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+		return
+	}
+
+	// Registered users who already have access cannot be invited
+	user, err := stores.Users.GetUserByEmail(r.Context(), email)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Error checking workspace membership", http.StatusInternalServerError)
+		return
+	}
+	if user != nil {
+		_, err := stores.Workspaces.GetWorkspaceRole(r.Context(), workspaceID, user.ID)
+		if err == nil {
+			http.Error(w, "User is already part of this workspace", http.StatusConflict)
+			return
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Error checking workspace membership", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	_, err = stores.Invitations.GetOpenInvitation(r.Context(), workspaceID, email)
+	if err == nil {
+		http.Error(w, "An invitation is already pending for this email", http.StatusConflict)
+		return
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Error checking invitations", http.StatusInternalServerError)
+		return
+	}
+
+	invitation := store.Invitation{
+		WorkspaceID:   workspaceID,
+		WorkspaceName: workspace.Name,
+		Email:         email,
+		Role:          req.Role,
+		InvitedBy:     loggedInUserID,
+		ExpiresAt:     time.Now().Add(config.Invitations.Expiry),
+	}
+	if err := stores.Invitations.CreateInvitation(r.Context(), &invitation); err != nil {
+		http.Error(w, "Error creating invitation", http.StatusInternalServerError)
+		return
+	}
+
+	if err := sendInvitation(r, &invitation); err != nil {
+		// Nobody can answer an invitation that was never delivered
+		stores.Invitations.CloseInvitation(r.Context(), invitation.ID, store.InvitationRevoked)
+		http.Error(w, "Error sending invitation email", http.StatusInternalServerError)
+		return
+	}
+
+	response := defaultResponse{
+		Data:   invitation,
+		Status: "Success",
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// sendInvitation emails the invitation link carrying a signed token to the invitee
+func sendInvitation(r *http.Request, invitation *store.Invitation) error {
+	token, err := tokens.SignInvitation(invitation.ID, invitation.Email, invitation.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(config.Invitations.AcceptURL)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	inviter := "Someone"
+	if principal, ok := middleware.PrincipalFromContext(r.Context()); ok && principal.Name != "" {
+		inviter = principal.Name
+	}
+
+	return sender.Send(r.Context(), mail.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("You have been invited to %s", invitation.WorkspaceName),
+		Body: fmt.Sprintf(
+			"%s invited you to join the workspace %q as %s.\n\nOpen the link below to accept or decline the invitation:\n%s\n\n"+
+				"If you don't have an account yet, register with this email address and you will join the workspace automatically.\n"+
+				"The invitation expires on %s.\n",
+			inviter, invitation.WorkspaceName, invitation.Role, link.String(), invitation.ExpiresAt.Format(time.RFC1123),
+		),
+	})
+}
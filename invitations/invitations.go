@@ -0,0 +1,87 @@
+package invitations
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"shopping_list/mail"
+	"shopping_list/middleware"
+	"shopping_list/store"
+
+	"github.com/gorilla/mux"
+)
+
+var stores *store.Stores
+
+// SetStores injects the storage backends used by the invitation handlers
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
+var sender mail.Sender
+
+// SetSender injects the mail sender used to deliver the invitations
+func SetSender(s mail.Sender) {
+	sender = s
+}
+
+type defaultResponse struct {
+	Status string      `json:"status"`
+	Data   interface{} `json:"data"`
+}
+
+// NormalizeEmail returns the form in which invitation emails are stored and compared
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ListWorkspaceInvitations returns the pending invitations of a workspace
+func ListWorkspaceInvitations(w http.ResponseWriter, r *http.Request) {
+	// Get workspace ID from URL parameters
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	invitations, err := stores.Invitations.ListWorkspaceInvitations(r.Context(), workspaceID)
+	if err != nil {
+		http.Error(w, "Error fetching invitations", http.StatusInternalServerError)
+		return
+	}
+
+	response := defaultResponse{
+		Data:   invitations,
+		Status: "Success",
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// ListMyInvitations returns the open invitations sent to the email of the authenticated user
+func ListMyInvitations(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated user from the request context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	invitations, err := stores.Invitations.ListInvitationsByEmail(r.Context(), NormalizeEmail(principal.Email))
+	if err != nil {
+		http.Error(w, "Error fetching invitations", http.StatusInternalServerError)
+		return
+	}
+
+	response := defaultResponse{
+		Data:   invitations,
+		Status: "Success",
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
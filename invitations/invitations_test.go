@@ -0,0 +1,317 @@
+package invitations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shopping_list/config"
+	"shopping_list/mail"
+	"shopping_list/middleware"
+	"shopping_list/store"
+
+	"github.com/gorilla/mux"
+)
+
+// recordingSender keeps the messages it is asked to send
+type recordingSender struct {
+	mu       sync.Mutex
+	messages []mail.Message
+}
+
+func (s *recordingSender) Send(ctx context.Context, msg mail.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// fixture is a workspace of its owner and a registered user who is not a member yet
+type fixture struct {
+	s         *store.Stores
+	sender    *recordingSender
+	workspace *store.Workspace
+	owner     *middleware.Principal
+	jane      *middleware.Principal
+}
+
+func setup(t *testing.T) *fixture {
+	t.Helper()
+	ctx := context.Background()
+	config.JWT = config.JWTConfig{Secret: []byte(strings.Repeat("k", 32)), Issuer: "shopping_list", Audience: "shopping_list"}
+	config.Invitations = config.InvitationConfig{Expiry: time.Hour, AcceptURL: "https://app.example.com/invitations"}
+
+	f := &fixture{s: store.NewMemory(), sender: &recordingSender{}}
+	SetStores(f.s)
+	SetSender(f.sender)
+
+	owner := &store.User{Email: "owner@example.com", Name: "Owner"}
+	jane := &store.User{Email: "Jane@Example.com", Name: "Jane"}
+	for _, user := range []*store.User{owner, jane} {
+		if err := f.s.Users.CreateUser(ctx, user); err != nil {
+			t.Fatalf("CreateUser: %s", err)
+		}
+	}
+	f.owner = &middleware.Principal{UserID: owner.ID, Email: owner.Email, Name: owner.Name}
+	f.jane = &middleware.Principal{UserID: jane.ID, Email: jane.Email, Name: jane.Name}
+
+	f.workspace = &store.Workspace{Name: "home", UserID: owner.ID}
+	if err := f.s.Workspaces.CreateWorkspace(ctx, f.workspace); err != nil {
+		t.Fatalf("CreateWorkspace: %s", err)
+	}
+	return f
+}
+
+// serve routes a request to a handler as the principal, or anonymously when it is nil
+func serve(route string, handler http.HandlerFunc, method, path, body string, principal *middleware.Principal) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	router.HandleFunc(route, handler).Methods(method)
+
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if principal != nil {
+		r = r.WithContext(middleware.WithPrincipal(r.Context(), principal))
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+// invite invites an email to the workspace as the owner
+func (f *fixture) invite(email string, role store.Role) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(CreateInvitationRequest{Email: email, Role: role})
+	path := fmt.Sprintf("/workspaces/%d/invitations", f.workspace.ID)
+	return serve("/workspaces/{workspace_id}/invitations", CreateInvitation, http.MethodPost, path, string(body), f.owner)
+}
+
+// mustInvite invites an email and returns the stored invitation and its emailed token
+func (f *fixture) mustInvite(t *testing.T, email string, role store.Role) (*store.Invitation, string) {
+	t.Helper()
+	w := f.invite(email, role)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("invite status = %d: %s", w.Code, w.Body)
+	}
+	var response struct {
+		Data store.Invitation `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %s", err)
+	}
+	return &response.Data, f.emailedToken(t)
+}
+
+var acceptLink = regexp.MustCompile(`https://app\.example\.com/invitations\?\S+`)
+
+// emailedToken returns the token of the link in the last message sent
+func (f *fixture) emailedToken(t *testing.T) string {
+	t.Helper()
+	if len(f.sender.messages) == 0 {
+		t.Fatal("no invitation was sent")
+	}
+	link, err := url.Parse(acceptLink.FindString(f.sender.messages[len(f.sender.messages)-1].Body))
+	if err != nil || link.Query().Get("token") == "" {
+		t.Fatalf("the invitation has no link with a token: %v", err)
+	}
+	return link.Query().Get("token")
+}
+
+// status returns the stored status of an invitation
+func (f *fixture) status(t *testing.T, invitationID int) store.InvitationStatus {
+	t.Helper()
+	invitation, err := f.s.Invitations.GetInvitation(context.Background(), invitationID)
+	if err != nil {
+		t.Fatalf("GetInvitation: %s", err)
+	}
+	return invitation.Status
+}
+
+func respond(invitationID int, answer string, principal *middleware.Principal) *httptest.ResponseRecorder {
+	handler := AcceptInvitation
+	if answer == "decline" {
+		handler = DeclineInvitation
+	}
+	path := fmt.Sprintf("/invitations/%d/%s", invitationID, answer)
+	return serve("/invitations/{invitation_id}/"+answer, handler, http.MethodPost, path, "", principal)
+}
+
+func respondByToken(token, answer string, principal *middleware.Principal) *httptest.ResponseRecorder {
+	handler := AcceptInvitationByToken
+	if answer == "decline" {
+		handler = DeclineInvitationByToken
+	}
+	body, _ := json.Marshal(InvitationTokenRequest{Token: token})
+	return serve("/invitations/"+answer, handler, http.MethodPost, "/invitations/"+answer, string(body), principal)
+}
+
+func revoke(f *fixture, workspaceID, invitationID int) *httptest.ResponseRecorder {
+	path := fmt.Sprintf("/workspaces/%d/invitations/%d", workspaceID, invitationID)
+	return serve("/workspaces/{workspace_id}/invitations/{invitation_id}", RevokeInvitation, http.MethodDelete, path, "", f.owner)
+}
+
+func TestCreateInvitationNormalizesTheEmail(t *testing.T) {
+	for _, email := range []string{"jane@example.com", "  JANE@Example.com ", "Jane Doe <Jane@EXAMPLE.com>"} {
+		t.Run(email, func(t *testing.T) {
+			f := setup(t)
+			invitation, _ := f.mustInvite(t, email, store.RoleViewer)
+			if invitation.Email != "jane@example.com" {
+				t.Errorf("invitation email = %q, want jane@example.com", invitation.Email)
+			}
+			if to := f.sender.messages[0].To; to != "jane@example.com" {
+				t.Errorf("invitation sent to %q, want jane@example.com", to)
+			}
+
+			// The same address written another way is already invited
+			if w := f.invite("Jane@example.COM", store.RoleEditor); w.Code != http.StatusConflict {
+				t.Errorf("second invitation status = %d, want %d", w.Code, http.StatusConflict)
+			}
+		})
+	}
+}
+
+func TestCreateInvitationRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		email  string
+		role   store.Role
+		status int
+	}{
+		{"invalid email", "jane", store.RoleEditor, http.StatusBadRequest},
+		{"owner role", "jane@example.com", store.RoleOwner, http.StatusBadRequest},
+		{"unknown role", "jane@example.com", "admin", http.StatusBadRequest},
+		{"member", "OWNER@example.com", store.RoleEditor, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := setup(t)
+			if w := f.invite(tt.email, tt.role); w.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if len(f.sender.messages) != 0 {
+				t.Errorf("%d invitations were sent, want none", len(f.sender.messages))
+			}
+		})
+	}
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	for _, byToken := range []bool{false, true} {
+		t.Run(fmt.Sprintf("by token %v", byToken), func(t *testing.T) {
+			f := setup(t)
+			invitation, token := f.mustInvite(t, "jane@example.com", store.RoleViewer)
+			accept := func(principal *middleware.Principal) *httptest.ResponseRecorder {
+				if byToken {
+					return respondByToken(token, "accept", principal)
+				}
+				return respond(invitation.ID, "accept", principal)
+			}
+
+			// Only the invitee can accept, whatever the case of the email they registered with
+			wantOther := http.StatusNotFound
+			if byToken {
+				wantOther = http.StatusForbidden
+			}
+			if w := accept(f.owner); w.Code != wantOther {
+				t.Errorf("accepting as another user status = %d, want %d", w.Code, wantOther)
+			}
+			if w := accept(f.jane); w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+			}
+
+			role, err := f.s.Workspaces.GetWorkspaceRole(context.Background(), f.workspace.ID, f.jane.UserID)
+			if err != nil || role != store.RoleViewer {
+				t.Errorf("GetWorkspaceRole = %q, %v, want the invited role %q", role, err, store.RoleViewer)
+			}
+			if status := f.status(t, invitation.ID); status != store.InvitationAccepted {
+				t.Errorf("invitation status = %s, want %s", status, store.InvitationAccepted)
+			}
+			if w := accept(f.jane); w.Code != http.StatusConflict {
+				t.Errorf("accepting again status = %d, want %d", w.Code, http.StatusConflict)
+			}
+		})
+	}
+}
+
+func TestDeclineInvitation(t *testing.T) {
+	f := setup(t)
+	byID, _ := f.mustInvite(t, "jane@example.com", store.RoleEditor)
+	if w := respond(byID.ID, "decline", f.jane); w.Code != http.StatusOK {
+		t.Fatalf("decline status = %d: %s", w.Code, w.Body)
+	}
+	if status := f.status(t, byID.ID); status != store.InvitationDeclined {
+		t.Errorf("invitation status = %s, want %s", status, store.InvitationDeclined)
+	}
+
+	// People without an account decline with the emailed token alone
+	byToken, token := f.mustInvite(t, "Someone@example.com", store.RoleEditor)
+	if w := respondByToken("invalid", "decline", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("decline with an invalid token status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := respondByToken(token, "decline", nil); w.Code != http.StatusOK {
+		t.Fatalf("decline by token status = %d: %s", w.Code, w.Body)
+	}
+	if status := f.status(t, byToken.ID); status != store.InvitationDeclined {
+		t.Errorf("invitation status = %s, want %s", status, store.InvitationDeclined)
+	}
+
+	if w := respond(byID.ID, "accept", f.jane); w.Code != http.StatusConflict {
+		t.Errorf("accepting a declined invitation status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if _, err := f.s.Workspaces.GetWorkspaceRole(context.Background(), f.workspace.ID, f.jane.UserID); err == nil {
+		t.Error("declining the invitation gave access to the workspace")
+	}
+}
+
+func TestRevokeInvitation(t *testing.T) {
+	f := setup(t)
+	invitation, token := f.mustInvite(t, "jane@example.com", store.RoleEditor)
+
+	if w := revoke(f, f.workspace.ID+1, invitation.ID); w.Code != http.StatusNotFound {
+		t.Errorf("revoking from another workspace status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := revoke(f, f.workspace.ID, invitation.ID); w.Code != http.StatusOK {
+		t.Fatalf("revoke status = %d: %s", w.Code, w.Body)
+	}
+	if status := f.status(t, invitation.ID); status != store.InvitationRevoked {
+		t.Errorf("invitation status = %s, want %s", status, store.InvitationRevoked)
+	}
+	if w := revoke(f, f.workspace.ID, invitation.ID); w.Code != http.StatusConflict {
+		t.Errorf("revoking again status = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	// The emailed link no longer works
+	if w := respondByToken(token, "accept", f.jane); w.Code != http.StatusConflict {
+		t.Errorf("accepting a revoked invitation status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if _, err := f.s.Workspaces.GetWorkspaceRole(context.Background(), f.workspace.ID, f.jane.UserID); err == nil {
+		t.Error("a revoked invitation gave access to the workspace")
+	}
+}
+
+func TestAcceptExpiredInvitation(t *testing.T) {
+	f := setup(t)
+	config.Invitations.Expiry = -time.Minute
+	expired, token := f.mustInvite(t, "jane@example.com", store.RoleEditor)
+
+	if w := respond(expired.ID, "accept", f.jane); w.Code != http.StatusGone {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusGone)
+	}
+	if _, err := f.s.Workspaces.GetWorkspaceRole(context.Background(), f.workspace.ID, f.jane.UserID); err == nil {
+		t.Error("an expired invitation gave access to the workspace")
+	}
+
+	// An expired invitation no longer blocks inviting the same email again
+	config.Invitations.Expiry = time.Hour
+	if w := f.invite("Jane@example.com", store.RoleEditor); w.Code != http.StatusCreated {
+		t.Errorf("inviting again status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	if w := respondByToken(token, "accept", f.jane); w.Code == http.StatusOK {
+		t.Error("the link of the expired invitation was accepted")
+	}
+}
@@ -0,0 +1,138 @@
+package invitations
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"shopping_list/middleware"
+	"shopping_list/store"
+	"shopping_list/tokens"
+
+	"github.com/gorilla/mux"
+)
+
+// InvitationTokenRequest represents the request body carrying the token of an emailed invitation
+type InvitationTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// AcceptInvitation joins the workspace of an invitation sent to the authenticated user
+func AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	respondByID(w, r, true)
+}
+
+// DeclineInvitation declines an invitation sent to the authenticated user
+func DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	respondByID(w, r, false)
+}
+
+// AcceptInvitationByToken joins the workspace of the invitation carried by an emailed token.
+// The token must have been sent to the email of the authenticated user.
+func AcceptInvitationByToken(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated user from the request context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	claims, ok := parseInvitationToken(w, r)
+	if !ok {
+		return
+	}
+	if NormalizeEmail(claims.Email) != NormalizeEmail(principal.Email) {
+		http.Error(w, "This invitation was sent to another email address", http.StatusForbidden)
+		return
+	}
+
+	answerInvitation(w, r, claims.InvitationID, claims.Email, principal.UserID, true)
+}
+
+// DeclineInvitationByToken declines the invitation carried by an emailed token.
+// Holding the token is enough, so people without an account can decline too.
+func DeclineInvitationByToken(w http.ResponseWriter, r *http.Request) {
+	claims, ok := parseInvitationToken(w, r)
+	if !ok {
+		return
+	}
+
+	answerInvitation(w, r, claims.InvitationID, claims.Email, 0, false)
+}
+
+func respondByID(w http.ResponseWriter, r *http.Request, accept bool) {
+	// Get the authenticated user from the request context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get invitation ID from URL parameters
+	invitationID, err := strconv.Atoi(mux.Vars(r)["invitation_id"])
+	if err != nil {
+		http.Error(w, "Invalid invitation ID", http.StatusBadRequest)
+		return
+	}
+
+	answerInvitation(w, r, invitationID, principal.Email, principal.UserID, accept)
+}
+
+// parseInvitationToken reads and verifies the invitation token of the request body, writing the error response if it is invalid
+func parseInvitationToken(w http.ResponseWriter, r *http.Request) (*tokens.InvitationClaims, bool) {
+	var req InvitationTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return nil, false
+	}
+
+	claims, err := tokens.ParseInvitation(req.Token)
+	if err != nil {
+		http.Error(w, "Invalid or expired invitation token", http.StatusBadRequest)
+		return nil, false
+	}
+	return claims, true
+}
+
+// answerInvitation accepts or declines the invitation if it was sent to the email and is still open
+func answerInvitation(w http.ResponseWriter, r *http.Request, invitationID int, email string, userID int, accept bool) {
+	invitation, err := stores.Invitations.GetInvitation(r.Context(), invitationID)
+	if err != nil || invitation.Email != NormalizeEmail(email) {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+	if !invitation.Open(time.Now()) {
+		if invitation.Status == store.InvitationPending {
+			http.Error(w, "Invitation has expired", http.StatusGone)
+			return
+		}
+		http.Error(w, "Invitation is no longer pending", http.StatusConflict)
+		return
+	}
+
+	message := "Invitation successfully declined"
+	if accept {
+		message = "Invitation successfully accepted"
+		err = stores.Invitations.AcceptInvitation(r.Context(), invitationID, userID)
+	} else {
+		err = stores.Invitations.CloseInvitation(r.Context(), invitationID, store.InvitationDeclined)
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Invitation is no longer pending", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error answering invitation", http.StatusInternalServerError)
+		return
+	}
+
+	response := defaultResponse{
+		Data:   message,
+		Status: "Success",
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,52 @@
+package invitations
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"shopping_list/store"
+
+	"github.com/gorilla/mux"
+)
+
+// RevokeInvitation withdraws a pending invitation of the workspace
+func RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	// Get workspace ID and invitation ID from URL parameters
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+	invitationID, err := strconv.Atoi(vars["invitation_id"])
+	if err != nil {
+		http.Error(w, "Invalid invitation ID", http.StatusBadRequest)
+		return
+	}
+
+	invitation, err := stores.Invitations.GetInvitation(r.Context(), invitationID)
+	if err != nil || invitation.WorkspaceID != workspaceID {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+
+	err = stores.Invitations.CloseInvitation(r.Context(), invitationID, store.InvitationRevoked)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Invitation is no longer pending", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error revoking invitation", http.StatusInternalServerError)
+		return
+	}
+
+	response := defaultResponse{
+		Data:   "Invitation successfully revoked",
+		Status: "Success",
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
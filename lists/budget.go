@@ -0,0 +1,120 @@
+package lists
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"shopping_list/money"
+	"shopping_list/store"
+
+	"github.com/gorilla/mux"
+)
+
+// SetListBudgetRequest represents the request body for setting the budget of a list
+type SetListBudgetRequest struct {
+	// Budget is a decimal amount in the workspace currency, null removes the budget
+	Budget money.Decimal `json:"budget"`
+}
+
+// parseBudget reads an optional budget in the workspace currency, writing the error response
+// if it is not a positive amount with at most the decimals of the currency
+func parseBudget(w http.ResponseWriter, value money.Decimal, currency string) (*money.Money, bool) {
+	if value == "" {
+		return nil, true
+	}
+
+	budget, err := value.Money(currency)
+	if err != nil || budget.Amount <= 0 {
+		http.Error(w, "Invalid budget, expected a positive amount in "+currency, http.StatusBadRequest)
+		return nil, false
+	}
+	return &budget, true
+}
+
+// SetListBudget handles setting or removing the budget of a list and returns the list with its estimated total
+func SetListBudget(w http.ResponseWriter, r *http.Request) {
+	// Get workspace ID and list ID from URL parameters
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	listID, err := strconv.Atoi(vars["list_id"])
+	if err != nil {
+		http.Error(w, "Invalid list ID", http.StatusBadRequest)
+		return
+	}
+
+	// Parse request body
+	var req SetListBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	workspace, err := stores.Workspaces.GetWorkspace(r.Context(), workspaceID)
+	if err != nil {
+		http.Error(w, "Error fetching workspace", http.StatusInternalServerError)
+		return
+	}
+	budget, ok := parseBudget(w, req.Budget, workspace.Currency)
+	if !ok {
+		return
+	}
+
+	// Check if the list exists and belongs to the specified workspace
+	storedList, err := stores.Lists.GetList(r.Context(), listID)
+	if err != nil || storedList.WorkspaceID != workspaceID {
+		http.Error(w, "List not found in this workspace", http.StatusNotFound)
+		return
+	}
+
+	err = stores.Lists.SetListBudget(r.Context(), listID, budget)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "List not found in this workspace", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error updating list budget", http.StatusInternalServerError)
+		return
+	}
+
+	items, err := stores.Lists.ListItems(r.Context(), listID)
+	if err != nil {
+		http.Error(w, "Error retrieving list products", http.StatusInternalServerError)
+		return
+	}
+
+	storedList, err = stores.Lists.GetList(r.Context(), listID)
+	if err != nil {
+		http.Error(w, "Error fetching list", http.StatusInternalServerError)
+		return
+	}
+	list := newProductList(*storedList)
+	for _, item := range items {
+		list.Products = append(list.Products, newListProduct(item))
+	}
+	if err := list.setCost(items, workspace.Currency); err != nil {
+		http.Error(w, "Error estimating list total", http.StatusInternalServerError)
+		return
+	}
+
+	publishList(r, workspaceID, listID)
+
+	// Return the updated list
+	response := struct {
+		Status string      `json:"status"`
+		Data   ProductList `json:"data"`
+	}{
+		Status: "Success",
+		Data:   list,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,88 @@
+package lists
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"shopping_list/store"
+)
+
+// CategoryGroup holds the products of a list in one category, Category is nil for uncategorized products
+type CategoryGroup struct {
+	Category *store.Category `json:"category"`
+	Aisle    string          `json:"aisle,omitempty"`
+	Products []Product       `json:"products"`
+}
+
+// walkingOrder orders categories by the layout of a store when one is given, and by their
+// position in the workspace otherwise. Categories missing from the layout come after the
+// ones in it, and uncategorized products come last.
+type walkingOrder struct {
+	layout map[int]store.ShopAisle
+}
+
+// newWalkingOrder returns the walking order of a store layout, or of the category positions if aisles is nil
+func newWalkingOrder(aisles []store.ShopAisle) walkingOrder {
+	order := walkingOrder{layout: make(map[int]store.ShopAisle, len(aisles))}
+	for _, aisle := range aisles {
+		order.layout[aisle.CategoryID] = aisle
+	}
+	return order
+}
+
+// rank returns the place of a category in the walking order, lower ranks come first
+func (o walkingOrder) rank(category *store.Category) int {
+	if category == nil {
+		return math.MaxInt
+	}
+	if aisle, ok := o.layout[category.ID]; ok {
+		return aisle.Position
+	}
+	return math.MaxInt32 + category.Position
+}
+
+// aisle returns the aisle label of a category in the store layout
+func (o walkingOrder) aisle(category *store.Category) string {
+	if category == nil {
+		return ""
+	}
+	return o.layout[category.ID].Aisle
+}
+
+// sort orders products by the rank of their category, then by name
+func (o walkingOrder) sort(products []Product) {
+	sort.SliceStable(products, func(i, j int) bool {
+		a, b := products[i].Category, products[j].Category
+		if rankA, rankB := o.rank(a), o.rank(b); rankA != rankB {
+			return rankA < rankB
+		}
+		if a != nil && b != nil && a.ID != b.ID {
+			if a.Name != b.Name {
+				return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+			}
+			return a.ID < b.ID
+		}
+		return strings.ToLower(products[i].Name) < strings.ToLower(products[j].Name)
+	})
+}
+
+// group splits products sorted by the walking order into one group per category
+func (o walkingOrder) group(products []Product) []CategoryGroup {
+	var groups []CategoryGroup
+	for _, product := range products {
+		if len(groups) == 0 || !sameCategory(groups[len(groups)-1].Category, product.Category) {
+			groups = append(groups, CategoryGroup{Category: product.Category, Aisle: o.aisle(product.Category)})
+		}
+		current := &groups[len(groups)-1]
+		current.Products = append(current.Products, product)
+	}
+	return groups
+}
+
+func sameCategory(a, b *store.Category) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.ID == b.ID
+}
@@ -0,0 +1,113 @@
+package lists
+
+import (
+	"reflect"
+	"testing"
+
+	"shopping_list/store"
+)
+
+var (
+	dairy   = &store.Category{ID: 1, Name: "Dairy", Position: 2}
+	bakery  = &store.Category{ID: 2, Name: "Bakery", Position: 1}
+	fruit   = &store.Category{ID: 3, Name: "fruit", Position: 3}
+	frozen  = &store.Category{ID: 4, Name: "Frozen", Position: 3}
+	cleaner = &store.Category{ID: 5, Name: "Cleaning", Position: 4}
+)
+
+func product(id int, name string, category *store.Category) Product {
+	return Product{ListProduct: ListProduct{ProductID: id}, Name: name, Category: category}
+}
+
+// products returns the products of the tests in no particular order
+func products() []Product {
+	return []Product{
+		product(1, "milk", dairy),
+		product(2, "Sponges", cleaner),
+		product(3, "bread", bakery),
+		product(4, "Apples", fruit),
+		product(5, "batteries", nil),
+		product(6, "butter", dairy),
+		product(7, "Ice cream", frozen),
+		product(8, "Bagels", bakery),
+		product(9, "Candles", nil),
+	}
+}
+
+func productIDs(products []Product) []int {
+	ids := make([]int, 0, len(products))
+	for _, product := range products {
+		ids = append(ids, product.ProductID)
+	}
+	return ids
+}
+
+func TestWalkingOrderSort(t *testing.T) {
+	tests := []struct {
+		name   string
+		aisles []store.ShopAisle
+		want   []int
+	}{
+		// Category positions, fruit and frozen share a position and are ordered by name
+		{"category positions", nil, []int{8, 3, 6, 1, 7, 4, 2, 5, 9}},
+		{"store layout", []store.ShopAisle{
+			{CategoryID: fruit.ID, Position: 1},
+			{CategoryID: cleaner.ID, Position: 2},
+			{CategoryID: dairy.ID, Position: 3},
+			{CategoryID: frozen.ID, Position: 4},
+			{CategoryID: bakery.ID, Position: 5},
+		}, []int{4, 2, 6, 1, 7, 8, 3, 5, 9}},
+		// Categories missing from the layout follow it by their position
+		{"partial layout", []store.ShopAisle{
+			{CategoryID: cleaner.ID, Position: 1},
+			{CategoryID: frozen.ID, Position: 2},
+		}, []int{2, 7, 8, 3, 6, 1, 4, 5, 9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := products()
+			newWalkingOrder(tt.aisles).sort(list)
+			if got := productIDs(list); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sort = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkingOrderGroup(t *testing.T) {
+	order := newWalkingOrder([]store.ShopAisle{
+		{CategoryID: dairy.ID, Position: 1, Aisle: "A1"},
+		{CategoryID: bakery.ID, Position: 2},
+	})
+	list := products()
+	order.sort(list)
+	groups := order.group(list)
+
+	want := []struct {
+		category *store.Category
+		aisle    string
+		products []int
+	}{
+		{dairy, "A1", []int{6, 1}},
+		{bakery, "", []int{8, 3}},
+		{frozen, "", []int{7}},
+		{fruit, "", []int{4}},
+		{cleaner, "", []int{2}},
+		{nil, "", []int{5, 9}},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("got %d groups, want %d", len(groups), len(want))
+	}
+	for i, group := range groups {
+		if group.Category != want[i].category || group.Aisle != want[i].aisle {
+			t.Errorf("group %d = %v in aisle %q, want %v in aisle %q", i, group.Category, group.Aisle, want[i].category, want[i].aisle)
+		}
+		if got := productIDs(group.Products); !reflect.DeepEqual(got, want[i].products) {
+			t.Errorf("group %d products = %v, want %v", i, got, want[i].products)
+		}
+	}
+
+	if groups := order.group(nil); groups != nil {
+		t.Errorf("group(nil) = %v, want no groups", groups)
+	}
+}
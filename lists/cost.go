@@ -0,0 +1,32 @@
+package lists
+
+import (
+	"shopping_list/money"
+	"shopping_list/store"
+)
+
+// estimateTotal returns the cost of the items of a list at their current prices
+func estimateTotal(items []store.ListItem, currency string) (money.Money, error) {
+	total := money.New(0, currency)
+	for _, item := range items {
+		cost, err := item.Price.Mul(int64(item.Quantity))
+		if err != nil {
+			return total, err
+		}
+		if total, err = total.Add(cost); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// setCost sets the estimated total of a list and whether it exceeds the budget of the list
+func (l *ProductList) setCost(items []store.ListItem, currency string) error {
+	total, err := estimateTotal(items, currency)
+	if err != nil {
+		return err
+	}
+	l.EstimatedTotal = total
+	l.OverBudget = l.Budget != nil && total.Amount > l.Budget.Amount
+	return nil
+}
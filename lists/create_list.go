@@ -1,10 +1,15 @@
 package lists
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
-	"shopping_list/db"
+	"shopping_list/etag"
+	"shopping_list/events"
 	"shopping_list/middleware"
+	"shopping_list/money"
+	"shopping_list/store"
 	"strconv"
 	"time"
 
@@ -18,31 +23,77 @@ type ProductList struct {
 	UserID      int           `json:"user_id"`
 	Title       string        `json:"title"`
 	Products    []ListProduct `json:"products,omitempty"`
-	CreatedAt   time.Time     `json:"created_at"`
-	UpdatedAt   time.Time     `json:"updated_at"`
-	DeletedAt   *time.Time    `json:"deleted_at,omitempty"`
-}
-
-type ListProduct struct {
-	ListID    int        `json:"list_id"`
-	ProductID int        `json:"product_id"`
-	Quantity  int        `json:"quantity"`
-	Checked   bool       `json:"checked"`
+	// EstimatedTotal is the sum of quantity × current price of the products
+	EstimatedTotal money.Money  `json:"estimated_total"`
+	Budget         *money.Money `json:"budget"`
+	OverBudget     bool         `json:"over_budget"`
+	// Version changes on every change to the list or its products, it is also sent as the ETag
+	Version   int        `json:"version"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
-// ListProduct represents a product in a list with its quantity
+type ListProduct struct {
+	ListID    int  `json:"list_id"`
+	ProductID int  `json:"product_id"`
+	Quantity  int  `json:"quantity"`
+	Checked   bool `json:"checked"`
+	// Price is the current unit price of the product
+	Price money.Money `json:"price"`
+	// Purchase is what was bought when the item was checked
+	Purchase  *store.Purchase `json:"purchase,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	DeletedAt *time.Time      `json:"deleted_at,omitempty"`
+}
+
+// newProductList converts a stored list into its response representation
+func newProductList(list store.List) ProductList {
+	return ProductList{
+		ID:          list.ID,
+		WorkspaceID: list.WorkspaceID,
+		UserID:      list.UserID,
+		Title:       list.Title,
+		Budget:      list.Budget,
+		Version:     list.Version,
+		CreatedAt:   list.CreatedAt,
+		UpdatedAt:   list.UpdatedAt,
+		DeletedAt:   list.DeletedAt,
+	}
+}
+
+// newListProduct converts a stored list item into its response representation
+func newListProduct(item store.ListItem) ListProduct {
+	return ListProduct{
+		ListID:    item.ListID,
+		ProductID: item.ProductID,
+		Quantity:  item.Quantity,
+		Checked:   item.Checked,
+		Price:     item.Price,
+		Purchase:  item.Purchase,
+		CreatedAt: item.CreatedAt,
+		UpdatedAt: item.UpdatedAt,
+		DeletedAt: item.DeletedAt,
+	}
+}
+
+// ListProductRequest represents a product in a list with its quantity
 type ListProductRequest struct {
 	ProductID int `json:"product_id"`
 	Quantity  int `json:"quantity"`
+	// Checked and Purchase are only used when updating a list, a missing
+	// checked leaves the item checked or unchecked
+	Checked  *bool            `json:"checked"`
+	Purchase *PurchaseRequest `json:"purchase"`
 }
 
 // CreateProductListRequest represents the request body for creating a product list
 type CreateProductListRequest struct {
 	Title    string               `json:"title"`
 	Products []ListProductRequest `json:"products"`
+	// Budget is an optional decimal amount in the workspace currency
+	Budget money.Decimal `json:"budget"`
 }
 
 // CreateProductList handles the creation of a new product list
@@ -55,9 +106,9 @@ func CreateProductList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user ID from the token
-	loggedInUserID, err := middleware.ExtractUserIDFromToken(r)
-	if err != nil {
+	// Get the authenticated user from the request context
+	loggedInUserID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -75,113 +126,125 @@ func CreateProductList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Begin transaction
-	tx, err := db.DB.Begin()
+	workspace, err := stores.Workspaces.GetWorkspace(r.Context(), workspaceID)
 	if err != nil {
-		http.Error(w, "Error starting transaction", http.StatusInternalServerError)
+		http.Error(w, "Error fetching workspace", http.StatusInternalServerError)
+		return
+	}
+	budget, ok := parseBudget(w, req.Budget, workspace.Currency)
+	if !ok {
 		return
 	}
-	defer tx.Rollback() // Rollback if not committed
 
-	// Create the product list
-	result, err := tx.Exec(
-		"INSERT INTO lists (workspace_id, user_id, title, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
-		workspaceID, loggedInUserID, req.Title, time.Now(), time.Now(),
-	)
+	productList, err := createList(r.Context(), workspace, loggedInUserID, req.Title, req.Products, budget)
+	var invalid invalidListError
+	if errors.As(err, &invalid) {
+		http.Error(w, invalid.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error creating product list: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Get the ID of the newly created product list
-	listID, err := result.LastInsertId()
+	// Return the created product list
+	response := struct {
+		Status string      `json:"status"`
+		Data   ProductList `json:"data"`
+	}{
+		Status: "Success",
+		Data:   productList,
+	}
+
+	etag.Set(w, productList.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// invalidListError reports a product that cannot be put in a new list
+type invalidListError string
+
+func (e invalidListError) Error() string {
+	return string(e)
+}
+
+// listItem returns the item of a requested product, which must belong to the workspace, or an
+// invalidListError. A missing or non-positive quantity is one.
+func listItem(ctx context.Context, workspaceID int, product ListProductRequest) (store.ItemQuantity, error) {
+	storedProduct, err := stores.Products.GetProduct(ctx, product.ProductID)
 	if err != nil {
-		http.Error(w, "Error retrieving product list ID", http.StatusInternalServerError)
-		return
+		return store.ItemQuantity{}, invalidListError("Product not found: " + strconv.Itoa(product.ProductID))
 	}
+	if storedProduct.WorkspaceID != workspaceID {
+		return store.ItemQuantity{}, invalidListError("Product does not belong to this workspace: " + strconv.Itoa(product.ProductID))
+	}
+
+	// Set default quantity if not provided
+	quantity := product.Quantity
+	if quantity <= 0 {
+		quantity = 1
+	}
+	return store.ItemQuantity{ProductID: product.ProductID, Quantity: quantity}, nil
+}
 
-	// Insert products into the list if provided
-	if len(req.Products) > 0 {
-		// Verify all products belong to the workspace
-		for _, product := range req.Products {
-			var productWorkspaceID int
-			err := tx.QueryRow("SELECT workspace_id FROM products WHERE id = ? AND deleted_at IS NULL", product.ProductID).Scan(&productWorkspaceID)
-			if err != nil {
-				http.Error(w, "Product not found: "+strconv.Itoa(product.ProductID), http.StatusBadRequest)
-				return
-			}
-			if productWorkspaceID != workspaceID {
-				http.Error(w, "Product does not belong to this workspace: "+strconv.Itoa(product.ProductID), http.StatusBadRequest)
-				return
-			}
-
-			// Set default quantity if not provided
-			quantity := product.Quantity
-			if quantity <= 0 {
-				quantity = 1
-			}
-
-			// Insert product into list
-			_, err = tx.Exec(
-				"INSERT INTO list_products (list_id, product_id, quantity, checked, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
-				listID, product.ProductID, quantity, false, time.Now(), time.Now(),
-			)
-			if err != nil {
-				http.Error(w, "Error adding product to list: "+err.Error(), http.StatusInternalServerError)
-				return
-			}
+// createList creates a list of the user with the products, which must belong to the workspace, and
+// publishes it. It returns the list with its estimated total, or an invalidListError if a product
+// cannot be used. CreateProductList, the list templates and their scheduler all create lists with it.
+func createList(ctx context.Context, workspace *store.Workspace, userID int, title string, products []ListProductRequest, budget *money.Money) (ProductList, error) {
+	// Verify all products belong to the workspace
+	items := make([]store.ItemQuantity, 0, len(products))
+	for _, product := range products {
+		item, err := listItem(ctx, workspace.ID, product)
+		if err != nil {
+			return ProductList{}, err
 		}
+		items = append(items, item)
 	}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		http.Error(w, "Error committing transaction", http.StatusInternalServerError)
-		return
+	// Create the product list together with its products
+	list := store.List{
+		WorkspaceID: workspace.ID,
+		UserID:      userID,
+		Title:       title,
+		Budget:      budget,
+	}
+	if err := stores.Lists.CreateList(ctx, &list, items); err != nil {
+		return ProductList{}, err
 	}
 
 	// Retrieve all products in the list
-	var products []ListProduct
-	rows, err := db.DB.Query(
-		"SELECT product_id, quantity, checked FROM list_products WHERE list_id = ? AND deleted_at IS NULL",
-		listID,
-	)
+	listItems, err := stores.Lists.ListItems(ctx, list.ID)
 	if err != nil {
-		http.Error(w, "Error retrieving list products", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var item ListProduct
-		if err := rows.Scan(&item.ProductID, &item.Quantity, &item.Checked); err != nil {
-			http.Error(w, "Error scanning list product", http.StatusInternalServerError)
-			return
-		}
-		products = append(products, item)
+		return ProductList{}, err
 	}
 
 	// Create the response object
-	productList := ProductList{
-		ID:          int(listID),
-		WorkspaceID: workspaceID,
-		UserID:      loggedInUserID,
-		Title:       req.Title,
-		Products:    products,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-		DeletedAt:   nil,
+	productList := newProductList(list)
+	for _, item := range listItems {
+		productList.Products = append(productList.Products, newListProduct(item))
 	}
-
-	// Return the created product list
-	response := struct {
-		Status string      `json:"status"`
-		Data   ProductList `json:"data"`
-	}{
-		Status: "Success",
-		Data:   productList,
+	if err := productList.setCost(listItems, workspace.Currency); err != nil {
+		return ProductList{}, err
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+	publishAs(ctx, userID, events.Event{Type: events.ListCreated, WorkspaceID: workspace.ID, ListID: list.ID, Data: productList})
+	return productList, nil
+}
+
+// availableProducts leaves out the products that were deleted or moved out of the workspace since
+// they were put in a list or template, so that copying them does not fail
+func availableProducts(ctx context.Context, workspaceID int, products []ListProductRequest) ([]ListProductRequest, error) {
+	available := make([]ListProductRequest, 0, len(products))
+	for _, product := range products {
+		storedProduct, err := stores.Products.GetProduct(ctx, product.ProductID)
+		if errors.Is(err, store.ErrNotFound) || (err == nil && storedProduct.WorkspaceID != workspaceID) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		available = append(available, product)
+	}
+	return available, nil
 }
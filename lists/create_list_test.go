@@ -0,0 +1,88 @@
+package lists
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"shopping_list/store"
+)
+
+const listsRoute = "/workspaces/{workspace_id}/product-lists"
+
+func TestCreateProductList(t *testing.T) {
+	s, workspace := setup(t)
+	milk := createProduct(t, s, workspace, "milk", 120)
+	bread := createProduct(t, s, workspace, "bread", 250)
+
+	other := &store.Workspace{Name: "office", UserID: 2}
+	if err := s.Workspaces.CreateWorkspace(context.Background(), other); err != nil {
+		t.Fatalf("CreateWorkspace: %s", err)
+	}
+	coffee := createProduct(t, s, other, "coffee", 800)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		// total and items are only checked for created lists
+		total string
+		items map[int]int
+	}{
+		{
+			name:   "products and budget",
+			body:   fmt.Sprintf(`{"title":"groceries","budget":"10","products":[{"product_id":%d,"quantity":2},{"product_id":%d}]}`, milk.ID, bread.ID),
+			status: http.StatusCreated,
+			total:  "4.90",
+			items:  map[int]int{milk.ID: 2, bread.ID: 1},
+		},
+		{
+			name:   "no products",
+			body:   `{"title":"empty"}`,
+			status: http.StatusCreated,
+			total:  "0.00",
+			items:  map[int]int{},
+		},
+		{name: "missing title", body: `{"products":[]}`, status: http.StatusBadRequest},
+		{name: "invalid body", body: `{"title":`, status: http.StatusBadRequest},
+		{name: "unknown product", body: `{"title":"groceries","products":[{"product_id":999}]}`, status: http.StatusBadRequest},
+		{name: "product of another workspace", body: fmt.Sprintf(`{"title":"groceries","products":[{"product_id":%d}]}`, coffee.ID), status: http.StatusBadRequest},
+		{name: "negative budget", body: `{"title":"groceries","budget":"-1"}`, status: http.StatusBadRequest},
+		{name: "budget with too many decimals", body: `{"title":"groceries","budget":"1.234"}`, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := fmt.Sprintf("/workspaces/%d/product-lists", workspace.ID)
+			w := serve(t, listsRoute, CreateProductList, http.MethodPost, path, "", tt.body, store.RoleEditor)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusCreated {
+				return
+			}
+
+			var response struct {
+				Data struct {
+					ID             int    `json:"id"`
+					Version        int    `json:"version"`
+					EstimatedTotal string `json:"estimated_total"`
+				} `json:"data"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("decoding response: %s", err)
+			}
+			if response.Data.EstimatedTotal != tt.total {
+				t.Errorf("estimated total = %s, want %s", response.Data.EstimatedTotal, tt.total)
+			}
+			if want := fmt.Sprintf(`"%d"`, response.Data.Version); w.Header().Get("ETag") != want {
+				t.Errorf("ETag = %s, want %s", w.Header().Get("ETag"), want)
+			}
+			if got := listItems(t, s, response.Data.ID); !reflect.DeepEqual(got, tt.items) {
+				t.Errorf("items = %v, want %v", got, tt.items)
+			}
+		})
+	}
+}
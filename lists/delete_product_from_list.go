@@ -2,15 +2,19 @@ package lists
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"shopping_list/db"
 	"strconv"
-	"time"
+
+	"shopping_list/etag"
+	"shopping_list/events"
+	"shopping_list/store"
 
 	"github.com/gorilla/mux"
 )
 
-// DeleteProductFromList handles the soft deletion of a product from a list
+// DeleteProductFromList handles the soft deletion of a product from a list. Like UpdateProductList
+// it requires the If-Match header of the list, If-Match: * removes the product from any version.
 func DeleteProductFromList(w http.ResponseWriter, r *http.Request) {
 	// Get workspace ID, list ID, and product ID from URL parameters
 	vars := mux.Vars(r)
@@ -31,29 +35,35 @@ func DeleteProductFromList(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid product ID", http.StatusBadRequest)
 		return
 	}
+	version, ok := etag.IfMatch(w, r)
+	if !ok {
+		return
+	}
 
 	// Check if the list exists and belongs to the specified workspace
-	var listExists bool
-	err = db.DB.QueryRow(
-		"SELECT EXISTS(SELECT 1 FROM lists WHERE id = ? AND workspace_id = ? AND deleted_at IS NULL)",
-		listID, workspaceID,
-	).Scan(&listExists)
-
-	if err != nil || !listExists {
+	list, err := stores.Lists.GetList(r.Context(), listID)
+	if err != nil || list.WorkspaceID != workspaceID {
 		http.Error(w, "List not found in this workspace", http.StatusNotFound)
 		return
 	}
 
 	// Soft delete the product from the list by setting the deleted_at timestamp
-	_, err = db.DB.Exec(
-		"UPDATE list_products SET deleted_at = ? WHERE list_id = ? AND product_id = ? AND deleted_at IS NULL",
-		time.Now(), listID, productID,
-	)
+	version, err = stores.Lists.RemoveListItem(r.Context(), listID, productID, version)
+	if errors.Is(err, store.ErrVersionConflict) {
+		writeCurrentList(w, r, workspaceID, listID, http.StatusPreconditionFailed)
+		return
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "List not found in this workspace", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error deleting product from list: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	publish(r, events.Event{Type: events.ItemDeleted, WorkspaceID: workspaceID, ListID: listID, ProductID: productID})
+
 	// Return success response
 	response := struct {
 		Status string `json:"status"`
@@ -61,6 +71,7 @@ func DeleteProductFromList(w http.ResponseWriter, r *http.Request) {
 		Status: "Success",
 	}
 
+	etag.Set(w, version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
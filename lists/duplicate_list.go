@@ -0,0 +1,107 @@
+package lists
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"shopping_list/etag"
+	"shopping_list/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+// DuplicateProductListRequest represents the optional request body for duplicating a list
+type DuplicateProductListRequest struct {
+	// Title replaces the title of the copied list when it is set
+	Title string `json:"title"`
+}
+
+// DuplicateProductList creates a new list of the user with the title, budget and products of a list.
+// The products keep their quantities but are not checked, and products deleted since they were put
+// in the list are left out.
+func DuplicateProductList(w http.ResponseWriter, r *http.Request) {
+	// Get workspace ID and list ID from URL parameters
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	listID, err := strconv.Atoi(vars["list_id"])
+	if err != nil {
+		http.Error(w, "Invalid list ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// The body is optional
+	var req DuplicateProductListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the list exists and belongs to the specified workspace
+	storedList, err := stores.Lists.GetList(r.Context(), listID)
+	if err != nil || storedList.WorkspaceID != workspaceID {
+		http.Error(w, "List not found in this workspace", http.StatusNotFound)
+		return
+	}
+	title := storedList.Title
+	if req.Title != "" {
+		title = req.Title
+	}
+
+	items, err := stores.Lists.ListItems(r.Context(), listID)
+	if err != nil {
+		http.Error(w, "Error retrieving list products", http.StatusInternalServerError)
+		return
+	}
+	products := make([]ListProductRequest, 0, len(items))
+	for _, item := range items {
+		products = append(products, ListProductRequest{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+	if products, err = availableProducts(r.Context(), workspaceID, products); err != nil {
+		http.Error(w, "Error retrieving list products", http.StatusInternalServerError)
+		return
+	}
+
+	workspace, err := stores.Workspaces.GetWorkspace(r.Context(), workspaceID)
+	if err != nil {
+		http.Error(w, "Error fetching workspace", http.StatusInternalServerError)
+		return
+	}
+
+	productList, err := createList(r.Context(), workspace, userID, title, products, storedList.Budget)
+	var invalid invalidListError
+	if errors.As(err, &invalid) {
+		http.Error(w, invalid.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error creating product list: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string      `json:"status"`
+		Data   ProductList `json:"data"`
+	}{
+		Status: "Success",
+		Data:   productList,
+	}
+
+	etag.Set(w, productList.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
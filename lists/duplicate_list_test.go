@@ -0,0 +1,96 @@
+package lists
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"shopping_list/store"
+)
+
+const duplicateRoute = "/workspaces/{workspace_id}/product-lists/{list_id}/duplicate"
+
+func TestDuplicateProductList(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		title string
+	}{
+		{"keeps the title", "", "groceries"},
+		{"empty body", "{}", "groceries"},
+		{"new title", `{"title":"next week"}`, "next week"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, workspace := setup(t)
+			milk := createProduct(t, s, workspace, "milk", 120)
+			bread := createProduct(t, s, workspace, "bread", 250)
+			eggs := createProduct(t, s, workspace, "eggs", 300)
+			list := createStoredList(t, s, workspace,
+				store.ItemQuantity{ProductID: milk.ID, Quantity: 2},
+				store.ItemQuantity{ProductID: bread.ID, Quantity: 1},
+				store.ItemQuantity{ProductID: eggs.ID, Quantity: 6},
+			)
+			checkStoredItem(t, s, workspace, list.ID, milk.ID, 2, 99)
+			if err := s.Products.DeleteProduct(ctx, eggs.ID, 0); err != nil {
+				t.Fatalf("DeleteProduct: %s", err)
+			}
+
+			path := fmt.Sprintf("/workspaces/%d/product-lists/%d/duplicate", workspace.ID, list.ID)
+			w := serve(t, duplicateRoute, DuplicateProductList, http.MethodPost, path, "", tt.body, store.RoleEditor)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+			}
+
+			var response struct {
+				Data struct {
+					ID    int    `json:"id"`
+					Title string `json:"title"`
+				} `json:"data"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("decoding response: %s", err)
+			}
+			if response.Data.ID == list.ID {
+				t.Fatal("the list was not copied")
+			}
+			if response.Data.Title != tt.title {
+				t.Errorf("title = %q, want %q", response.Data.Title, tt.title)
+			}
+
+			// The copy is unchecked and leaves out the deleted product, the original is unchanged
+			if got, want := listItems(t, s, response.Data.ID), map[int]int{milk.ID: 2, bread.ID: 1}; !reflect.DeepEqual(got, want) {
+				t.Errorf("copied items = %v, want %v", got, want)
+			}
+			if got, want := listItems(t, s, list.ID), map[int]int{milk.ID: -2, bread.ID: 1, eggs.ID: 6}; !reflect.DeepEqual(got, want) {
+				t.Errorf("original items = %v, want %v", got, want)
+			}
+			if !pricePaid(t, s, milk.ID, 99) {
+				t.Error("the price paid for the original list is gone")
+			}
+		})
+	}
+}
+
+func TestDuplicateProductListNotFound(t *testing.T) {
+	s, workspace := setup(t)
+	other := &store.Workspace{Name: "office", UserID: 1}
+	if err := s.Workspaces.CreateWorkspace(context.Background(), other); err != nil {
+		t.Fatalf("CreateWorkspace: %s", err)
+	}
+	list := createStoredList(t, s, other)
+
+	for name, listID := range map[string]int{"unknown list": list.ID + 1, "list of another workspace": list.ID} {
+		t.Run(name, func(t *testing.T) {
+			path := fmt.Sprintf("/workspaces/%d/product-lists/%d/duplicate", workspace.ID, listID)
+			w := serve(t, duplicateRoute, DuplicateProductList, http.MethodPost, path, "", "", store.RoleEditor)
+			if w.Code != http.StatusNotFound {
+				t.Errorf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package lists
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"shopping_list/events"
+	"shopping_list/middleware"
+)
+
+var broker events.Broker
+
+// SetBroker injects the broker notified of the changes to lists
+func SetBroker(b events.Broker) {
+	broker = b
+}
+
+// publish notifies the subscribers of the workspace of a change made by the request
+func publish(r *http.Request, event events.Event) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	publishAs(r.Context(), userID, event)
+}
+
+// publishAs notifies the subscribers of the workspace of a change made by the user
+func publishAs(ctx context.Context, userID int, event events.Event) {
+	event.UserID = userID
+	if err := broker.Publish(ctx, event); err != nil {
+		log.Printf("error publishing %s event: %s", event.Type, err)
+	}
+}
+
+// publishList notifies the subscribers of a change to a list, sending the whole list as a
+// change may touch any of its products
+func publishList(r *http.Request, workspaceID, listID int) {
+	storedList, err := stores.Lists.GetList(r.Context(), listID)
+	if err != nil {
+		log.Printf("error publishing %s event: %s", events.ListUpdated, err)
+		return
+	}
+	list, err := loadProductList(r.Context(), storedList)
+	if err != nil {
+		log.Printf("error publishing %s event: %s", events.ListUpdated, err)
+		return
+	}
+	publish(r, events.Event{Type: events.ListUpdated, WorkspaceID: workspaceID, ListID: listID, Data: list})
+}
@@ -0,0 +1,91 @@
+package lists
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"shopping_list/etag"
+	"shopping_list/store"
+
+	"github.com/gorilla/mux"
+)
+
+// GetProductList returns a list with its products and its version in the ETag header, to be
+// sent back in If-Match when updating the list or its status
+func GetProductList(w http.ResponseWriter, r *http.Request) {
+	// Get workspace ID and list ID from URL parameters
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	listID, err := strconv.Atoi(vars["list_id"])
+	if err != nil {
+		http.Error(w, "Invalid list ID", http.StatusBadRequest)
+		return
+	}
+
+	writeCurrentList(w, r, workspaceID, listID, http.StatusOK)
+}
+
+// writeCurrentList writes the current representation of a list with its ETag. With 412 it
+// answers a change made against an outdated version, so the client can merge and retry.
+func writeCurrentList(w http.ResponseWriter, r *http.Request, workspaceID, listID, status int) {
+	storedList, err := stores.Lists.GetList(r.Context(), listID)
+	if err != nil || storedList.WorkspaceID != workspaceID {
+		http.Error(w, "List not found in this workspace", http.StatusNotFound)
+		return
+	}
+
+	list, err := loadProductList(r.Context(), storedList)
+	if err != nil {
+		http.Error(w, "Error retrieving list products", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string                  `json:"status"`
+		Data   ProductListWithProducts `json:"data"`
+	}{
+		Status: "Success",
+		Data:   list,
+	}
+	if status == http.StatusPreconditionFailed {
+		response.Status = "The list was changed by someone else"
+	}
+
+	etag.Set(w, storedList.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// loadProductList returns a stored list with its products and estimated cost
+func loadProductList(ctx context.Context, storedList *store.List) (ProductListWithProducts, error) {
+	items, err := stores.Lists.ListItems(ctx, storedList.ID)
+	if err != nil {
+		return ProductListWithProducts{ProductList: newProductList(*storedList)}, err
+	}
+	return withProducts(ctx, storedList, items)
+}
+
+// withProducts returns a stored list with the items as its products and their estimated cost
+func withProducts(ctx context.Context, storedList *store.List, items []store.ListItem) (ProductListWithProducts, error) {
+	list := ProductListWithProducts{ProductList: newProductList(*storedList)}
+
+	workspace, err := stores.Workspaces.GetWorkspace(ctx, storedList.WorkspaceID)
+	if err != nil {
+		return list, err
+	}
+	if err := list.setCost(items, workspace.Currency); err != nil {
+		return list, err
+	}
+	for _, item := range items {
+		list.Products = append(list.Products, Product{ListProduct: newListProduct(item), Name: item.ProductTitle, Category: item.Category})
+	}
+	return list, nil
+}
@@ -1,27 +1,31 @@
 package lists
 
 import (
-	"database/sql"
 	"encoding/json"
-	"fmt"
 	"net/http"
-	"shopping_list/db"
 	"strconv"
 
+	"shopping_list/store"
+
 	"github.com/gorilla/mux"
 )
 
 type Product struct {
 	ListProduct
-	Name string `json:"name"`
+	Name     string          `json:"name"`
+	Category *store.Category `json:"category,omitempty"`
 }
 
 type ProductListWithProducts struct {
 	ProductList
 	Products []Product
+	// Groups is only set when grouping by category
+	Groups []CategoryGroup `json:"groups,omitempty"`
 }
 
-// ListProductLists handles listing all product lists in a workspace, including their products and product names
+// ListProductLists handles listing all product lists in a workspace, including their products and product names.
+// With ?store_id= the products are sorted in the walking order of that store, and with ?group_by=category
+// they are ordered by category and also returned grouped by category.
 func ListProductLists(w http.ResponseWriter, r *http.Request) {
 	// Get workspace ID from URL parameters
 	vars := mux.Vars(r)
@@ -31,90 +35,65 @@ func ListProductLists(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Retrieve all product lists and their products in the workspace
-	rows, err := db.DB.Query(`
-		SELECT l.id, l.user_id, l.title, l.created_at, l.updated_at, l.deleted_at,
-		       lp.product_id, lp.quantity, lp.checked, lp.created_at, lp.updated_at, lp.deleted_at,
-		       p.title
-		FROM lists l
-		LEFT JOIN list_products lp ON l.id = lp.list_id AND lp.deleted_at IS NULL
-		LEFT JOIN products p ON lp.product_id = p.id AND p.deleted_at IS NULL
-		WHERE l.workspace_id = ? AND l.deleted_at IS NULL
-		ORDER BY l.id DESC
-	`, workspaceID)
-	if err != nil {
-		http.Error(w, "Error fetching product lists", http.StatusInternalServerError)
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy != "" && groupBy != "category" {
+		http.Error(w, "Invalid group_by, expected category", http.StatusBadRequest)
 		return
 	}
-	defer rows.Close()
-
-	// Map to hold lists and their products
-	listMap := make(map[int]*ProductListWithProducts)
-
-	for rows.Next() {
-		var listID int
-		var list ProductListWithProducts
-		var product Product
-		var productName string
-
-		// Scan list and product details
-		// Use nullable types for product fields to handle NULL values
-		var productID, quantity sql.NullInt64
-		var checked sql.NullBool
-		var productCreatedAt, productUpdatedAt, productDeletedAt sql.NullTime
-		var nullableProductName sql.NullString
 
-		err := rows.Scan(
-			&listID, &list.UserID, &list.Title, &list.CreatedAt, &list.UpdatedAt, &list.DeletedAt,
-			&productID, &quantity, &checked, &productCreatedAt, &productUpdatedAt, &productDeletedAt,
-			&nullableProductName,
-		)
-
-		// Convert nullable types to regular types if valid
-		if productID.Valid {
-			product.ProductID = int(productID.Int64)
-			product.Quantity = int(quantity.Int64)
-			product.Checked = checked.Bool
-			product.CreatedAt = productCreatedAt.Time
-			product.UpdatedAt = productUpdatedAt.Time
-			if productDeletedAt.Valid {
-				product.DeletedAt = &productDeletedAt.Time
-			}
-			if nullableProductName.Valid {
-				productName = nullableProductName.String
-			}
+	// Without a store the categories are walked in their workspace order
+	var order walkingOrder
+	sortProducts := groupBy == "category"
+	if storeIDParam := r.URL.Query().Get("store_id"); storeIDParam != "" {
+		shopID, err := strconv.Atoi(storeIDParam)
+		if err != nil {
+			http.Error(w, "Invalid store ID", http.StatusBadRequest)
+			return
 		}
+		shop, err := stores.Shops.GetShop(r.Context(), shopID)
+		if err != nil || shop.WorkspaceID != workspaceID {
+			http.Error(w, "Store not found", http.StatusNotFound)
+			return
+		}
+		aisles, err := stores.Shops.GetShopLayout(r.Context(), shop.ID)
 		if err != nil {
-			fmt.Println(err)
-			http.Error(w, "Error scanning product list", http.StatusInternalServerError)
+			http.Error(w, "Error fetching store layout", http.StatusInternalServerError)
 			return
 		}
+		order = newWalkingOrder(aisles)
+		sortProducts = true
+	}
 
-		// Check if the list is already in the map
-		if existingList, exists := listMap[listID]; exists {
-			// Add product to existing list
-			if product.ProductID != 0 { // Ensure product exists
-				product.Name = productName
-				product.ListID = listID
-				existingList.Products = append(existingList.Products, product)
-			}
-		} else {
+	workspace, err := stores.Workspaces.GetWorkspace(r.Context(), workspaceID)
+	if err != nil {
+		http.Error(w, "Error fetching workspace", http.StatusInternalServerError)
+		return
+	}
 
-			// Create a new list entry
-			list.ID = listID
-			if product.ProductID != 0 { // Ensure product exists
-				product.Name = productName
-				product.ListID = listID
-				list.Products = append(list.Products, product)
-			}
-			listMap[listID] = &list
-		}
+	// Retrieve all product lists and their products in the workspace
+	storedLists, err := stores.Lists.ListListsWithItems(r.Context(), workspaceID)
+	if err != nil {
+		http.Error(w, "Error fetching product lists", http.StatusInternalServerError)
+		return
 	}
 
-	// Convert map to slice
 	var productLists []ProductListWithProducts
-	for _, list := range listMap {
-		productLists = append(productLists, *list)
+	for _, storedList := range storedLists {
+		list := ProductListWithProducts{ProductList: newProductList(storedList.List)}
+		if err := list.setCost(storedList.Items, workspace.Currency); err != nil {
+			http.Error(w, "Error estimating list total", http.StatusInternalServerError)
+			return
+		}
+		for _, item := range storedList.Items {
+			list.Products = append(list.Products, Product{ListProduct: newListProduct(item), Name: item.ProductTitle, Category: item.Category})
+		}
+		if sortProducts {
+			order.sort(list.Products)
+		}
+		if groupBy == "category" {
+			list.Groups = order.group(list.Products)
+		}
+		productLists = append(productLists, list)
 	}
 
 	// Return the list of product lists
@@ -0,0 +1,10 @@
+package lists
+
+import "shopping_list/store"
+
+var stores *store.Stores
+
+// SetStores injects the storage backends used by the list handlers
+func SetStores(s *store.Stores) {
+	stores = s
+}
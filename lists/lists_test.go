@@ -0,0 +1,112 @@
+package lists
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"shopping_list/events"
+	"shopping_list/middleware"
+	"shopping_list/money"
+	"shopping_list/store"
+
+	"github.com/gorilla/mux"
+)
+
+// setup injects a memory store and broker and returns the store with a workspace
+func setup(t *testing.T) (*store.Stores, *store.Workspace) {
+	t.Helper()
+	s := store.NewMemory()
+	SetStores(s)
+	SetBroker(events.NewMemoryBroker())
+
+	workspace := &store.Workspace{Name: "home", UserID: 1}
+	if err := s.Workspaces.CreateWorkspace(context.Background(), workspace); err != nil {
+		t.Fatalf("CreateWorkspace: %s", err)
+	}
+	return s, workspace
+}
+
+// createProduct stores a product of the workspace with a price in minor units
+func createProduct(t *testing.T, s *store.Stores, workspace *store.Workspace, title string, price int64) *store.Product {
+	t.Helper()
+	product := &store.Product{WorkspaceID: workspace.ID, Title: title, Price: money.New(price, workspace.Currency)}
+	if err := s.Products.CreateProduct(context.Background(), product, store.PriceSource{}); err != nil {
+		t.Fatalf("CreateProduct: %s", err)
+	}
+	return product
+}
+
+// serve routes a request to a handler as the user with the role in the workspace, the If-Match
+// header is only sent when ifMatch is not empty
+func serve(t *testing.T, route string, handler http.HandlerFunc, method, path, ifMatch, body string, role store.Role) *httptest.ResponseRecorder {
+	t.Helper()
+	router := mux.NewRouter()
+	router.HandleFunc(route, handler).Methods(method)
+
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if ifMatch != "" {
+		r.Header.Set("If-Match", ifMatch)
+	}
+	ctx := middleware.WithPrincipal(r.Context(), &middleware.Principal{UserID: 1})
+	r = r.WithContext(middleware.WithWorkspaceRole(ctx, role))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+// createStoredList stores a list of the workspace with the products and their quantities
+func createStoredList(t *testing.T, s *store.Stores, workspace *store.Workspace, items ...store.ItemQuantity) *store.List {
+	t.Helper()
+	list := &store.List{WorkspaceID: workspace.ID, UserID: 1, Title: "groceries"}
+	if err := s.Lists.CreateList(context.Background(), list, items); err != nil {
+		t.Fatalf("CreateList: %s", err)
+	}
+	return list
+}
+
+// listItems returns the quantity of each product of a list, negated for checked products
+func listItems(t *testing.T, s *store.Stores, listID int) map[int]int {
+	t.Helper()
+	items, err := s.Lists.ListItems(context.Background(), listID)
+	if err != nil {
+		t.Fatalf("ListItems: %s", err)
+	}
+	quantities := make(map[int]int, len(items))
+	for _, item := range items {
+		quantities[item.ProductID] = item.Quantity
+		if item.Checked {
+			quantities[item.ProductID] = -item.Quantity
+		}
+	}
+	return quantities
+}
+
+// checkStoredItem checks a product of a list bought at a unit price in minor units, recording
+// a price point
+func checkStoredItem(t *testing.T, s *store.Stores, workspace *store.Workspace, listID, productID, quantity int, unitPrice int64) {
+	t.Helper()
+	price := money.New(unitPrice, workspace.Currency)
+	check := &store.ItemCheck{Checked: true, Purchase: &store.PurchaseDetails{UnitPrice: &price}}
+	if _, err := s.Lists.PutListItem(context.Background(), listID, store.ItemQuantity{ProductID: productID, Quantity: quantity, Check: check}); err != nil {
+		t.Fatalf("PutListItem: %s", err)
+	}
+}
+
+// pricePaid reports whether the price history of a product has a point at a price in minor units
+func pricePaid(t *testing.T, s *store.Stores, productID int, price int64) bool {
+	t.Helper()
+	points, err := s.Products.ListPriceHistory(context.Background(), store.PriceHistoryFilter{ProductID: productID})
+	if err != nil {
+		t.Fatalf("ListPriceHistory: %s", err)
+	}
+	for _, point := range points {
+		if point.Price.Amount == price {
+			return true
+		}
+	}
+	return false
+}
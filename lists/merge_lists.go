@@ -0,0 +1,131 @@
+package lists
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"shopping_list/etag"
+	"shopping_list/events"
+	"shopping_list/middleware"
+	"shopping_list/store"
+
+	"github.com/gorilla/mux"
+)
+
+// MergeProductListsRequest represents the request body for merging a list into another
+type MergeProductListsRequest struct {
+	// SourceListID is the list whose products are moved, it is deleted by the merge
+	SourceListID int `json:"source_list_id"`
+	// DryRun returns the merged list without merging
+	DryRun bool `json:"dry_run"`
+}
+
+// MergeProductLists merges another list of the workspace into the list of the URL and deletes it.
+// Products in both lists get their quantities added up and stay checked only if they were checked
+// in both; an undone purchase keeps its price in the price history. The merge requires the If-Match header of the list of the URL and the permission to
+// delete lists; a dry run only previews the merged list, checking If-Match when it is sent.
+func MergeProductLists(w http.ResponseWriter, r *http.Request) {
+	// Get workspace ID and list ID from URL parameters
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	listID, err := strconv.Atoi(vars["list_id"])
+	if err != nil {
+		http.Error(w, "Invalid list ID", http.StatusBadRequest)
+		return
+	}
+
+	// Parse request body
+	var req MergeProductListsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.SourceListID <= 0 {
+		http.Error(w, "source_list_id is required", http.StatusBadRequest)
+		return
+	}
+	if req.SourceListID == listID {
+		http.Error(w, "A list cannot be merged into itself", http.StatusBadRequest)
+		return
+	}
+
+	version := 0
+	if !req.DryRun || r.Header.Get("If-Match") != "" {
+		var ok bool
+		if version, ok = etag.IfMatch(w, r); !ok {
+			return
+		}
+	}
+
+	// Check if both lists exist and belong to the specified workspace
+	if storedList, err := stores.Lists.GetList(r.Context(), listID); err != nil || storedList.WorkspaceID != workspaceID {
+		http.Error(w, "List not found in this workspace", http.StatusNotFound)
+		return
+	}
+	if source, err := stores.Lists.GetList(r.Context(), req.SourceListID); err != nil || source.WorkspaceID != workspaceID {
+		http.Error(w, "Source list not found in this workspace", http.StatusNotFound)
+		return
+	}
+
+	// The merge deletes the source list
+	if !req.DryRun && !middleware.Can(r.Context(), middleware.PermDeleteLists) {
+		http.Error(w, "You don't have permission to delete the merged list", http.StatusForbidden)
+		return
+	}
+
+	merge := store.ListMerge{TargetID: listID, SourceID: req.SourceListID, Version: version, DryRun: req.DryRun}
+	merged, items, err := stores.Lists.MergeLists(r.Context(), merge)
+	if errors.Is(err, store.ErrInactiveList) {
+		http.Error(w, "Lists can only be merged into an active list", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, store.ErrVersionConflict) {
+		writeCurrentList(w, r, workspaceID, listID, http.StatusPreconditionFailed)
+		return
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "List not found in this workspace", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error merging lists: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	list, err := withProducts(r.Context(), merged, items)
+	if err != nil {
+		http.Error(w, "Error retrieving list products", http.StatusInternalServerError)
+		return
+	}
+
+	if !req.DryRun {
+		publish(r, events.Event{Type: events.ListDeleted, WorkspaceID: workspaceID, ListID: req.SourceListID})
+		publish(r, events.Event{Type: events.ListUpdated, WorkspaceID: workspaceID, ListID: listID, Data: list})
+	}
+
+	// Return the merged list
+	response := struct {
+		Status string                  `json:"status"`
+		Data   ProductListWithProducts `json:"data"`
+		DryRun bool                    `json:"dry_run"`
+	}{
+		Status: "Success",
+		Data:   list,
+		DryRun: req.DryRun,
+	}
+
+	// The version of a preview is not stored yet
+	if !req.DryRun {
+		etag.Set(w, merged.Version)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
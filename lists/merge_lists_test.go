@@ -0,0 +1,212 @@
+package lists
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"shopping_list/store"
+)
+
+const mergeRoute = "/workspaces/{workspace_id}/product-lists/{list_id}/merge"
+
+// mergeFixture stores a target and a source list sharing products in every checked state
+type mergeFixture struct {
+	s                                 *store.Stores
+	workspace                         *store.Workspace
+	target, source                    *store.List
+	milk, eggs, cheese, bread, butter *store.Product
+}
+
+func newMergeFixture(t *testing.T) *mergeFixture {
+	t.Helper()
+	f := &mergeFixture{}
+	f.s, f.workspace = setup(t)
+	f.milk = createProduct(t, f.s, f.workspace, "milk", 120)
+	f.eggs = createProduct(t, f.s, f.workspace, "eggs", 300)
+	f.cheese = createProduct(t, f.s, f.workspace, "cheese", 450)
+	f.bread = createProduct(t, f.s, f.workspace, "bread", 250)
+	f.butter = createProduct(t, f.s, f.workspace, "butter", 200)
+
+	f.target = createStoredList(t, f.s, f.workspace,
+		store.ItemQuantity{ProductID: f.eggs.ID, Quantity: 4},
+		store.ItemQuantity{ProductID: f.bread.ID, Quantity: 1},
+	)
+	checkStoredItem(t, f.s, f.workspace, f.target.ID, f.milk.ID, 2, 99)
+	checkStoredItem(t, f.s, f.workspace, f.target.ID, f.cheese.ID, 1, 400)
+
+	f.source = createStoredList(t, f.s, f.workspace, store.ItemQuantity{ProductID: f.milk.ID, Quantity: 3})
+	checkStoredItem(t, f.s, f.workspace, f.source.ID, f.eggs.ID, 6, 280)
+	checkStoredItem(t, f.s, f.workspace, f.source.ID, f.cheese.ID, 1, 420)
+	checkStoredItem(t, f.s, f.workspace, f.source.ID, f.butter.ID, 1, 190)
+
+	// Checking the items changed the version of the target list
+	var err error
+	if f.target, err = f.s.Lists.GetList(context.Background(), f.target.ID); err != nil {
+		t.Fatalf("GetList: %s", err)
+	}
+	return f
+}
+
+func (f *mergeFixture) merge(t *testing.T, ifMatch, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	path := fmt.Sprintf("/workspaces/%d/product-lists/%d/merge", f.workspace.ID, f.target.ID)
+	return serve(t, mergeRoute, MergeProductLists, http.MethodPost, path, ifMatch, body, store.RoleEditor)
+}
+
+// mergedItems are the items of the target list once the source list is merged into it
+func (f *mergeFixture) mergedItems() map[int]int {
+	return map[int]int{
+		// Checked in one list only, the quantities are to be bought again
+		f.milk.ID: 5,
+		f.eggs.ID: 10,
+		// Checked in both lists
+		f.cheese.ID: -2,
+		// In one list only
+		f.bread.ID:  1,
+		f.butter.ID: -1,
+	}
+}
+
+func TestMergeProductLists(t *testing.T) {
+	ctx := context.Background()
+	f := newMergeFixture(t)
+	body := fmt.Sprintf(`{"source_list_id":%d}`, f.source.ID)
+	w := f.merge(t, fmt.Sprintf(`"%d"`, f.target.Version), body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	if got, want := listItems(t, f.s, f.target.ID), f.mergedItems(); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged items = %v, want %v", got, want)
+	}
+	stored, err := f.s.Lists.GetList(ctx, f.target.ID)
+	if err != nil {
+		t.Fatalf("GetList: %s", err)
+	}
+	if want := fmt.Sprintf(`"%d"`, stored.Version); w.Header().Get("ETag") != want {
+		t.Errorf("ETag = %s, want %s", w.Header().Get("ETag"), want)
+	}
+
+	// The source list is soft deleted
+	if source, err := f.s.Lists.GetList(ctx, f.source.ID); err == nil {
+		t.Errorf("GetList = %+v, want the source list deleted", source)
+	}
+
+	// Undone purchases were paid all the same
+	for product, price := range map[*store.Product]int64{f.milk: 99, f.eggs: 280, f.cheese: 400} {
+		if !pricePaid(t, f.s, product.ID, price) {
+			t.Errorf("the price point of %s at %d was deleted", product.Title, price)
+		}
+	}
+}
+
+func TestMergeProductListsDryRun(t *testing.T) {
+	ctx := context.Background()
+	f := newMergeFixture(t)
+	targetItems := listItems(t, f.s, f.target.ID)
+	sourceItems := listItems(t, f.s, f.source.ID)
+
+	body := fmt.Sprintf(`{"source_list_id":%d,"dry_run":true}`, f.source.ID)
+	w := f.merge(t, "", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if w.Header().Get("ETag") != "" {
+		t.Errorf("ETag = %s, want none for a preview", w.Header().Get("ETag"))
+	}
+
+	var response struct {
+		Data struct {
+			Products []struct {
+				ProductID int  `json:"product_id"`
+				Quantity  int  `json:"quantity"`
+				Checked   bool `json:"checked"`
+			}
+		} `json:"data"`
+		DryRun bool `json:"dry_run"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %s", err)
+	}
+	if !response.DryRun {
+		t.Error("the response is not marked as a dry run")
+	}
+	preview := make(map[int]int, len(response.Data.Products))
+	for _, product := range response.Data.Products {
+		preview[product.ProductID] = product.Quantity
+		if product.Checked {
+			preview[product.ProductID] = -product.Quantity
+		}
+	}
+	if want := f.mergedItems(); !reflect.DeepEqual(preview, want) {
+		t.Errorf("preview items = %v, want %v", preview, want)
+	}
+
+	// Both lists are unchanged
+	if got := listItems(t, f.s, f.target.ID); !reflect.DeepEqual(got, targetItems) {
+		t.Errorf("target items = %v, want %v", got, targetItems)
+	}
+	if got := listItems(t, f.s, f.source.ID); !reflect.DeepEqual(got, sourceItems) {
+		t.Errorf("source items = %v, want %v", got, sourceItems)
+	}
+	if stored, err := f.s.Lists.GetList(ctx, f.target.ID); err != nil || stored.Version != f.target.Version {
+		t.Errorf("GetList = %+v, %v, want version %d", stored, err, f.target.Version)
+	}
+	if _, err := f.s.Lists.GetList(ctx, f.source.ID); err != nil {
+		t.Errorf("GetList of the source list: %s", err)
+	}
+}
+
+func TestMergeProductListsRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		// prepare changes the fixture and returns the request body
+		prepare func(t *testing.T, f *mergeFixture) string
+		status  int
+	}{
+		{"missing If-Match", "", nil, http.StatusPreconditionRequired},
+		{"stale version", `"1"`, nil, http.StatusPreconditionFailed},
+		{"into itself", "*", func(t *testing.T, f *mergeFixture) string {
+			return fmt.Sprintf(`{"source_list_id":%d}`, f.target.ID)
+		}, http.StatusBadRequest},
+		{"missing source", "*", func(t *testing.T, f *mergeFixture) string {
+			return "{}"
+		}, http.StatusBadRequest},
+		{"soft deleted source", "*", func(t *testing.T, f *mergeFixture) string {
+			if _, err := f.s.Lists.SetListStatus(context.Background(), f.source.ID, ListStatusDeleted, 0); err != nil {
+				t.Fatalf("SetListStatus: %s", err)
+			}
+			return fmt.Sprintf(`{"source_list_id":%d}`, f.source.ID)
+		}, http.StatusNotFound},
+		{"into a completed list", "*", func(t *testing.T, f *mergeFixture) string {
+			if _, err := f.s.Lists.SetListStatus(context.Background(), f.target.ID, ListStatusCompleted, 0); err != nil {
+				t.Fatalf("SetListStatus: %s", err)
+			}
+			return fmt.Sprintf(`{"source_list_id":%d}`, f.source.ID)
+		}, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newMergeFixture(t)
+			body := fmt.Sprintf(`{"source_list_id":%d}`, f.source.ID)
+			if tt.prepare != nil {
+				body = tt.prepare(t, f)
+			}
+			targetItems := listItems(t, f.s, f.target.ID)
+
+			w := f.merge(t, tt.ifMatch, body)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if got := listItems(t, f.s, f.target.ID); !reflect.DeepEqual(got, targetItems) {
+				t.Errorf("target items = %v, want %v", got, targetItems)
+			}
+		})
+	}
+}
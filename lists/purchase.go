@@ -0,0 +1,80 @@
+package lists
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"shopping_list/middleware"
+	"shopping_list/money"
+	"shopping_list/store"
+)
+
+// PurchaseRequest holds what the shopper actually bought when checking an item, every field is optional
+type PurchaseRequest struct {
+	// UnitPrice is a decimal amount in the workspace currency, it is added to the price history of the product
+	UnitPrice money.Decimal `json:"unit_price"`
+	Quantity  *int          `json:"quantity"`
+	StoreID   *int          `json:"store_id"`
+}
+
+// CheckItem converts the checked state and purchase of an item, returning an error if they are
+// invalid. The user, if any, is recorded as the one who checked the item. It returns nil when the
+// request leaves the item checked or unchecked. The list handlers and sync both check items with it.
+func CheckItem(ctx context.Context, checked *bool, req *PurchaseRequest, workspace *store.Workspace, userID *int) (*store.ItemCheck, error) {
+	if checked == nil {
+		if req != nil {
+			return nil, errors.New("A purchase can only be recorded when checking an item")
+		}
+		return nil, nil
+	}
+
+	check := &store.ItemCheck{Checked: *checked}
+	if !*checked {
+		return check, nil
+	}
+	check.CheckedBy = userID
+	if req == nil {
+		return check, nil
+	}
+
+	var details store.PurchaseDetails
+	if req.UnitPrice != "" {
+		price, err := req.UnitPrice.Money(workspace.Currency)
+		if err != nil || price.Amount < 0 {
+			return nil, errors.New("Invalid unit price, expected a non-negative amount in " + workspace.Currency)
+		}
+		details.UnitPrice = &price
+	}
+	if req.Quantity != nil {
+		if *req.Quantity <= 0 {
+			return nil, errors.New("Invalid purchase quantity, expected a positive number")
+		}
+		details.Quantity = req.Quantity
+	}
+	if req.StoreID != nil {
+		shop, err := stores.Shops.GetShop(ctx, *req.StoreID)
+		if err != nil || shop.WorkspaceID != workspace.ID {
+			return nil, errors.New("Store not found in this workspace")
+		}
+		details.ShopID = req.StoreID
+	}
+	check.Purchase = &details
+	return check, nil
+}
+
+// parseItemCheck converts the checked state and purchase of a requested item, writing the error response
+// if they are invalid. It returns nil when the request leaves the item checked or unchecked.
+func parseItemCheck(w http.ResponseWriter, r *http.Request, checked *bool, req *PurchaseRequest, workspace *store.Workspace) (*store.ItemCheck, bool) {
+	var userID *int
+	if id, ok := middleware.UserIDFromContext(r.Context()); ok {
+		userID = &id
+	}
+
+	check, err := CheckItem(r.Context(), checked, req, workspace, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return check, true
+}
@@ -0,0 +1,85 @@
+package lists
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"shopping_list/middleware"
+	"shopping_list/store"
+)
+
+// RunTemplateScheduler creates the lists of the recurring templates that are due, checking every
+// interval until ctx is done. A run is claimed before its list is created, so that servers sharing
+// the database create it once; if creating the list then fails the run is logged and skipped. Runs
+// missed while no server was running are caught up with a single list.
+func RunTemplateScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		runDueTemplates(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDueTemplates creates the lists of the templates due at now
+func runDueTemplates(ctx context.Context, now time.Time) {
+	templates, err := stores.Templates.ListDueTemplates(ctx, now)
+	if err != nil {
+		log.Printf("error listing due list templates: %s", err)
+		return
+	}
+
+	for i := range templates {
+		if err := runTemplate(ctx, &templates[i], now); err != nil {
+			log.Printf("error creating the list of template %d: %s", templates[i].ID, err)
+		}
+	}
+}
+
+// runTemplate claims the due run of a template and creates its list on behalf of the template's author.
+// The run is skipped if the author can no longer edit the lists of the workspace.
+func runTemplate(ctx context.Context, template *store.ListTemplate, now time.Time) error {
+	if template.Recurrence == nil || template.NextRunAt == nil {
+		return nil
+	}
+
+	run := *template.NextRunAt
+	err := stores.Templates.AdvanceTemplateRun(ctx, template.ID, run, template.Recurrence.Next(run, now))
+	if errors.Is(err, store.ErrNotFound) {
+		// Claimed by another server, or changed since it was listed
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// The author may have left the workspace or lost the right to edit lists since saving the template
+	role, err := stores.Workspaces.GetWorkspaceRole(ctx, template.WorkspaceID, template.UserID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && !middleware.RoleCan(role, middleware.PermEditLists)) {
+		log.Printf("skipping the run of template %d, its author can no longer edit lists", template.ID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	workspace, err := stores.Workspaces.GetWorkspace(ctx, template.WorkspaceID)
+	if err != nil {
+		return err
+	}
+	// Products deleted since the template was saved are left out of its lists
+	products, err := availableProducts(ctx, workspace.ID, templateProducts(template))
+	if err != nil {
+		return err
+	}
+	_, err = createList(ctx, workspace, template.UserID, template.Title, products, nil)
+	return err
+}
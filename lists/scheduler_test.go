@@ -0,0 +1,139 @@
+package lists
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"shopping_list/store"
+)
+
+func TestRunTemplateSkipsDeletedProducts(t *testing.T) {
+	ctx := context.Background()
+	s, workspace := setup(t)
+	milk := createProduct(t, s, workspace, "milk", 120)
+	bread := createProduct(t, s, workspace, "bread", 250)
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	run := now.Add(-time.Hour)
+	template := &store.ListTemplate{
+		WorkspaceID: workspace.ID,
+		UserID:      1,
+		Title:       "weekly",
+		Items:       []store.TemplateItem{{ProductID: milk.ID, Quantity: 2}, {ProductID: bread.ID, Quantity: 1}},
+		Recurrence:  &store.Recurrence{Weekday: time.Sunday, IntervalWeeks: 1},
+		NextRunAt:   &run,
+	}
+	if err := s.Templates.CreateTemplate(ctx, template); err != nil {
+		t.Fatalf("CreateTemplate: %s", err)
+	}
+
+	// The product is deleted after the due template was read
+	due, err := s.Templates.ListDueTemplates(ctx, now)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("ListDueTemplates = %v, %v, want one template", due, err)
+	}
+	if err := s.Products.DeleteProduct(ctx, bread.ID, 0); err != nil {
+		t.Fatalf("DeleteProduct: %s", err)
+	}
+
+	if err := runTemplate(ctx, &due[0], now); err != nil {
+		t.Fatalf("runTemplate: %s", err)
+	}
+
+	lists, err := s.Lists.ListListsWithItems(ctx, workspace.ID)
+	if err != nil {
+		t.Fatalf("ListListsWithItems: %s", err)
+	}
+	if len(lists) != 1 {
+		t.Fatalf("got %d lists, want 1", len(lists))
+	}
+	items := lists[0].Items
+	if len(items) != 1 || items[0].ProductID != milk.ID || items[0].Quantity != 2 {
+		t.Errorf("list items = %+v, want 2 of product %d", items, milk.ID)
+	}
+
+	stored, err := s.Templates.GetTemplate(ctx, template.ID)
+	if err != nil {
+		t.Fatalf("GetTemplate: %s", err)
+	}
+	if stored.NextRunAt == nil || !stored.NextRunAt.After(now) {
+		t.Errorf("next run = %v, want after %s", stored.NextRunAt, now)
+	}
+}
+
+func TestRunTemplateChecksTheAuthorRole(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup gives user 2, the author of the template, their place in the workspace
+		setup   func(ctx context.Context, s *store.Stores, workspaceID int) error
+		created bool
+	}{
+		{"editor", func(ctx context.Context, s *store.Stores, workspaceID int) error {
+			return s.Workspaces.AddWorkspaceMember(ctx, workspaceID, 2, store.RoleEditor)
+		}, true},
+		{"demoted to viewer", func(ctx context.Context, s *store.Stores, workspaceID int) error {
+			if err := s.Workspaces.AddWorkspaceMember(ctx, workspaceID, 2, store.RoleEditor); err != nil {
+				return err
+			}
+			return s.Workspaces.SetWorkspaceMemberRole(ctx, workspaceID, 2, store.RoleViewer)
+		}, false},
+		{"left the workspace", func(ctx context.Context, s *store.Stores, workspaceID int) error {
+			if err := s.Workspaces.AddWorkspaceMember(ctx, workspaceID, 2, store.RoleEditor); err != nil {
+				return err
+			}
+			return s.Workspaces.RemoveWorkspaceMember(ctx, workspaceID, 2)
+		}, false},
+		{"never a member", func(ctx context.Context, s *store.Stores, workspaceID int) error {
+			return nil
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, workspace := setup(t)
+			milk := createProduct(t, s, workspace, "milk", 120)
+			if err := tt.setup(ctx, s, workspace.ID); err != nil {
+				t.Fatalf("setting up the author: %s", err)
+			}
+
+			now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+			run := now.Add(-time.Hour)
+			template := &store.ListTemplate{
+				WorkspaceID: workspace.ID,
+				UserID:      2,
+				Title:       "weekly",
+				Items:       []store.TemplateItem{{ProductID: milk.ID, Quantity: 1}},
+				Recurrence:  &store.Recurrence{Weekday: time.Sunday, IntervalWeeks: 1},
+				NextRunAt:   &run,
+			}
+			if err := s.Templates.CreateTemplate(ctx, template); err != nil {
+				t.Fatalf("CreateTemplate: %s", err)
+			}
+
+			if err := runTemplate(ctx, template, now); err != nil {
+				t.Fatalf("runTemplate: %s", err)
+			}
+
+			lists, err := s.Lists.ListListsWithItems(ctx, workspace.ID)
+			if err != nil {
+				t.Fatalf("ListListsWithItems: %s", err)
+			}
+			if created := len(lists) == 1; created != tt.created {
+				t.Errorf("got %d lists, want a list created: %v", len(lists), tt.created)
+			}
+			if tt.created && lists[0].UserID != 2 {
+				t.Errorf("list of user %d, want the author", lists[0].UserID)
+			}
+
+			// A skipped run is still claimed, so it is not retried every minute
+			stored, err := s.Templates.GetTemplate(ctx, template.ID)
+			if err != nil {
+				t.Fatalf("GetTemplate: %s", err)
+			}
+			if stored.NextRunAt == nil || !stored.NextRunAt.After(now) {
+				t.Errorf("next run = %v, want after %s", stored.NextRunAt, now)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package lists
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"shopping_list/money"
+	"shopping_list/store"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	monthLayout          = "2006-01"
+	defaultSpendingRange = 12
+	maxSpendingRange     = 120
+)
+
+// SpendingSummary is the monthly spending of a workspace over a range of months
+type SpendingSummary struct {
+	From     string                  `json:"from"`
+	To       string                  `json:"to"`
+	Currency string                  `json:"currency"`
+	Total    money.Money             `json:"total"`
+	Months   []store.MonthlySpending `json:"months"`
+}
+
+// GetMonthlySpending returns what the workspace spent each month on the checked products of the lists
+// completed in it, at the price and quantity recorded when they were checked, or else at the current
+// price and the quantity on the list. Months are in UTC. The from and to months are inclusive and
+// formatted as 2006-01, by default the last 12 months up to the current one are returned. Months
+// without completed lists are returned with a zero total.
+func GetMonthlySpending(w http.ResponseWriter, r *http.Request) {
+	// Get workspace ID from URL parameters
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if value := r.URL.Query().Get("to"); value != "" {
+		if to, err = time.Parse(monthLayout, value); err != nil {
+			http.Error(w, "Invalid to, expected a month such as 2026-01", http.StatusBadRequest)
+			return
+		}
+	}
+	from := to.AddDate(0, 1-defaultSpendingRange, 0)
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = time.Parse(monthLayout, value); err != nil {
+			http.Error(w, "Invalid from, expected a month such as 2026-01", http.StatusBadRequest)
+			return
+		}
+	}
+	if from.After(to) {
+		http.Error(w, "Invalid range, from is after to", http.StatusBadRequest)
+		return
+	}
+	if from.AddDate(0, maxSpendingRange, 0).Before(to) {
+		http.Error(w, "Invalid range, at most "+strconv.Itoa(maxSpendingRange)+" months can be requested", http.StatusBadRequest)
+		return
+	}
+
+	workspace, err := stores.Workspaces.GetWorkspace(r.Context(), workspaceID)
+	if err != nil {
+		http.Error(w, "Error fetching workspace", http.StatusInternalServerError)
+		return
+	}
+
+	spent, err := stores.Lists.ListMonthlySpending(r.Context(), workspaceID, from, to.AddDate(0, 1, 0))
+	if err != nil {
+		http.Error(w, "Error fetching spending", http.StatusInternalServerError)
+		return
+	}
+	byMonth := make(map[string]store.MonthlySpending, len(spent))
+	for _, month := range spent {
+		byMonth[month.Month] = month
+	}
+
+	// Fill in the months without completed lists
+	summary := SpendingSummary{
+		From:     from.Format(monthLayout),
+		To:       to.Format(monthLayout),
+		Currency: workspace.Currency,
+		Total:    money.New(0, workspace.Currency),
+	}
+	for month := from; !month.After(to); month = month.AddDate(0, 1, 0) {
+		key := month.Format(monthLayout)
+		spending, ok := byMonth[key]
+		if !ok {
+			spending = store.MonthlySpending{Month: key, Total: money.New(0, workspace.Currency)}
+		}
+		if summary.Total, err = summary.Total.Add(spending.Total); err != nil {
+			http.Error(w, "Error adding up spending", http.StatusInternalServerError)
+			return
+		}
+		summary.Months = append(summary.Months, spending)
+	}
+
+	response := struct {
+		Status string          `json:"status"`
+		Data   SpendingSummary `json:"data"`
+	}{
+		Status: "Success",
+		Data:   summary,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
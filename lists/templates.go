@@ -0,0 +1,411 @@
+package lists
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"shopping_list/etag"
+	"shopping_list/middleware"
+	"shopping_list/store"
+
+	"github.com/gorilla/mux"
+)
+
+// maxIntervalWeeks is the longest recurrence accepted, a year
+const maxIntervalWeeks = 52
+
+// ListTemplate is a title and products that lists are created from, on demand or on a recurrence
+type ListTemplate struct {
+	ID          int               `json:"id"`
+	WorkspaceID int               `json:"workspace_id"`
+	UserID      int               `json:"user_id"`
+	Title       string            `json:"title"`
+	Products    []TemplateProduct `json:"products"`
+	// Recurrence is null for templates that are only used on demand
+	Recurrence *TemplateRecurrence `json:"recurrence"`
+	// NextRunAt is when the next list will be created, null without recurrence
+	NextRunAt *time.Time `json:"next_run_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// TemplateProduct is a product of a template with the quantity put in the lists
+type TemplateProduct struct {
+	ProductID int    `json:"product_id"`
+	Name      string `json:"name,omitempty"`
+	Quantity  int    `json:"quantity"`
+}
+
+// TemplateRecurrence creates a list from a template every interval_weeks weeks on a weekday, at
+// midnight UTC. For example {"weekday": "saturday", "interval_weeks": 2} is every other Saturday.
+type TemplateRecurrence struct {
+	// Weekday is the English name of the day, such as "saturday"
+	Weekday       string `json:"weekday"`
+	IntervalWeeks int    `json:"interval_weeks"`
+}
+
+// ListTemplateRequest represents the request body for creating or replacing a list template
+type ListTemplateRequest struct {
+	Title      string              `json:"title"`
+	Products   []TemplateProduct   `json:"products"`
+	Recurrence *TemplateRecurrence `json:"recurrence"`
+}
+
+// newListTemplate converts a stored template into its response representation
+func newListTemplate(template store.ListTemplate) ListTemplate {
+	response := ListTemplate{
+		ID:          template.ID,
+		WorkspaceID: template.WorkspaceID,
+		UserID:      template.UserID,
+		Title:       template.Title,
+		Products:    []TemplateProduct{},
+		NextRunAt:   template.NextRunAt,
+		CreatedAt:   template.CreatedAt,
+		UpdatedAt:   template.UpdatedAt,
+	}
+	for _, item := range template.Items {
+		response.Products = append(response.Products, TemplateProduct{ProductID: item.ProductID, Name: item.ProductTitle, Quantity: item.Quantity})
+	}
+	if template.Recurrence != nil {
+		response.Recurrence = &TemplateRecurrence{
+			Weekday:       strings.ToLower(template.Recurrence.Weekday.String()),
+			IntervalWeeks: template.Recurrence.IntervalWeeks,
+		}
+	}
+	return response
+}
+
+// templateProducts returns the products of a template as requested products of a new list
+func templateProducts(template *store.ListTemplate) []ListProductRequest {
+	products := make([]ListProductRequest, 0, len(template.Items))
+	for _, item := range template.Items {
+		products = append(products, ListProductRequest{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+	return products
+}
+
+// parseTemplate validates a template request, writing the error response if it is invalid.
+// It sets the title, items and recurrence of the template but not its next run.
+func parseTemplate(w http.ResponseWriter, r *http.Request, workspaceID int, req ListTemplateRequest, template *store.ListTemplate) bool {
+	if req.Title == "" {
+		http.Error(w, "Title is required", http.StatusBadRequest)
+		return false
+	}
+
+	items := make([]store.TemplateItem, 0, len(req.Products))
+	seen := make(map[int]bool)
+	for _, product := range req.Products {
+		storedProduct, err := stores.Products.GetProduct(r.Context(), product.ProductID)
+		if err != nil || storedProduct.WorkspaceID != workspaceID {
+			http.Error(w, "Product not found in this workspace: "+strconv.Itoa(product.ProductID), http.StatusBadRequest)
+			return false
+		}
+		if seen[product.ProductID] {
+			http.Error(w, "Product is listed more than once: "+strconv.Itoa(product.ProductID), http.StatusBadRequest)
+			return false
+		}
+		seen[product.ProductID] = true
+
+		// Set default quantity if not provided
+		quantity := product.Quantity
+		if quantity <= 0 {
+			quantity = 1
+		}
+		items = append(items, store.TemplateItem{ProductID: product.ProductID, Quantity: quantity})
+	}
+
+	var recurrence *store.Recurrence
+	if req.Recurrence != nil {
+		weekday, ok := parseWeekday(req.Recurrence.Weekday)
+		if !ok {
+			http.Error(w, "Invalid recurrence weekday, expected a day such as saturday", http.StatusBadRequest)
+			return false
+		}
+		if req.Recurrence.IntervalWeeks < 1 || req.Recurrence.IntervalWeeks > maxIntervalWeeks {
+			http.Error(w, "Invalid recurrence interval_weeks, expected a number between 1 and "+strconv.Itoa(maxIntervalWeeks), http.StatusBadRequest)
+			return false
+		}
+		recurrence = &store.Recurrence{Weekday: weekday, IntervalWeeks: req.Recurrence.IntervalWeeks}
+	}
+
+	template.Title = req.Title
+	template.Items = items
+	template.Recurrence = recurrence
+	return true
+}
+
+// parseWeekday parses the English name of a day of the week, ignoring case
+func parseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(name, day.String()) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// loadTemplate returns the template of the URL, writing the error response if it is not in the workspace
+func loadTemplate(w http.ResponseWriter, r *http.Request) (*store.ListTemplate, bool) {
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return nil, false
+	}
+	templateID, err := strconv.Atoi(vars["template_id"])
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	template, err := stores.Templates.GetTemplate(r.Context(), templateID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && template.WorkspaceID != workspaceID) {
+		http.Error(w, "Template not found in this workspace", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Error fetching template", http.StatusInternalServerError)
+		return nil, false
+	}
+	return template, true
+}
+
+// writeTemplate writes a template response with the status
+func writeTemplate(w http.ResponseWriter, status int, template store.ListTemplate) {
+	response := struct {
+		Status string       `json:"status"`
+		Data   ListTemplate `json:"data"`
+	}{
+		Status: "Success",
+		Data:   newListTemplate(template),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// ListListTemplates returns the list templates of a workspace ordered by title
+func ListListTemplates(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	templates, err := stores.Templates.ListTemplates(r.Context(), workspaceID)
+	if err != nil {
+		http.Error(w, "Error fetching templates", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string         `json:"status"`
+		Data   []ListTemplate `json:"data"`
+	}{
+		Status: "Success",
+		Data:   []ListTemplate{},
+	}
+	for _, template := range templates {
+		response.Data = append(response.Data, newListTemplate(template))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateListTemplate handles the creation of a list template. With a recurrence, its first list
+// is created on the next matching weekday.
+func CreateListTemplate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ListTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	template := store.ListTemplate{WorkspaceID: workspaceID, UserID: userID}
+	if !parseTemplate(w, r, workspaceID, req, &template) {
+		return
+	}
+	if template.Recurrence != nil {
+		next := template.Recurrence.First(time.Now())
+		template.NextRunAt = &next
+	}
+
+	if err := stores.Templates.CreateTemplate(r.Context(), &template); err != nil {
+		http.Error(w, "Error creating template", http.StatusInternalServerError)
+		return
+	}
+
+	created, err := stores.Templates.GetTemplate(r.Context(), template.ID)
+	if err != nil {
+		http.Error(w, "Error fetching template", http.StatusInternalServerError)
+		return
+	}
+	writeTemplate(w, http.StatusCreated, *created)
+}
+
+// GetListTemplate returns a list template with its products
+func GetListTemplate(w http.ResponseWriter, r *http.Request) {
+	template, ok := loadTemplate(w, r)
+	if !ok {
+		return
+	}
+	writeTemplate(w, http.StatusOK, *template)
+}
+
+// UpdateListTemplate replaces the title, products and recurrence of a list template. The next run
+// is kept unless the recurrence changes.
+func UpdateListTemplate(w http.ResponseWriter, r *http.Request) {
+	template, ok := loadTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	var req ListTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	previous := template.Recurrence
+	if !parseTemplate(w, r, template.WorkspaceID, req, template) {
+		return
+	}
+	switch {
+	case template.Recurrence == nil:
+		template.NextRunAt = nil
+	case previous == nil || *previous != *template.Recurrence:
+		next := template.Recurrence.First(time.Now())
+		template.NextRunAt = &next
+	}
+
+	err := stores.Templates.UpdateTemplate(r.Context(), template)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Template not found in this workspace", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error updating template", http.StatusInternalServerError)
+		return
+	}
+
+	updated, err := stores.Templates.GetTemplate(r.Context(), template.ID)
+	if err != nil {
+		http.Error(w, "Error fetching template", http.StatusInternalServerError)
+		return
+	}
+	writeTemplate(w, http.StatusOK, *updated)
+}
+
+// DeleteListTemplate deletes a list template, the lists created from it are kept
+func DeleteListTemplate(w http.ResponseWriter, r *http.Request) {
+	template, ok := loadTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	err := stores.Templates.DeleteTemplate(r.Context(), template.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Template not found in this workspace", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error deleting template", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string `json:"status"`
+		Data   string `json:"data"`
+	}{
+		Status: "Success",
+		Data:   "Template successfully deleted",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateListFromTemplateRequest represents the optional request body for creating a list from a template
+type CreateListFromTemplateRequest struct {
+	// Title replaces the title of the template when it is set
+	Title string `json:"title"`
+}
+
+// CreateListFromTemplate creates a list with the title and products of a template, the same way
+// CreateProductList does. Products deleted since they were added to the template are left out.
+func CreateListFromTemplate(w http.ResponseWriter, r *http.Request) {
+	template, ok := loadTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// The body is optional
+	var req CreateListFromTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	title := template.Title
+	if req.Title != "" {
+		title = req.Title
+	}
+
+	workspace, err := stores.Workspaces.GetWorkspace(r.Context(), template.WorkspaceID)
+	if err != nil {
+		http.Error(w, "Error fetching workspace", http.StatusInternalServerError)
+		return
+	}
+
+	productList, err := createList(r.Context(), workspace, userID, title, templateProducts(template), nil)
+	var invalid invalidListError
+	if errors.As(err, &invalid) {
+		http.Error(w, invalid.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error creating product list: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string      `json:"status"`
+		Data   ProductList `json:"data"`
+	}{
+		Status: "Success",
+		Data:   productList,
+	}
+
+	etag.Set(w, productList.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,138 @@
+package lists
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"shopping_list/etag"
+	"shopping_list/events"
+	"shopping_list/store"
+
+	"github.com/gorilla/mux"
+)
+
+// UpdateListItemRequest represents the request body for changing a single product of a list.
+// Fields that are left out are not changed.
+type UpdateListItemRequest struct {
+	// Quantity replaces the quantity, QuantityDelta adds to it or, when negative, takes from it
+	Quantity      *int             `json:"quantity"`
+	QuantityDelta int              `json:"quantity_delta"`
+	Checked       *bool            `json:"checked"`
+	Purchase      *PurchaseRequest `json:"purchase"`
+}
+
+// UpdateListItem handles checking, unchecking and changing the quantity of one product in a list without
+// resending the others. Like UpdateProductList it requires the If-Match header of the list. The changes
+// are applied atomically on the current item, so with If-Match: * shoppers changing the same list at
+// once do not undo each other's changes; quantity_delta lets them adjust the quantity relative to its
+// current value.
+func UpdateListItem(w http.ResponseWriter, r *http.Request) {
+	// Get workspace ID, list ID, and product ID from URL parameters
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	listID, err := strconv.Atoi(vars["list_id"])
+	if err != nil {
+		http.Error(w, "Invalid list ID", http.StatusBadRequest)
+		return
+	}
+
+	productID, err := strconv.Atoi(vars["product_id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	version, ok := etag.IfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req UpdateListItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate the requested changes
+	if req.Quantity == nil && req.QuantityDelta == 0 && req.Checked == nil {
+		http.Error(w, "Nothing to update, expected quantity, quantity_delta or checked", http.StatusBadRequest)
+		return
+	}
+	if req.Quantity != nil && req.QuantityDelta != 0 {
+		http.Error(w, "Use either quantity or quantity_delta", http.StatusBadRequest)
+		return
+	}
+	if req.Quantity != nil && *req.Quantity <= 0 {
+		http.Error(w, "Invalid quantity, expected a positive number", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the list exists and belongs to the specified workspace
+	list, err := stores.Lists.GetList(r.Context(), listID)
+	if err != nil || list.WorkspaceID != workspaceID {
+		http.Error(w, "List not found in this workspace", http.StatusNotFound)
+		return
+	}
+
+	workspace, err := stores.Workspaces.GetWorkspace(r.Context(), workspaceID)
+	if err != nil {
+		http.Error(w, "Error fetching workspace", http.StatusInternalServerError)
+		return
+	}
+	check, ok := parseItemCheck(w, r, req.Checked, req.Purchase, workspace)
+	if !ok {
+		return
+	}
+
+	change := store.ItemChange{
+		Quantity:      req.Quantity,
+		QuantityDelta: req.QuantityDelta,
+		Check:         check,
+		Version:       version,
+	}
+	item, err := stores.Lists.UpdateListItem(r.Context(), listID, productID, &change)
+	if errors.Is(err, store.ErrVersionConflict) {
+		writeCurrentList(w, r, workspaceID, listID, http.StatusPreconditionFailed)
+		return
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Product not found in this list", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, store.ErrInvalidQuantity) {
+		http.Error(w, "The quantity would drop below one", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error updating list product: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	product := Product{ListProduct: newListProduct(*item), Name: item.ProductTitle, Category: item.Category}
+	eventType := events.ItemUpdated
+	if req.Checked != nil {
+		eventType = events.ItemChecked
+	}
+	publish(r, events.Event{Type: eventType, WorkspaceID: workspaceID, ListID: listID, ProductID: productID, Data: product})
+
+	// Return the updated product
+	response := struct {
+		Status string  `json:"status"`
+		Data   Product `json:"data"`
+	}{
+		Status: "Success",
+		Data:   product,
+	}
+
+	etag.Set(w, change.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
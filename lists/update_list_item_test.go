@@ -0,0 +1,108 @@
+package lists
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"shopping_list/store"
+)
+
+const itemRoute = "/workspaces/{workspace_id}/product-lists/{list_id}/products/{product_id}"
+
+func TestUpdateListItem(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		ifMatch string
+		status  int
+		// quantity is the quantity of the item afterwards, negated when checked
+		quantity int
+	}{
+		{"quantity", `{"quantity":5}`, `"1"`, http.StatusOK, 5},
+		{"quantity delta on any version", `{"quantity_delta":-1}`, "*", http.StatusOK, 1},
+		{"check", `{"checked":true}`, `"1"`, http.StatusOK, -2},
+		{"missing If-Match", `{"quantity":5}`, "", http.StatusPreconditionRequired, 2},
+		{"stale version", `{"quantity":5}`, `"3"`, http.StatusPreconditionFailed, 2},
+		{"quantity below one", `{"quantity_delta":-2}`, "*", http.StatusConflict, 2},
+		{"nothing to update", `{}`, `"1"`, http.StatusBadRequest, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, workspace := setup(t)
+			milk := createProduct(t, s, workspace, "milk", 120)
+			list := createStoredList(t, s, workspace, store.ItemQuantity{ProductID: milk.ID, Quantity: 2})
+
+			path := fmt.Sprintf("/workspaces/%d/product-lists/%d/products/%d", workspace.ID, list.ID, milk.ID)
+			w := serve(t, itemRoute, UpdateListItem, http.MethodPatch, path, tt.ifMatch, tt.body, store.RoleEditor)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+
+			stored, err := s.Lists.GetList(context.Background(), list.ID)
+			if err != nil {
+				t.Fatalf("GetList: %s", err)
+			}
+			if tt.status == http.StatusOK || tt.status == http.StatusPreconditionFailed {
+				if want := fmt.Sprintf(`"%d"`, stored.Version); w.Header().Get("ETag") != want {
+					t.Errorf("ETag = %s, want %s", w.Header().Get("ETag"), want)
+				}
+			}
+			if tt.status != http.StatusOK && stored.Version != list.Version {
+				t.Errorf("list version = %d, want it unchanged at %d", stored.Version, list.Version)
+			}
+			if got, want := listItems(t, s, list.ID), map[int]int{milk.ID: tt.quantity}; !reflect.DeepEqual(got, want) {
+				t.Errorf("items = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDeleteProductFromList(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		status  int
+		// removed tells whether milk was removed from the list
+		removed bool
+	}{
+		{"current version", `"1"`, http.StatusOK, true},
+		{"any version", "*", http.StatusOK, true},
+		{"missing If-Match", "", http.StatusPreconditionRequired, false},
+		{"stale version", `"4"`, http.StatusPreconditionFailed, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, workspace := setup(t)
+			milk := createProduct(t, s, workspace, "milk", 120)
+			bread := createProduct(t, s, workspace, "bread", 250)
+			list := createStoredList(t, s, workspace, store.ItemQuantity{ProductID: milk.ID, Quantity: 2}, store.ItemQuantity{ProductID: bread.ID, Quantity: 1})
+
+			path := fmt.Sprintf("/workspaces/%d/product-lists/%d/products/%d", workspace.ID, list.ID, milk.ID)
+			w := serve(t, itemRoute, DeleteProductFromList, http.MethodDelete, path, tt.ifMatch, "", store.RoleEditor)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+
+			stored, err := s.Lists.GetList(context.Background(), list.ID)
+			if err != nil {
+				t.Fatalf("GetList: %s", err)
+			}
+			if tt.status == http.StatusOK || tt.status == http.StatusPreconditionFailed {
+				if want := fmt.Sprintf(`"%d"`, stored.Version); w.Header().Get("ETag") != want {
+					t.Errorf("ETag = %s, want %s", w.Header().Get("ETag"), want)
+				}
+			}
+
+			want := map[int]int{milk.ID: 2, bread.ID: 1}
+			if tt.removed {
+				want = map[int]int{bread.ID: 1}
+			}
+			if got := listItems(t, s, list.ID); !reflect.DeepEqual(got, want) {
+				t.Errorf("items = %v, want %v", got, want)
+			}
+		})
+	}
+}
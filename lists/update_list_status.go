@@ -2,28 +2,41 @@ package lists
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"shopping_list/db"
+	"shopping_list/etag"
+	"shopping_list/events"
 	"shopping_list/middleware"
+	"shopping_list/store"
 	"strconv"
-	"time"
 
 	"github.com/gorilla/mux"
 )
 
 // ListStatus represents the possible status values for a list
 const (
-	ListStatusDeleted   = 0
-	ListStatusActive    = 1
-	ListStatusCompleted = 2
+	ListStatusDeleted   = store.ListStatusDeleted
+	ListStatusActive    = store.ListStatusActive
+	ListStatusCompleted = store.ListStatusCompleted
 )
 
 // UpdateListStatusRequest represents the request body for updating a list's status
 type UpdateListStatusRequest struct {
 	Status int `json:"status"`
+	// CarryOver moves the unchecked products of a list being completed to another list
+	CarryOver *CarryOverRequest `json:"carry_over"`
 }
 
-// UpdateListStatus handles updating the status of a list
+// CarryOverRequest names the active list of the workspace receiving the unchecked products, or
+// without a list ID the title of a new list, which defaults to the title of the completed list
+type CarryOverRequest struct {
+	ListID int    `json:"list_id"`
+	Title  string `json:"title"`
+}
+
+// UpdateListStatus handles updating the status of a list. Like UpdateProductList it requires the
+// If-Match header. Completing a list with carry_over also moves its unchecked products, in the same
+// transaction, and the response then includes the list they were carried over to.
 func UpdateListStatus(w http.ResponseWriter, r *http.Request) {
 	// Get workspace ID and list ID from URL parameters
 	vars := mux.Vars(r)
@@ -38,11 +51,8 @@ func UpdateListStatus(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid list ID", http.StatusBadRequest)
 		return
 	}
-
-	// Get user ID from the token
-	userID, err := middleware.ExtractUserIDFromToken(r)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	version, ok := etag.IfMatch(w, r)
+	if !ok {
 		return
 	}
 
@@ -60,89 +70,114 @@ func UpdateListStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if the list exists and belongs to the specified workspace
-	var listExists bool
-	var listOwnerID int
-	err = db.DB.QueryRow(
-		"SELECT EXISTS(SELECT 1 FROM lists WHERE id = ? AND workspace_id = ? AND deleted_at IS NULL), user_id FROM lists WHERE id = ?",
-		listID, workspaceID, listID,
-	).Scan(&listExists, &listOwnerID)
-
-	if err != nil || !listExists {
+	storedList, err := stores.Lists.GetList(r.Context(), listID)
+	if err != nil || storedList.WorkspaceID != workspaceID {
 		http.Error(w, "List not found in this workspace", http.StatusNotFound)
 		return
 	}
 
-	// Check if the user has access to modify this list
-	// Either they are the list owner or they have access to the workspace
-	var hasAccess bool
-	if listOwnerID == userID {
-		hasAccess = true
-	} else {
-		err = db.DB.QueryRow(`
-			SELECT EXISTS(
-				SELECT 1 FROM workspaces 
-				WHERE id = ? AND user_id = ? AND deleted_at IS NULL
-				UNION
-				SELECT 1 FROM workspace_users 
-				WHERE workspace_id = ? AND user_id = ? AND deleted_at IS NULL
-			)`, workspaceID, userID, workspaceID, userID).Scan(&hasAccess)
-
-		if err != nil {
-			http.Error(w, "Error checking access", http.StatusInternalServerError)
-			return
-		}
+	// Deleting a list needs its own permission on top of editing it
+	if req.Status == ListStatusDeleted && !middleware.Can(r.Context(), middleware.PermDeleteLists) {
+		http.Error(w, "You don't have permission to delete this list", http.StatusForbidden)
+		return
 	}
-
-	if !hasAccess {
-		http.Error(w, "You don't have permission to modify this list", http.StatusForbidden)
+	if req.CarryOver != nil && req.Status != ListStatusCompleted {
+		http.Error(w, "Products can only be carried over when completing a list", http.StatusBadRequest)
 		return
 	}
 
-	// Update the list status
-	_, err = db.DB.Exec(
-		"UPDATE lists SET status = ?, updated_at = ? WHERE id = ?",
-		req.Status, time.Now(), listID,
-	)
+	// Update the list status, soft deleting it if status is "deleted"
+	var updatedList, target *store.List
+	if req.CarryOver != nil {
+		updatedList, target, err = carryOver(r, storedList, req.CarryOver, version)
+	} else {
+		updatedList, err = stores.Lists.SetListStatus(r.Context(), listID, req.Status, version)
+	}
+	if errors.Is(err, store.ErrInactiveList) {
+		http.Error(w, "Products can only be carried over to an active list of this workspace", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, store.ErrVersionConflict) {
+		writeCurrentList(w, r, workspaceID, listID, http.StatusPreconditionFailed)
+		return
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "List not found in this workspace", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error updating list status: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	list := newProductList(*updatedList)
 
-	// If status is "deleted", soft delete the list
-	if req.Status == ListStatusDeleted {
-		_, err = db.DB.Exec(
-			"UPDATE lists SET deleted_at = ? WHERE id = ?",
-			time.Now(), listID,
-		)
+	// A deleted list has no items left to estimate
+	if updatedList.DeletedAt == nil {
+		items, err := stores.Lists.ListItems(r.Context(), listID)
 		if err != nil {
-			http.Error(w, "Error deleting list: "+err.Error(), http.StatusInternalServerError)
+			http.Error(w, "Error retrieving list products", http.StatusInternalServerError)
+			return
+		}
+		workspace, err := stores.Workspaces.GetWorkspace(r.Context(), workspaceID)
+		if err != nil {
+			http.Error(w, "Error fetching workspace", http.StatusInternalServerError)
+			return
+		}
+		if err := list.setCost(items, workspace.Currency); err != nil {
+			http.Error(w, "Error estimating list total", http.StatusInternalServerError)
 			return
 		}
 	}
 
-	// Retrieve the updated list
-	var list ProductList
-	err = db.DB.QueryRow(
-		"SELECT id, workspace_id, user_id, title, created_at, updated_at, deleted_at FROM lists WHERE id = ?",
-		listID,
-	).Scan(
-		&list.ID, &list.WorkspaceID, &list.UserID, &list.Title, &list.CreatedAt, &list.UpdatedAt, &list.DeletedAt,
-	)
-	if err != nil {
-		http.Error(w, "Error retrieving updated list", http.StatusInternalServerError)
-		return
+	var carriedOver *ProductListWithProducts
+	if target != nil {
+		targetList, err := loadProductList(r.Context(), target)
+		if err != nil {
+			http.Error(w, "Error retrieving list products", http.StatusInternalServerError)
+			return
+		}
+		carriedOver = &targetList
+	}
+
+	if updatedList.DeletedAt != nil {
+		publish(r, events.Event{Type: events.ListDeleted, WorkspaceID: workspaceID, ListID: listID})
+	} else {
+		publishList(r, workspaceID, listID)
+	}
+	if target != nil && req.CarryOver.ListID == 0 {
+		publish(r, events.Event{Type: events.ListCreated, WorkspaceID: workspaceID, ListID: target.ID, Data: carriedOver})
+	} else if target != nil {
+		publishList(r, workspaceID, target.ID)
 	}
 
 	// Return the updated list
 	response := struct {
-		Status string      `json:"status"`
-		Data   ProductList `json:"data"`
+		Status      string                   `json:"status"`
+		Data        ProductList              `json:"data"`
+		CarriedOver *ProductListWithProducts `json:"carried_over,omitempty"`
 	}{
-		Status: "Success",
-		Data:   list,
+		Status:      "Success",
+		Data:        list,
+		CarriedOver: carriedOver,
 	}
 
+	etag.Set(w, updatedList.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
+
+// carryOver completes a list and moves its unchecked products to the requested list, or to a new
+// list of the user
+func carryOver(r *http.Request, storedList *store.List, req *CarryOverRequest, version int) (*store.List, *store.List, error) {
+	carry := store.CarryOver{ListID: storedList.ID, Version: version, TargetID: req.ListID}
+	if req.ListID == 0 {
+		userID, _ := middleware.UserIDFromContext(r.Context())
+		title := req.Title
+		if title == "" {
+			title = storedList.Title
+		}
+		carry.NewList = &store.List{WorkspaceID: storedList.WorkspaceID, UserID: userID, Title: title}
+	}
+	return stores.Lists.CompleteAndCarryOver(r.Context(), carry)
+}
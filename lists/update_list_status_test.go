@@ -0,0 +1,173 @@
+package lists
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"shopping_list/store"
+)
+
+const statusRoute = "/workspaces/{workspace_id}/product-lists/{list_id}/status"
+
+func TestUpdateListStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		ifMatch string
+		status  int
+		// listStatus is the stored status of the list afterwards
+		listStatus int
+	}{
+		{"complete", `{"status":2}`, `"1"`, http.StatusOK, ListStatusCompleted},
+		{"reactivate", `{"status":1}`, "*", http.StatusOK, ListStatusActive},
+		{"delete", `{"status":0}`, `"1"`, http.StatusOK, ListStatusDeleted},
+		{"missing If-Match", `{"status":2}`, "", http.StatusPreconditionRequired, ListStatusActive},
+		{"stale version", `{"status":2}`, `"2"`, http.StatusPreconditionFailed, ListStatusActive},
+		{"invalid status", `{"status":5}`, `"1"`, http.StatusBadRequest, ListStatusActive},
+		{"carry over without completing", `{"status":1,"carry_over":{}}`, `"1"`, http.StatusBadRequest, ListStatusActive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, workspace := setup(t)
+			milk := createProduct(t, s, workspace, "milk", 120)
+			list := createStoredList(t, s, workspace, store.ItemQuantity{ProductID: milk.ID, Quantity: 2})
+
+			path := fmt.Sprintf("/workspaces/%d/product-lists/%d/status", workspace.ID, list.ID)
+			w := serve(t, statusRoute, UpdateListStatus, http.MethodPatch, path, tt.ifMatch, tt.body, store.RoleOwner)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+
+			stored, err := s.Lists.GetList(context.Background(), list.ID)
+			if tt.listStatus == ListStatusDeleted {
+				if err == nil {
+					t.Errorf("GetList = %+v, want the list deleted", stored)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetList: %s", err)
+			}
+			if stored.Status != tt.listStatus {
+				t.Errorf("list status = %d, want %d", stored.Status, tt.listStatus)
+			}
+			if tt.status == http.StatusOK {
+				if want := fmt.Sprintf(`"%d"`, stored.Version); w.Header().Get("ETag") != want {
+					t.Errorf("ETag = %s, want %s", w.Header().Get("ETag"), want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateListStatusCarryOver(t *testing.T) {
+	tests := []struct {
+		name string
+		// target is the status of the list carried over to, 0 to carry over to a new list
+		target int
+		status int
+	}{
+		{"to a new list", 0, http.StatusOK},
+		{"to an active list", ListStatusActive, http.StatusOK},
+		{"to a completed list", ListStatusCompleted, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, workspace := setup(t)
+			milk := createProduct(t, s, workspace, "milk", 120)
+			bread := createProduct(t, s, workspace, "bread", 250)
+			eggs := createProduct(t, s, workspace, "eggs", 300)
+			list := createStoredList(t, s, workspace,
+				store.ItemQuantity{ProductID: milk.ID, Quantity: 2},
+				store.ItemQuantity{ProductID: bread.ID, Quantity: 1},
+				store.ItemQuantity{ProductID: eggs.ID, Quantity: 6},
+			)
+			if _, err := s.Lists.PutListItem(ctx, list.ID, store.ItemQuantity{ProductID: milk.ID, Quantity: 2, Check: &store.ItemCheck{Checked: true}}); err != nil {
+				t.Fatalf("PutListItem: %s", err)
+			}
+
+			body := `{"status":2,"carry_over":{"title":"next week"}}`
+			targetItems := map[int]int{bread.ID: 1, eggs.ID: 6}
+			if tt.target != 0 {
+				target := createStoredList(t, s, workspace, store.ItemQuantity{ProductID: eggs.ID, Quantity: 4})
+				if tt.target != ListStatusActive {
+					if _, err := s.Lists.SetListStatus(ctx, target.ID, tt.target, 0); err != nil {
+						t.Fatalf("SetListStatus: %s", err)
+					}
+				}
+				body = fmt.Sprintf(`{"status":2,"carry_over":{"list_id":%d}}`, target.ID)
+				targetItems = map[int]int{bread.ID: 1, eggs.ID: 10}
+			}
+
+			path := fmt.Sprintf("/workspaces/%d/product-lists/%d/status", workspace.ID, list.ID)
+			w := serve(t, statusRoute, UpdateListStatus, http.MethodPatch, path, "*", body, store.RoleEditor)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				// Nothing is completed or moved
+				stored, err := s.Lists.GetList(ctx, list.ID)
+				if err != nil || stored.Status != ListStatusActive {
+					t.Errorf("GetList = %+v, %v, want the list active", stored, err)
+				}
+				want := map[int]int{milk.ID: -2, bread.ID: 1, eggs.ID: 6}
+				if got := listItems(t, s, list.ID); !reflect.DeepEqual(got, want) {
+					t.Errorf("items = %v, want %v", got, want)
+				}
+				return
+			}
+
+			var response struct {
+				CarriedOver *struct {
+					ID    int    `json:"id"`
+					Title string `json:"title"`
+				} `json:"carried_over"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("decoding response: %s", err)
+			}
+			if response.CarriedOver == nil {
+				t.Fatal("the response has no carried_over list")
+			}
+			if tt.target == 0 && response.CarriedOver.Title != "next week" {
+				t.Errorf("carried over to %q, want a new list titled next week", response.CarriedOver.Title)
+			}
+
+			// The completed list keeps only its checked products
+			if got, want := listItems(t, s, list.ID), map[int]int{milk.ID: -2}; !reflect.DeepEqual(got, want) {
+				t.Errorf("completed list items = %v, want %v", got, want)
+			}
+			if got := listItems(t, s, response.CarriedOver.ID); !reflect.DeepEqual(got, targetItems) {
+				t.Errorf("carried over items = %v, want %v", got, targetItems)
+			}
+		})
+	}
+}
+
+func TestUpdateListStatusCarryOverKeepsPricePoints(t *testing.T) {
+	s, workspace := setup(t)
+	milk := createProduct(t, s, workspace, "milk", 120)
+	list := createStoredList(t, s, workspace, store.ItemQuantity{ProductID: milk.ID, Quantity: 2})
+	target := createStoredList(t, s, workspace, store.ItemQuantity{ProductID: milk.ID, Quantity: 1})
+	checkStoredItem(t, s, workspace, target.ID, milk.ID, 1, 99)
+
+	path := fmt.Sprintf("/workspaces/%d/product-lists/%d/status", workspace.ID, list.ID)
+	body := fmt.Sprintf(`{"status":2,"carry_over":{"list_id":%d}}`, target.ID)
+	w := serve(t, statusRoute, UpdateListStatus, http.MethodPatch, path, "*", body, store.RoleEditor)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	// The carried over milk is still to be bought, but the milk bought already was paid for
+	if got, want := listItems(t, s, target.ID), map[int]int{milk.ID: 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("target items = %v, want %v", got, want)
+	}
+	if !pricePaid(t, s, milk.ID, 99) {
+		t.Error("the price point of the unchecked target item was deleted")
+	}
+}
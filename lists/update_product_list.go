@@ -2,24 +2,28 @@ package lists
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
-	"shopping_list/db"
+	"shopping_list/etag"
+	"shopping_list/events"
 	"shopping_list/middleware"
+	"shopping_list/store"
 	"strconv"
-	"time"
 
 	"github.com/gorilla/mux"
 )
 
 // UpdateProductListRequest represents the request body for updating a product list
 type UpdateProductListRequest struct {
-	Title    string               `json:"title"`
-	Status   int                  `json:"status"`
+	Title string `json:"title"`
+	// Status is required, the deleted status soft deletes the list
+	Status   *int                 `json:"status"`
 	Products []ListProductRequest `json:"products"`
 }
 
-// UpdateProductList handles updating the title, status, and products of a list
+// UpdateProductList handles updating the title, status, and products of a list. The If-Match header
+// must hold the ETag of the version being replaced, a list changed since is answered with 412 and its
+// current version.
 func UpdateProductList(w http.ResponseWriter, r *http.Request) {
 	// Get workspace ID and list ID from URL parameters
 	vars := mux.Vars(r)
@@ -34,11 +38,8 @@ func UpdateProductList(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid list ID", http.StatusBadRequest)
 		return
 	}
-
-	// Get user ID from the token
-	userID, err := middleware.ExtractUserIDFromToken(r)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	version, ok := etag.IfMatch(w, r)
+	if !ok {
 		return
 	}
 
@@ -50,108 +51,74 @@ func UpdateProductList(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate status value
-	if req.Status < ListStatusDeleted || req.Status > ListStatusCompleted {
+	if req.Status == nil {
+		http.Error(w, "Status is required", http.StatusBadRequest)
+		return
+	}
+	if *req.Status < ListStatusDeleted || *req.Status > ListStatusCompleted {
 		http.Error(w, "Invalid status value", http.StatusBadRequest)
 		return
 	}
 
-	// Begin transaction
-	tx, err := db.DB.Begin()
-	if err != nil {
-		http.Error(w, "Error starting transaction", http.StatusInternalServerError)
+	// Check if the list exists and belongs to the specified workspace
+	list, err := stores.Lists.GetList(r.Context(), listID)
+	if err != nil || list.WorkspaceID != workspaceID {
+		http.Error(w, "List not found in this workspace", http.StatusNotFound)
 		return
 	}
-	defer tx.Rollback() // Rollback if not committed
 
-	// Update the list title and status
-	_, err = tx.Exec(
-		"UPDATE lists SET title = ?, status = ?, updated_at = ? WHERE id = ? AND workspace_id = ? AND user_id = ? AND deleted_at IS NULL",
-		req.Title, req.Status, time.Now(), listID, workspaceID, userID,
-	)
-	if err != nil {
-		http.Error(w, "Error updating list: "+err.Error(), http.StatusInternalServerError)
+	// Deleting a list needs its own permission on top of editing it
+	if *req.Status == ListStatusDeleted && !middleware.Can(r.Context(), middleware.PermDeleteLists) {
+		http.Error(w, "You don't have permission to delete this list", http.StatusForbidden)
 		return
 	}
 
-	// Handle products in the list
-	existingProducts := ""
-	for index, product := range req.Products {
-		existingProducts += strconv.Itoa(product.ProductID)
-		if index < len(req.Products)-1 {
-			existingProducts += ", "
-		}
-
-		// Check if the product already exists in the list
-		var exists bool
-		err := tx.QueryRow(
-			"SELECT EXISTS(SELECT 1 FROM list_products WHERE list_id = ? AND product_id = ? AND deleted_at IS NULL)",
-			listID, product.ProductID,
-		).Scan(&exists)
+	workspace, err := stores.Workspaces.GetWorkspace(r.Context(), workspaceID)
+	if err != nil {
+		http.Error(w, "Error fetching workspace", http.StatusInternalServerError)
+		return
+	}
 
+	// Update the list title and status and replace its products, checking them off as requested
+	update := store.ListUpdate{
+		ListID:      listID,
+		WorkspaceID: workspaceID,
+		Title:       req.Title,
+		Status:      *req.Status,
+		Version:     version,
+	}
+	for _, product := range req.Products {
+		item, err := listItem(r.Context(), workspaceID, product)
 		if err != nil {
-			http.Error(w, "Error checking product existence: "+err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-
-		if exists {
-			// Update existing product
-			_, err = tx.Exec(
-				"UPDATE list_products SET quantity = ?, updated_at = ? WHERE list_id = ? AND product_id = ?",
-				product.Quantity, time.Now(), listID, product.ProductID,
-			)
-		} else {
-			// Insert new product
-			_, err = tx.Exec(
-				"INSERT INTO list_products (list_id, product_id, quantity, checked, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
-				listID, product.ProductID, product.Quantity, false, time.Now(), time.Now(),
-			)
-		}
-
-		if err != nil {
-			http.Error(w, "Error updating product in list: "+err.Error(), http.StatusInternalServerError)
+		check, ok := parseItemCheck(w, r, product.Checked, product.Purchase, workspace)
+		if !ok {
 			return
 		}
+		item.Check = check
+		update.Items = append(update.Items, item)
 	}
 
-	// Soft delete products that are not in the request
-	if len(req.Products) > 0 {
-		// Build a proper query with placeholders for each product ID
-		query := "UPDATE list_products SET deleted_at = ? WHERE list_id = ? AND deleted_at IS NULL"
-
-		// Only add the NOT IN clause if there are products to exclude
-		placeholders := make([]interface{}, 0, len(req.Products)+2)
-		placeholders = append(placeholders, time.Now(), listID)
-
-		if len(req.Products) > 0 {
-			query += " AND product_id NOT IN ("
-			for i, product := range req.Products {
-				if i > 0 {
-					query += ", "
-				}
-				query += "?"
-				placeholders = append(placeholders, product.ProductID)
-			}
-			query += ")"
-		}
-
-		_, err = tx.Exec(query, placeholders...)
-	} else {
-		// If no products in request, mark all products as deleted
-		_, err = tx.Exec(
-			"UPDATE list_products SET deleted_at = ? WHERE list_id = ? AND deleted_at IS NULL",
-			time.Now(), listID,
-		)
+	err = stores.Lists.UpdateList(r.Context(), &update)
+	if errors.Is(err, store.ErrVersionConflict) {
+		writeCurrentList(w, r, workspaceID, listID, http.StatusPreconditionFailed)
+		return
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "List not found in this workspace", http.StatusNotFound)
+		return
 	}
 	if err != nil {
-		fmt.Print(existingProducts)
-		http.Error(w, "Error deleting products from list: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Error updating list: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		http.Error(w, "Error committing transaction", http.StatusInternalServerError)
-		return
+	if *req.Status == ListStatusDeleted {
+		publish(r, events.Event{Type: events.ListDeleted, WorkspaceID: workspaceID, ListID: listID})
+	} else {
+		publishList(r, workspaceID, listID)
 	}
 
 	// Return success response
@@ -161,6 +128,7 @@ func UpdateProductList(w http.ResponseWriter, r *http.Request) {
 		Status: "Success",
 	}
 
+	etag.Set(w, update.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
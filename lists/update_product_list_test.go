@@ -0,0 +1,190 @@
+package lists
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"shopping_list/store"
+)
+
+const listRoute = "/workspaces/{workspace_id}/product-lists/{list_id}"
+
+func TestUpdateProductList(t *testing.T) {
+	tests := []struct {
+		name string
+		// body is formatted with the IDs of milk, bread and coffee from another workspace
+		body    string
+		ifMatch string
+		status  int
+		// items are the products of the list afterwards, negated when checked
+		items map[int]int
+	}{
+		{
+			name:    "replace products",
+			body:    `{"title":"groceries","status":1,"products":[{"product_id":%[1]d,"quantity":3}]}`,
+			ifMatch: `"1"`,
+			status:  http.StatusOK,
+			items:   map[int]int{0: 3},
+		},
+		{
+			name:    "check with a purchase",
+			body:    `{"title":"groceries","status":1,"products":[{"product_id":%[1]d,"quantity":2,"checked":true,"purchase":{"unit_price":"1.10","quantity":2}},{"product_id":%[2]d,"quantity":1}]}`,
+			ifMatch: "*",
+			status:  http.StatusOK,
+			items:   map[int]int{0: -2, 1: 1},
+		},
+		{
+			name:    "quantity defaults to one",
+			body:    `{"title":"groceries","status":1,"products":[{"product_id":%[1]d,"quantity":0},{"product_id":%[2]d,"quantity":-3}]}`,
+			ifMatch: `"1"`,
+			status:  http.StatusOK,
+			items:   map[int]int{0: 1, 1: 1},
+		},
+		{
+			name:    "product of another workspace",
+			body:    `{"title":"groceries","status":1,"products":[{"product_id":%[1]d,"quantity":2},{"product_id":%[3]d,"quantity":1,"checked":true,"purchase":{"unit_price":"9.99"}}]}`,
+			ifMatch: `"1"`,
+			status:  http.StatusBadRequest,
+			items:   map[int]int{0: 2, 1: 1},
+		},
+		{
+			name:    "unknown product",
+			body:    `{"title":"groceries","status":1,"products":[{"product_id":999,"quantity":1}]}`,
+			ifMatch: `"1"`,
+			status:  http.StatusBadRequest,
+			items:   map[int]int{0: 2, 1: 1},
+		},
+		{
+			name:    "missing status",
+			body:    `{"title":"groceries","products":[{"product_id":%[1]d,"quantity":3}]}`,
+			ifMatch: `"1"`,
+			status:  http.StatusBadRequest,
+			items:   map[int]int{0: 2, 1: 1},
+		},
+		{
+			name:   "missing If-Match",
+			body:   `{"title":"groceries","status":1}`,
+			status: http.StatusPreconditionRequired,
+			items:  map[int]int{0: 2, 1: 1},
+		},
+		{
+			name:    "stale version",
+			body:    `{"title":"groceries","status":1}`,
+			ifMatch: `"7"`,
+			status:  http.StatusPreconditionFailed,
+			items:   map[int]int{0: 2, 1: 1},
+		},
+		{
+			name:    "purchase without checking",
+			body:    `{"title":"groceries","status":1,"products":[{"product_id":%[1]d,"quantity":2,"purchase":{"quantity":2}}]}`,
+			ifMatch: `"1"`,
+			status:  http.StatusBadRequest,
+			items:   map[int]int{0: 2, 1: 1},
+		},
+		{
+			name:    "invalid status",
+			body:    `{"title":"groceries","status":3}`,
+			ifMatch: `"1"`,
+			status:  http.StatusBadRequest,
+			items:   map[int]int{0: 2, 1: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, workspace := setup(t)
+			milk := createProduct(t, s, workspace, "milk", 120)
+			bread := createProduct(t, s, workspace, "bread", 250)
+			list := createStoredList(t, s, workspace, store.ItemQuantity{ProductID: milk.ID, Quantity: 2}, store.ItemQuantity{ProductID: bread.ID, Quantity: 1})
+			other := &store.Workspace{Name: "office", UserID: 2}
+			if err := s.Workspaces.CreateWorkspace(context.Background(), other); err != nil {
+				t.Fatalf("CreateWorkspace: %s", err)
+			}
+			coffee := createProduct(t, s, other, "coffee", 800)
+
+			path := fmt.Sprintf("/workspaces/%d/product-lists/%d", workspace.ID, list.ID)
+			body := fmt.Sprintf(tt.body, milk.ID, bread.ID, coffee.ID)
+			w := serve(t, listRoute, UpdateProductList, http.MethodPatch, path, tt.ifMatch, body, store.RoleEditor)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusOK || tt.status == http.StatusPreconditionFailed {
+				stored, err := s.Lists.GetList(context.Background(), list.ID)
+				if err != nil {
+					t.Fatalf("GetList: %s", err)
+				}
+				if want := fmt.Sprintf(`"%d"`, stored.Version); w.Header().Get("ETag") != want {
+					t.Errorf("ETag = %s, want %s", w.Header().Get("ETag"), want)
+				}
+			}
+
+			// The items are given by the position of their product
+			want := make(map[int]int, len(tt.items))
+			for i, quantity := range tt.items {
+				want[[]int{milk.ID, bread.ID}[i]] = quantity
+			}
+			if got := listItems(t, s, list.ID); !reflect.DeepEqual(got, want) {
+				t.Errorf("items = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// A product removed from a list can be put back, replacing its removed item
+func TestUpdateProductListRestoresRemovedItems(t *testing.T) {
+	s, workspace := setup(t)
+	milk := createProduct(t, s, workspace, "milk", 120)
+	bread := createProduct(t, s, workspace, "bread", 250)
+	list := createStoredList(t, s, workspace, store.ItemQuantity{ProductID: milk.ID, Quantity: 2}, store.ItemQuantity{ProductID: bread.ID, Quantity: 1})
+	path := fmt.Sprintf("/workspaces/%d/product-lists/%d", workspace.ID, list.ID)
+
+	for _, body := range []string{
+		fmt.Sprintf(`{"title":"groceries","status":1,"products":[{"product_id":%d,"quantity":2}]}`, milk.ID),
+		fmt.Sprintf(`{"title":"groceries","status":1,"products":[{"product_id":%d,"quantity":2},{"product_id":%d,"quantity":4}]}`, milk.ID, bread.ID),
+	} {
+		w := serve(t, listRoute, UpdateProductList, http.MethodPatch, path, "*", body, store.RoleOwner)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+		}
+	}
+
+	want := map[int]int{milk.ID: 2, bread.ID: 4}
+	if got := listItems(t, s, list.ID); !reflect.DeepEqual(got, want) {
+		t.Errorf("items = %v, want %v", got, want)
+	}
+}
+
+// The deleted status soft deletes the list, so it is hidden like a list deleted through its status
+func TestUpdateProductListDeletes(t *testing.T) {
+	ctx := context.Background()
+	s, workspace := setup(t)
+	milk := createProduct(t, s, workspace, "milk", 120)
+	list := createStoredList(t, s, workspace, store.ItemQuantity{ProductID: milk.ID, Quantity: 2})
+
+	path := fmt.Sprintf("/workspaces/%d/product-lists/%d", workspace.ID, list.ID)
+	body := fmt.Sprintf(`{"title":"groceries","status":0,"products":[{"product_id":%d,"quantity":2}]}`, milk.ID)
+	w := serve(t, listRoute, UpdateProductList, http.MethodPatch, path, `"1"`, body, store.RoleOwner)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	if stored, err := s.Lists.GetList(ctx, list.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetList = %+v, %v, want ErrNotFound", stored, err)
+	}
+	lists, err := s.Lists.ListListsWithItems(ctx, workspace.ID)
+	if err != nil {
+		t.Fatalf("ListListsWithItems: %s", err)
+	}
+	if len(lists) != 0 {
+		t.Errorf("ListListsWithItems = %+v, want no lists", lists)
+	}
+
+	// A deleted list cannot be updated again
+	w = serve(t, listRoute, UpdateProductList, http.MethodPatch, path, "*", `{"title":"groceries","status":1}`, store.RoleOwner)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("updating the deleted list status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,75 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"shopping_list/config"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers emails. Implementations must be safe for concurrent use.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender returns the sender selected by the mail configuration
+func NewSender(cfg config.MailConfig) (Sender, error) {
+	switch cfg.Driver {
+	case "log":
+		return &LogSender{From: cfg.From}, nil
+	case "file":
+		if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+			return nil, err
+		}
+		return &FileSender{Dir: cfg.Dir, From: cfg.From}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+// LogSender writes emails to the server log instead of delivering them
+type LogSender struct {
+	From string
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("mail from %s to %s: %s\n%s", s.From, msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileSender writes every email to its own .eml file in Dir, for local testing
+type FileSender struct {
+	Dir  string
+	From string
+}
+
+// unsafeFileChars are replaced in the recipient when building the file name
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9@._-]`)
+
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s.eml", now.Format("20060102T150405.000000000"), unsafeFileChars.ReplaceAllString(msg.To, "_"))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(msg.Body)
+
+	return os.WriteFile(filepath.Join(s.Dir, name), []byte(b.String()), 0o644)
+}
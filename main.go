@@ -1,17 +1,30 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"shopping_list/auth"
+	"shopping_list/categories"
+	"shopping_list/changes"
+	"shopping_list/config"
 	"shopping_list/db"
+	"shopping_list/events"
+	"shopping_list/invitations"
 	"shopping_list/lists"
+	"shopping_list/mail"
 	"shopping_list/middleware"
 	"shopping_list/products"
+	"shopping_list/shops"
+	"shopping_list/store"
 	"shopping_list/workspaces"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -21,21 +34,50 @@ func getRoot(w http.ResponseWriter, r *http.Request) {
 	io.WriteString(w, "This is my website!\n")
 }
 
-func enableCORS(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, PUT, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Accept")
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
-}
-
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+
+	config.Load()
 	db.DbConnect()
+	autoMigrate()
+
+	// Inject the storage backends into the handlers
+	stores := store.NewMySQL(db.DB)
+	stores.Tokens = store.NewCachedTokens(stores.Tokens, config.JWT.RevocationCacheTTL)
+	middleware.SetStores(stores)
+	auth.SetStores(stores)
+	workspaces.SetStores(stores)
+	products.SetStores(stores)
+	lists.SetStores(stores)
+	invitations.SetStores(stores)
+	categories.SetStores(stores)
+	shops.SetStores(stores)
+	events.SetStores(stores)
+	changes.SetStores(stores)
+
+	// Changes are published to the clients connected to this process
+	broker := events.NewMemoryBroker()
+	events.SetBroker(broker)
+	products.SetBroker(broker)
+	lists.SetBroker(broker)
+	changes.SetBroker(broker)
+
+	sender, err := mail.NewSender(config.Mail)
+	if err != nil {
+		fmt.Printf("error configuring mail sender: %s\n", err)
+		os.Exit(1)
+	}
+	invitations.SetSender(sender)
+
+	// The scheduler and the server stop when the process is interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Create the lists of recurring templates when they are due
+	go lists.RunTemplateScheduler(ctx, time.Minute)
 
 	r := mux.NewRouter()
 	r.HandleFunc("/", getRoot)
@@ -43,34 +85,105 @@ func main() {
 	// Users routes
 	r.HandleFunc("/users/register", auth.Register).Methods(http.MethodPost)
 	r.HandleFunc("/users/login", auth.Login).Methods(http.MethodPost)
-	r.HandleFunc("/users/logout", auth.Logout).Methods(http.MethodPost)
+	r.HandleFunc("/users/logout", middleware.TokenAuthMiddleware(auth.Logout)).Methods(http.MethodPost)
+	r.HandleFunc("/users/refresh", auth.Refresh).Methods(http.MethodPost)
+	r.HandleFunc("/users/logout-all", middleware.TokenAuthMiddleware(auth.LogoutAll)).Methods(http.MethodPost)
 
 	// Workspaces routes
 	r.HandleFunc("/workspaces", middleware.TokenAuthMiddleware(workspaces.CreateWorkspace)).Methods(http.MethodPost)
 	r.HandleFunc("/workspaces", middleware.TokenAuthMiddleware(workspaces.ListWorkspaces)).Methods(http.MethodGet)
-	r.HandleFunc("/workspaces/{workspace_id}", middleware.TokenAuthMiddleware(workspaces.UpdateWorkspace)).Methods(http.MethodPatch)
-	r.HandleFunc("/workspaces/{workspace_id}", middleware.TokenAuthMiddleware(workspaces.DeleteWorkspace)).Methods(http.MethodDelete)
-	r.HandleFunc("/workspaces/{workspace_id}", middleware.TokenAuthMiddleware(workspaces.GetWorkspace)).Methods(http.MethodGet)
-	r.HandleFunc("/workspaces/{workspace_id}/add_user/{user_id}", middleware.TokenAuthMiddleware(workspaces.AddUserToWorkspace)).Methods(http.MethodPost)
-	r.HandleFunc("/workspaces/{workspace_id}/remove_user/{user_id}", middleware.TokenAuthMiddleware(workspaces.RemoveUserFromWorkspace)).Methods(http.MethodDelete)
-	r.HandleFunc("/workspaces/{workspace_id}/users", middleware.TokenAuthMiddleware(workspaces.ListUsersInWorkspace)).Methods(http.MethodGet)
+	r.HandleFunc("/workspaces/{workspace_id}", middleware.AuthorizedWorkspaceMiddleware(middleware.PermManageWorkspace, workspaces.UpdateWorkspace)).Methods(http.MethodPatch)
+	r.HandleFunc("/workspaces/{workspace_id}", middleware.AuthorizedWorkspaceMiddleware(middleware.PermManageWorkspace, workspaces.DeleteWorkspace)).Methods(http.MethodDelete)
+	r.HandleFunc("/workspaces/{workspace_id}", middleware.AuthorizedWorkspaceMiddleware(middleware.PermViewWorkspace, workspaces.GetWorkspace)).Methods(http.MethodGet)
+	r.HandleFunc("/workspaces/{workspace_id}/transfer", middleware.AuthorizedWorkspaceMiddleware(middleware.PermTransferOwner, workspaces.TransferWorkspace)).Methods(http.MethodPost)
+	r.HandleFunc("/workspaces/{workspace_id}/leave", middleware.AuthorizedWorkspaceMiddleware(middleware.PermViewWorkspace, workspaces.LeaveWorkspace)).Methods(http.MethodPost)
+	r.HandleFunc("/workspaces/{workspace_id}/add_user/{user_id}", middleware.AuthorizedWorkspaceMiddleware(middleware.PermManageMembers, workspaces.AddUserToWorkspace)).Methods(http.MethodPost)
+	r.HandleFunc("/workspaces/{workspace_id}/remove_user/{user_id}", middleware.AuthorizedWorkspaceMiddleware(middleware.PermManageMembers, workspaces.RemoveUserFromWorkspace)).Methods(http.MethodDelete)
+	r.HandleFunc("/workspaces/{workspace_id}/users", middleware.AuthorizedWorkspaceMiddleware(middleware.PermViewWorkspace, workspaces.ListUsersInWorkspace)).Methods(http.MethodGet)
+	r.HandleFunc("/workspaces/{workspace_id}/users/{user_id}/role", middleware.AuthorizedWorkspaceMiddleware(middleware.PermManageMembers, workspaces.UpdateMemberRole)).Methods(http.MethodPatch)
+
+	// Invitations routes
+	r.HandleFunc("/workspaces/{workspace_id}/invitations", middleware.AuthorizedWorkspaceMiddleware(middleware.PermManageMembers, invitations.CreateInvitation)).Methods(http.MethodPost)
+	r.HandleFunc("/workspaces/{workspace_id}/invitations", middleware.AuthorizedWorkspaceMiddleware(middleware.PermManageMembers, invitations.ListWorkspaceInvitations)).Methods(http.MethodGet)
+	r.HandleFunc("/workspaces/{workspace_id}/invitations/{invitation_id}", middleware.AuthorizedWorkspaceMiddleware(middleware.PermManageMembers, invitations.RevokeInvitation)).Methods(http.MethodDelete)
+	r.HandleFunc("/invitations", middleware.TokenAuthMiddleware(invitations.ListMyInvitations)).Methods(http.MethodGet)
+	r.HandleFunc("/invitations/accept", middleware.TokenAuthMiddleware(invitations.AcceptInvitationByToken)).Methods(http.MethodPost)
+	r.HandleFunc("/invitations/decline", invitations.DeclineInvitationByToken).Methods(http.MethodPost)
+	r.HandleFunc("/invitations/{invitation_id}/accept", middleware.TokenAuthMiddleware(invitations.AcceptInvitation)).Methods(http.MethodPost)
+	r.HandleFunc("/invitations/{invitation_id}/decline", middleware.TokenAuthMiddleware(invitations.DeclineInvitation)).Methods(http.MethodPost)
 
 	// Products routes
-	r.HandleFunc("/workspaces/{workspace_id}/products", middleware.TokenAuthMiddleware(middleware.CombinedWorkspaceMiddleware(products.ProductsHandler)))
-	r.HandleFunc("/workspaces/{workspace_id}/products/{id}", middleware.TokenAuthMiddleware(middleware.CombinedWorkspaceMiddleware(products.ProductHandler)))
+	r.HandleFunc("/workspaces/{workspace_id}/products", middleware.CombinedWorkspaceMiddleware(products.ProductsHandler))
+	r.HandleFunc("/workspaces/{workspace_id}/products/{id}", middleware.CombinedWorkspaceMiddleware(products.ProductHandler))
+	r.HandleFunc("/workspaces/{workspace_id}/products/{id}/prices", middleware.CombinedWorkspaceMiddleware(products.GetPriceHistory)).Methods(http.MethodGet)
+
+	// Categories routes
+	r.HandleFunc("/workspaces/{workspace_id}/categories", middleware.AuthorizedWorkspaceMiddleware(middleware.PermViewWorkspace, categories.ListCategories)).Methods(http.MethodGet)
+	r.HandleFunc("/workspaces/{workspace_id}/categories", middleware.AuthorizedWorkspaceMiddleware(middleware.PermEditProducts, categories.CreateCategory)).Methods(http.MethodPost)
+	r.HandleFunc("/workspaces/{workspace_id}/categories/{category_id}", middleware.AuthorizedWorkspaceMiddleware(middleware.PermEditProducts, categories.UpdateCategory)).Methods(http.MethodPatch)
+	r.HandleFunc("/workspaces/{workspace_id}/categories/{category_id}", middleware.AuthorizedWorkspaceMiddleware(middleware.PermEditProducts, categories.DeleteCategory)).Methods(http.MethodDelete)
+
+	// Stores routes
+	r.HandleFunc("/workspaces/{workspace_id}/stores", middleware.AuthorizedWorkspaceMiddleware(middleware.PermViewWorkspace, shops.ListShops)).Methods(http.MethodGet)
+	r.HandleFunc("/workspaces/{workspace_id}/stores", middleware.AuthorizedWorkspaceMiddleware(middleware.PermEditProducts, shops.CreateShop)).Methods(http.MethodPost)
+	r.HandleFunc("/workspaces/{workspace_id}/stores/{store_id}", middleware.AuthorizedWorkspaceMiddleware(middleware.PermViewWorkspace, shops.GetShop)).Methods(http.MethodGet)
+	r.HandleFunc("/workspaces/{workspace_id}/stores/{store_id}", middleware.AuthorizedWorkspaceMiddleware(middleware.PermEditProducts, shops.UpdateShop)).Methods(http.MethodPatch)
+	r.HandleFunc("/workspaces/{workspace_id}/stores/{store_id}", middleware.AuthorizedWorkspaceMiddleware(middleware.PermEditProducts, shops.DeleteShop)).Methods(http.MethodDelete)
+	r.HandleFunc("/workspaces/{workspace_id}/stores/{store_id}/layout", middleware.AuthorizedWorkspaceMiddleware(middleware.PermViewWorkspace, shops.GetShopLayout)).Methods(http.MethodGet)
+	r.HandleFunc("/workspaces/{workspace_id}/stores/{store_id}/layout", middleware.AuthorizedWorkspaceMiddleware(middleware.PermEditProducts, shops.SetShopLayout)).Methods(http.MethodPut)
 
 	// Product Lists routes
-	r.HandleFunc("/workspaces/{workspace_id}/product-lists", middleware.TokenAuthMiddleware(middleware.WorkspaceMiddleware(lists.ListProductLists))).Methods(http.MethodGet)
-	r.HandleFunc("/workspaces/{workspace_id}/product-lists", middleware.TokenAuthMiddleware(middleware.WorkspaceMiddleware(lists.CreateProductList))).Methods(http.MethodPost)
-	r.HandleFunc("/workspaces/{workspace_id}/product-lists/{list_id}", middleware.TokenAuthMiddleware(middleware.WorkspaceMiddleware(lists.UpdateProductList))).Methods(http.MethodPatch)
-	r.HandleFunc("/workspaces/{workspace_id}/product-lists/{list_id}/status", middleware.TokenAuthMiddleware(middleware.WorkspaceMiddleware(lists.UpdateListStatus))).Methods(http.MethodPatch)
-	r.HandleFunc("/workspaces/{workspace_id}/product-lists/{list_id}/products/{product_id}", middleware.TokenAuthMiddleware(middleware.WorkspaceMiddleware(lists.DeleteProductFromList))).Methods(http.MethodDelete)
+	r.HandleFunc("/workspaces/{workspace_id}/product-lists", middleware.AuthorizedWorkspaceMiddleware(middleware.PermViewWorkspace, lists.ListProductLists)).Methods(http.MethodGet)
+	r.HandleFunc("/workspaces/{workspace_id}/product-lists", middleware.AuthorizedWorkspaceMiddleware(middleware.PermEditLists, lists.CreateProductList)).Methods(http.MethodPost)
+	r.HandleFunc("/workspaces/{workspace_id}/product-lists/{list_id}", middleware.AuthorizedWorkspaceMiddleware(middleware.PermViewWorkspace, lists.GetProductList)).Methods(http.MethodGet)
+	r.HandleFunc("/workspaces/{workspace_id}/product-lists/{list_id}", middleware.AuthorizedWorkspaceMiddleware(middleware.PermEditLists, lists.UpdateProductList)).Methods(http.MethodPatch)
+	r.HandleFunc("/workspaces/{workspace_id}/product-lists/{list_id}/status", middleware.AuthorizedWorkspaceMiddleware(middleware.PermEditLists, lists.UpdateListStatus)).Methods(http.MethodPatch)
+	r.HandleFunc("/workspaces/{workspace_id}/product-lists/{list_id}/duplicate", middleware.AuthorizedWorkspaceMiddleware(middleware.PermEditLists, lists.DuplicateProductList)).Methods(http.MethodPost)
+	r.HandleFunc("/workspaces/{workspace_id}/product-lists/{list_id}/merge", middleware.AuthorizedWorkspaceMiddleware(middleware.PermEditLists, lists.MergeProductLists)).Methods(http.MethodPost)
+	r.HandleFunc("/workspaces/{workspace_id}/product-lists/{list_id}/budget", middleware.AuthorizedWorkspaceMiddleware(middleware.PermEditLists, lists.SetListBudget)).Methods(http.MethodPut)
+	r.HandleFunc("/workspaces/{workspace_id}/product-lists/{list_id}/products/{product_id}", middleware.AuthorizedWorkspaceMiddleware(middleware.PermEditLists, lists.UpdateListItem)).Methods(http.MethodPatch)
+	r.HandleFunc("/workspaces/{workspace_id}/product-lists/{list_id}/products/{product_id}", middleware.AuthorizedWorkspaceMiddleware(middleware.PermEditLists, lists.DeleteProductFromList)).Methods(http.MethodDelete)
+
+	// List templates routes
+	r.HandleFunc("/workspaces/{workspace_id}/list-templates", middleware.AuthorizedWorkspaceMiddleware(middleware.PermViewWorkspace, lists.ListListTemplates)).Methods(http.MethodGet)
+	r.HandleFunc("/workspaces/{workspace_id}/list-templates", middleware.AuthorizedWorkspaceMiddleware(middleware.PermEditLists, lists.CreateListTemplate)).Methods(http.MethodPost)
+	r.HandleFunc("/workspaces/{workspace_id}/list-templates/{template_id}", middleware.AuthorizedWorkspaceMiddleware(middleware.PermViewWorkspace, lists.GetListTemplate)).Methods(http.MethodGet)
+	r.HandleFunc("/workspaces/{workspace_id}/list-templates/{template_id}", middleware.AuthorizedWorkspaceMiddleware(middleware.PermEditLists, lists.UpdateListTemplate)).Methods(http.MethodPut)
+	r.HandleFunc("/workspaces/{workspace_id}/list-templates/{template_id}", middleware.AuthorizedWorkspaceMiddleware(middleware.PermDeleteLists, lists.DeleteListTemplate)).Methods(http.MethodDelete)
+	r.HandleFunc("/workspaces/{workspace_id}/list-templates/{template_id}/lists", middleware.AuthorizedWorkspaceMiddleware(middleware.PermEditLists, lists.CreateListFromTemplate)).Methods(http.MethodPost)
+
+	// Events routes
+	r.HandleFunc("/workspaces/{workspace_id}/events", middleware.QueryTokenMiddleware(middleware.AuthorizedWorkspaceMiddleware(middleware.PermViewWorkspace, events.Stream))).Methods(http.MethodGet)
+
+	// Sync routes, each mutation of a batch is checked against the role of the user
+	r.HandleFunc("/workspaces/{workspace_id}/sync", middleware.AuthorizedWorkspaceMiddleware(middleware.PermViewWorkspace, changes.GetChanges)).Methods(http.MethodGet)
+	r.HandleFunc("/workspaces/{workspace_id}/sync", middleware.AuthorizedWorkspaceMiddleware(middleware.PermViewWorkspace, changes.ApplyMutations)).Methods(http.MethodPost)
+
+	r.HandleFunc("/workspaces/{workspace_id}/spending", middleware.AuthorizedWorkspaceMiddleware(middleware.PermViewWorkspace, lists.GetMonthlySpending)).Methods(http.MethodGet)
 
 	// Wrap the router with CORS middleware
-	handler := enableCORS(r)
+	handler := middleware.EnableCORS(r)
+
+	// Requests share the server context, so open event streams end on shutdown
+	server := &http.Server{
+		Addr:        ":3333",
+		Handler:     handler,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			fmt.Printf("error shutting down server: %s\n", err)
+		}
+	}()
 
-	err := http.ListenAndServe(":3333", handler)
+	err = server.ListenAndServe()
 	if errors.Is(err, http.ErrServerClosed) {
+		<-shutdown
 		fmt.Printf("server closed\n")
 	} else if err != nil {
 		fmt.Printf("error starting server: %s\n", err)
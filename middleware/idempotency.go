@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"shopping_list/config"
+	"shopping_list/store"
+)
+
+const maxIdempotencyKeyLen = 255
+
+// IdempotencyMiddleware makes mutating requests sent with an Idempotency-Key header safe to retry.
+// The first request with a key runs, and for config.Idempotency.Window every request of the user
+// with that key gets its response again, marked with the Idempotent-Replayed header. Reusing the key
+// for another method, path or body is answered with 422, and retrying while the first request is
+// still running with 409. Only successful responses are remembered: a request rejected for a missing
+// permission or If-Match header, or failed with a server error, runs again when retried with its key.
+// It must run after TokenAuthMiddleware.
+func IdempotencyMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" || !mutating(r.Method) {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			http.Error(w, "Idempotency-Key must be at most 255 characters", http.StatusBadRequest)
+			return
+		}
+		userID, ok := UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		request := &store.IdempotentRequest{
+			UserID:      userID,
+			Key:         key,
+			Fingerprint: fingerprint(r, body),
+			ExpiresAt:   time.Now().Add(config.Idempotency.Window),
+		}
+		existing, err := stores.Idempotency.BeginIdempotentRequest(r.Context(), request)
+		if errors.Is(err, store.ErrDuplicate) {
+			replay(w, request, existing)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error checking Idempotency-Key", http.StatusInternalServerError)
+			return
+		}
+
+		// The response is stored even if the client went away, that is when it retries
+		ctx := context.WithoutCancel(r.Context())
+		completed := false
+		defer func() {
+			if !completed {
+				if err := stores.Idempotency.DeleteIdempotentRequest(ctx, userID, key); err != nil {
+					log.Printf("error releasing idempotency key: %s", err)
+				}
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		next(recorder, r)
+
+		request.StatusCode = recorder.status
+		if request.StatusCode == 0 {
+			request.StatusCode = http.StatusOK
+		}
+		if request.StatusCode < 200 || request.StatusCode > 299 {
+			return
+		}
+		request.Header = recorder.header
+		request.Body = recorder.body.Bytes()
+		if err := stores.Idempotency.CompleteIdempotentRequest(ctx, request); err != nil {
+			log.Printf("error storing idempotent response: %s", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// replay answers a retried request with the response stored for its key
+func replay(w http.ResponseWriter, request, existing *store.IdempotentRequest) {
+	if existing.Fingerprint != request.Fingerprint {
+		http.Error(w, "Idempotency-Key was already used for a different request", http.StatusUnprocessableEntity)
+		return
+	}
+	if existing.StatusCode == 0 {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "A request with this Idempotency-Key is still in progress", http.StatusConflict)
+		return
+	}
+
+	for name, values := range existing.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(existing.StatusCode)
+	w.Write(existing.Body)
+}
+
+// mutating reports whether requests with the method change data
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// fingerprint identifies the method, URL and body of a request
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder passes a response through while keeping a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+		rec.header = rec.ResponseWriter.Header().Clone()
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
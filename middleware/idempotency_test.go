@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shopping_list/config"
+	"shopping_list/store"
+)
+
+// idempotent wraps handler in IdempotencyMiddleware for user 1 and counts its runs
+func idempotent(t *testing.T, handler http.HandlerFunc) (http.HandlerFunc, *int) {
+	t.Helper()
+	SetStores(store.NewMemory())
+	config.Idempotency.Window = time.Hour
+	runs := 0
+	next := IdempotencyMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		handler(w, r)
+	})
+	return func(w http.ResponseWriter, r *http.Request) {
+		next(w, r.WithContext(WithPrincipal(r.Context(), &Principal{UserID: 1})))
+	}, &runs
+}
+
+func send(handler http.HandlerFunc, method, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", key)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestIdempotencyReplaysSuccessfulResponses(t *testing.T) {
+	handler, runs := idempotent(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"1"`)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	})
+
+	first := send(handler, http.MethodPost, "/lists", "k1", `{"title":"groceries"}`)
+	second := send(handler, http.MethodPost, "/lists", "k1", `{"title":"groceries"}`)
+
+	if *runs != 1 {
+		t.Fatalf("handler ran %d times, want 1", *runs)
+	}
+	if first.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("first response is marked as replayed")
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != "created" {
+		t.Errorf("replay = %d %q, want 201 %q", second.Code, second.Body.String(), "created")
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("replay is not marked with Idempotent-Replayed")
+	}
+	if second.Header().Get("ETag") != `"1"` {
+		t.Errorf("replayed ETag = %q, want %q", second.Header().Get("ETag"), `"1"`)
+	}
+
+	// Keys are per request, another key runs the handler again
+	send(handler, http.MethodPost, "/lists", "k2", `{"title":"groceries"}`)
+	if *runs != 2 {
+		t.Errorf("handler ran %d times with a new key, want 2", *runs)
+	}
+}
+
+func TestIdempotencyRejectsAKeyReusedForAnotherRequest(t *testing.T) {
+	handler, runs := idempotent(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	send(handler, http.MethodPost, "/lists", "k1", `{"title":"groceries"}`)
+
+	tests := []struct {
+		name         string
+		method, path string
+		body         string
+	}{
+		{"another body", http.MethodPost, "/lists", `{"title":"hardware"}`},
+		{"another path", http.MethodPost, "/lists/1", `{"title":"groceries"}`},
+		{"another method", http.MethodPut, "/lists", `{"title":"groceries"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := send(handler, tt.method, tt.path, "k1", tt.body)
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+			}
+		})
+	}
+	if *runs != 1 {
+		t.Errorf("handler ran %d times, want 1", *runs)
+	}
+}
+
+func TestIdempotencyRejectsARetryInProgress(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler, _ := idempotent(t, func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("ok"))
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- send(handler, http.MethodPost, "/lists", "k1", "{}")
+	}()
+	<-started
+
+	rec := send(handler, http.MethodPost, "/lists", "k1", "{}")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After is not set")
+	}
+
+	close(release)
+	if first := <-done; first.Code != http.StatusOK {
+		t.Errorf("first request status = %d, want %d", first.Code, http.StatusOK)
+	}
+	if rec := send(handler, http.MethodPost, "/lists", "k1", "{}"); rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("retry after the first request finished is not replayed")
+	}
+}
+
+func TestIdempotencyDoesNotRememberFailures(t *testing.T) {
+	for _, status := range []int{
+		http.StatusForbidden,
+		http.StatusNotFound,
+		http.StatusPreconditionRequired,
+		http.StatusInternalServerError,
+	} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			fail := true
+			handler, runs := idempotent(t, func(w http.ResponseWriter, r *http.Request) {
+				if fail {
+					http.Error(w, "failed", status)
+					return
+				}
+				w.Write([]byte("ok"))
+			})
+
+			if rec := send(handler, http.MethodPost, "/lists", "k1", "{}"); rec.Code != status {
+				t.Fatalf("status = %d, want %d", rec.Code, status)
+			}
+			// The retry runs once the cause is fixed, e.g. the user got the role
+			fail = false
+			rec := send(handler, http.MethodPost, "/lists", "k1", "{}")
+			if rec.Code != http.StatusOK || rec.Header().Get("Idempotent-Replayed") != "" {
+				t.Errorf("retry = %d replayed %q, want a fresh 200", rec.Code, rec.Header().Get("Idempotent-Replayed"))
+			}
+			if *runs != 2 {
+				t.Errorf("handler ran %d times, want 2", *runs)
+			}
+		})
+	}
+}
+
+func TestIdempotencyIgnoresReads(t *testing.T) {
+	handler, runs := idempotent(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	send(handler, http.MethodGet, "/lists", "k1", "")
+	send(handler, http.MethodGet, "/lists", "k1", "")
+	if *runs != 2 {
+		t.Errorf("handler ran %d times, want 2", *runs)
+	}
+}
@@ -1,14 +1,25 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
-	"shopping_list/db"
+	"shopping_list/store"
+	"shopping_list/tokens"
 	"strings"
-
-	"github.com/dgrijalva/jwt-go"
+	"time"
 )
 
+var stores *store.Stores
+
+// SetStores injects the storage backends used by the middlewares
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
 // TokenAuthMiddleware checks if the user is logged in based on the JWT token
+// and stores the authenticated user in the request context. Mutating requests
+// with an Idempotency-Key header then go through IdempotencyMiddleware.
 func TokenAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Get the token from the Authorization header
@@ -20,64 +31,100 @@ func TokenAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		// Parse the token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate the algorithm
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, http.ErrNoLocation
-			}
-			return []byte("your_secret_key"), nil // Replace with your secret key
-		})
+		// Parse the token, validating its signature, issuer, audience and expiry
+		claims, err := tokens.Parse(tokenString)
+		if err != nil {
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			return
+		}
 
-		if err != nil || !token.Valid {
+		userID, err := claims.UserID()
+		if err != nil || claims.Id == "" {
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
 
+		// Reject tokens revoked by a logout
+		revoked, err := isTokenRevoked(r.Context(), userID, claims.Id, claims.IssuedAtTime())
+		if err != nil {
+			http.Error(w, "Error checking token", http.StatusInternalServerError)
+			return
+		}
+		if revoked {
+			http.Error(w, "Token has been revoked", http.StatusUnauthorized)
+			return
+		}
+
+		// Resolve the user once for the whole request
+		user, err := stores.Users.GetUserByID(r.Context(), userID)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		principal := &Principal{
+			UserID:    user.ID,
+			Email:     user.Email,
+			Name:      user.Name,
+			TokenID:   claims.Id,
+			SessionID: claims.SessionID,
+			IssuedAt:  claims.IssuedAtTime(),
+			ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+		}
+
 		// Call the next handler
-		next.ServeHTTP(w, r)
+		IdempotencyMiddleware(next)(w, r.WithContext(WithPrincipal(r.Context(), principal)))
 	})
 }
 
-// ExtractUserIDFromToken extracts the user ID from the JWT token
-func ExtractUserIDFromToken(r *http.Request) (int, error) {
-	tokenString := r.Header.Get("Authorization")
-	tokenString = strings.TrimPrefix(tokenString, "Bearer ")
-
-	if tokenString == "" {
-		return 0, http.ErrNoLocation
+// QueryTokenMiddleware accepts the JWT token in the access_token query parameter when there is
+// no Authorization header, for clients such as EventSource that cannot set headers
+func QueryTokenMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get("access_token"); token != "" && r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		next(w, r)
 	}
+}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte("your_secret_key"), nil // Replace with your secret key
-	})
+// TokenRevoked reports whether the token of a principal has been revoked since TokenAuthMiddleware
+// accepted it, for requests that outlive that check such as event streams
+func TokenRevoked(ctx context.Context, principal *Principal) (bool, error) {
+	return isTokenRevoked(ctx, principal.UserID, principal.TokenID, principal.IssuedAt)
+}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		if email, ok := claims["email"].(string); ok {
-			user, err := db.DB.Query("SELECT id FROM users WHERE email = ?", email)
-			if err != nil {
-				return 0, err
-			}
-			defer user.Close()
-
-			if user.Next() {
-				var id int
-				if err := user.Scan(&id); err != nil {
-					return 0, err
-				}
-				return id, nil
-			}
-		}
+// isTokenRevoked checks the token ID against the revoked tokens and the issue time
+// against the last time the user logged out of all devices
+func isTokenRevoked(ctx context.Context, userID int, jti string, issuedAt time.Time) (bool, error) {
+	revoked, err := stores.Tokens.IsAccessTokenRevoked(ctx, jti)
+	if err != nil || revoked {
+		return revoked, err
 	}
 
-	return 0, err
+	revokedAt, err := stores.Tokens.GetUserTokensRevokedAt(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return true, nil
+	}
+	if err != nil || revokedAt == nil {
+		return false, err
+	}
+	// Older tokens only carry the second they were issued. A token issued on a whole second
+	// cannot fall in the second of the cutoff after it, so comparing seconds is exact for it too.
+	if issuedAt.Truncate(time.Second).Equal(issuedAt) {
+		return !issuedAt.After(revokedAt.Truncate(time.Second)), nil
+	}
+	return !issuedAt.After(revokedAt.Truncate(time.Microsecond)), nil
 }
 
+// EnableCORS lets browser clients call every route, send the Authorization, If-Match and
+// Idempotency-Key headers, and read the ETag and Idempotent-Replayed headers of the responses
 func EnableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, PUT, PATCH, DELETE")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Accept, If-Match, Idempotency-Key")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Idempotent-Replayed")
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
 			return
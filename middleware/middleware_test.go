@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shopping_list/config"
+	"shopping_list/store"
+	"shopping_list/tokens"
+)
+
+// revokedUser injects a memory store with a user who logged out of all devices and returns the
+// user and the cutoff of the logout
+func revokedUser(t *testing.T) (*store.User, time.Time) {
+	t.Helper()
+	ctx := context.Background()
+	s := store.NewMemory()
+	SetStores(s)
+
+	user := &store.User{Email: "a@example.com", Name: "A"}
+	if err := s.Users.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %s", err)
+	}
+	if err := s.Tokens.RevokeUserTokens(ctx, user.ID); err != nil {
+		t.Fatalf("RevokeUserTokens: %s", err)
+	}
+	revokedAt, err := s.Tokens.GetUserTokensRevokedAt(ctx, user.ID)
+	if err != nil || revokedAt == nil {
+		t.Fatalf("GetUserTokensRevokedAt = %v, %v", revokedAt, err)
+	}
+	return user, *revokedAt
+}
+
+func TestIsTokenRevokedCutoff(t *testing.T) {
+	user, revokedAt := revokedUser(t)
+	cutoff := revokedAt.Truncate(time.Microsecond)
+	second := revokedAt.Truncate(time.Second)
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		want     bool
+	}{
+		{"a microsecond before", cutoff.Add(-time.Microsecond), true},
+		{"at the cutoff", cutoff, true},
+		{"a microsecond after", cutoff.Add(time.Microsecond), false},
+		// Tokens without microseconds only tell the second they were issued
+		{"in seconds, the second of the cutoff", second, true},
+		{"in seconds, the second before", second.Add(-time.Second), true},
+		{"in seconds, the second after", second.Add(time.Second), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked, err := isTokenRevoked(context.Background(), user.ID, "jti", tt.issuedAt)
+			if err != nil {
+				t.Fatalf("isTokenRevoked: %s", err)
+			}
+			if revoked != tt.want {
+				t.Errorf("isTokenRevoked(%s) = %v, want %v for the cutoff %s", tt.issuedAt.Format(time.StampMicro), revoked, tt.want, revokedAt.Format(time.StampNano))
+			}
+		})
+	}
+}
+
+func TestIsTokenRevoked(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	SetStores(s)
+	user := &store.User{Email: "a@example.com", Name: "A"}
+	if err := s.Users.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %s", err)
+	}
+	if err := s.Tokens.RevokeAccessToken(ctx, "logged-out", user.ID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("RevokeAccessToken: %s", err)
+	}
+
+	tests := []struct {
+		name   string
+		userID int
+		jti    string
+		want   bool
+	}{
+		{"never revoked", user.ID, "current", false},
+		{"logged out token", user.ID, "logged-out", true},
+		{"deleted user", user.ID + 1, "current", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked, err := isTokenRevoked(ctx, tt.userID, tt.jti, time.Now())
+			if err != nil {
+				t.Fatalf("isTokenRevoked: %s", err)
+			}
+			if revoked != tt.want {
+				t.Errorf("isTokenRevoked = %v, want %v", revoked, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenAuthMiddlewareAcceptsLoginsRightAfterTheCutoff(t *testing.T) {
+	config.JWT = config.JWTConfig{
+		Secret:   []byte(strings.Repeat("k", 32)),
+		Issuer:   "shopping_list",
+		Audience: "shopping_list",
+		Expiry:   time.Minute,
+	}
+	sign := func(user *store.User) string {
+		token, err := tokens.Sign(user.ID, user.Email, "session")
+		if err != nil {
+			t.Fatalf("Sign: %s", err)
+		}
+		return token
+	}
+	handler := TokenAuthMiddleware(func(w http.ResponseWriter, r *http.Request) {})
+	status := func(token string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w.Code
+	}
+
+	ctx := context.Background()
+	user, _ := revokedUser(t)
+	before := sign(user)
+	time.Sleep(time.Microsecond)
+	if err := stores.Tokens.RevokeUserTokens(ctx, user.ID); err != nil {
+		t.Fatalf("RevokeUserTokens: %s", err)
+	}
+	time.Sleep(time.Microsecond)
+	// Most likely in the same second as the cutoff
+	after := sign(user)
+
+	if got := status(before); got != http.StatusUnauthorized {
+		t.Errorf("token issued before logging out of all devices status = %d, want %d", got, http.StatusUnauthorized)
+	}
+	if got := status(after); got != http.StatusOK {
+		t.Errorf("token issued after logging out of all devices status = %d, want %d", got, http.StatusOK)
+	}
+}
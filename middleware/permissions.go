@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"shopping_list/store"
+)
+
+// Permission is an action a workspace role may be allowed to perform
+type Permission string
+
+const (
+	PermViewWorkspace   Permission = "view_workspace"
+	PermManageWorkspace Permission = "manage_workspace"
+	PermTransferOwner   Permission = "transfer_owner"
+	PermManageMembers   Permission = "manage_members"
+	PermEditProducts    Permission = "edit_products"
+	PermEditLists       Permission = "edit_lists"
+	PermDeleteLists     Permission = "delete_lists"
+)
+
+// rolePermissions is the permission matrix of the workspace roles
+var rolePermissions = map[store.Role]map[Permission]bool{
+	store.RoleOwner: {
+		PermViewWorkspace:   true,
+		PermManageWorkspace: true,
+		PermTransferOwner:   true,
+		PermManageMembers:   true,
+		PermEditProducts:    true,
+		PermEditLists:       true,
+		PermDeleteLists:     true,
+	},
+	store.RoleEditor: {
+		PermViewWorkspace: true,
+		PermEditProducts:  true,
+		PermEditLists:     true,
+		PermDeleteLists:   true,
+	},
+	store.RoleViewer: {
+		PermViewWorkspace: true,
+	},
+}
+
+// RoleCan reports whether the role grants the permission
+func RoleCan(role store.Role, permission Permission) bool {
+	return rolePermissions[role][permission]
+}
+
+type workspaceRoleKey struct{}
+
+// WithWorkspaceRole returns a copy of ctx carrying the role of the user in the requested workspace
+func WithWorkspaceRole(ctx context.Context, role store.Role) context.Context {
+	return context.WithValue(ctx, workspaceRoleKey{}, role)
+}
+
+// WorkspaceRoleFromContext returns the role stored by WorkspaceMiddleware
+func WorkspaceRoleFromContext(ctx context.Context) (store.Role, bool) {
+	role, ok := ctx.Value(workspaceRoleKey{}).(store.Role)
+	return role, ok
+}
+
+// Can reports whether the user's role in the requested workspace grants the permission.
+// It must run after WorkspaceMiddleware.
+func Can(ctx context.Context, permission Permission) bool {
+	role, ok := WorkspaceRoleFromContext(ctx)
+	return ok && RoleCan(role, permission)
+}
+
+// RequirePermission only calls next if the user's role in the workspace grants the permission
+func RequirePermission(permission Permission, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !Can(r.Context(), permission) {
+			http.Error(w, "You don't have permission to perform this action", http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// RequireWritePermission requires the permission for every method except GET and HEAD,
+// which only need access to the workspace
+func RequireWritePermission(permission Permission, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			RequirePermission(PermViewWorkspace, next)(w, r)
+			return
+		}
+		RequirePermission(permission, next)(w, r)
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"shopping_list/store"
+)
+
+var permissions = []Permission{
+	PermViewWorkspace,
+	PermManageWorkspace,
+	PermTransferOwner,
+	PermManageMembers,
+	PermEditProducts,
+	PermEditLists,
+	PermDeleteLists,
+}
+
+func TestRoleCan(t *testing.T) {
+	// granted lists the permissions of each role, every other permission is denied
+	granted := map[store.Role][]Permission{
+		store.RoleOwner:  permissions,
+		store.RoleEditor: {PermViewWorkspace, PermEditProducts, PermEditLists, PermDeleteLists},
+		store.RoleViewer: {PermViewWorkspace},
+		"":               nil,
+		"admin":          nil,
+	}
+	for role, rolePermissions := range granted {
+		for _, permission := range permissions {
+			want := false
+			for _, grantedPermission := range rolePermissions {
+				want = want || grantedPermission == permission
+			}
+			if got := RoleCan(role, permission); got != want {
+				t.Errorf("RoleCan(%q, %s) = %v, want %v", role, permission, got, want)
+			}
+		}
+		if RoleCan(role, "unknown") {
+			t.Errorf("RoleCan(%q, unknown) = true, want false", role)
+		}
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		method     string
+		permission Permission
+		// write uses RequireWritePermission instead of RequirePermission
+		write  bool
+		status int
+	}{
+		{"granted", WithWorkspaceRole(context.Background(), store.RoleEditor), http.MethodPost, PermEditLists, false, http.StatusOK},
+		{"denied", WithWorkspaceRole(context.Background(), store.RoleViewer), http.MethodPost, PermEditLists, false, http.StatusForbidden},
+		{"no role", context.Background(), http.MethodGet, PermViewWorkspace, false, http.StatusForbidden},
+		{"read only needs access", WithWorkspaceRole(context.Background(), store.RoleViewer), http.MethodGet, PermEditProducts, true, http.StatusOK},
+		{"write needs the permission", WithWorkspaceRole(context.Background(), store.RoleViewer), http.MethodPut, PermEditProducts, true, http.StatusForbidden},
+		{"read without access", context.Background(), http.MethodGet, PermEditProducts, true, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := func(w http.ResponseWriter, r *http.Request) {}
+			handler := RequirePermission(tt.permission, next)
+			if tt.write {
+				handler = RequireWritePermission(tt.permission, next)
+			}
+
+			w := httptest.NewRecorder()
+			handler(w, httptest.NewRequest(tt.method, "/", nil).WithContext(tt.ctx))
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
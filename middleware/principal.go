@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"context"
+	"time"
+)
+
+// Principal is the authenticated user of a request, resolved once by TokenAuthMiddleware
+type Principal struct {
+	UserID    int
+	Email     string
+	Name      string
+	TokenID   string
+	SessionID string
+	// IssuedAt is to the second for tokens issued before their issue time carried microseconds
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated user
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated user stored by TokenAuthMiddleware
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// UserIDFromContext returns the ID of the authenticated user stored by TokenAuthMiddleware
+func UserIDFromContext(ctx context.Context) (int, bool) {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	return principal.UserID, true
+}
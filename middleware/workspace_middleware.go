@@ -2,57 +2,51 @@ package middleware
 
 import (
 	"net/http"
-	"shopping_list/db"
 	"strconv"
 
 	"github.com/gorilla/mux"
 )
 
-// WorkspaceMiddleware checks if the workspace_id exists in the request
-// and verifies that the user has access to this workspace
+// WorkspaceMiddleware checks if the workspace_id exists in the request,
+// verifies that the user has access to this workspace and stores their role in the request context
 func WorkspaceMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Get workspace ID from URL parameters
 		vars := mux.Vars(r)
-		workspaceID, exists := vars["workspace_id"]
+		workspaceIDParam, exists := vars["workspace_id"]
 		if !exists {
 			http.Error(w, "Workspace ID is required", http.StatusBadRequest)
 			return
 		}
 
 		// Check if workspace exists
-		var workspaceExists int
-		err := db.DB.QueryRow("SELECT id FROM workspaces WHERE id = ? AND deleted_at IS NULL", workspaceID).Scan(&workspaceExists)
+		workspaceID, err := strconv.Atoi(workspaceIDParam)
 		if err != nil {
 			http.Error(w, "Workspace not found", http.StatusNotFound)
 			return
 		}
-
-		// Get user ID from the token
-		userID, err := ExtractUserIDFromToken(r)
+		_, err = stores.Workspaces.GetWorkspace(r.Context(), workspaceID)
 		if err != nil {
+			http.Error(w, "Workspace not found", http.StatusNotFound)
+			return
+		}
+
+		// Get the authenticated user from the request context
+		userID, ok := UserIDFromContext(r.Context())
+		if !ok {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
 		// Check if user has access to this workspace
 		// Either as the owner or as a workspace user
-		var hasAccess bool
-		err = db.DB.QueryRow(`
-			SELECT EXISTS(
-				SELECT 1 FROM workspaces 
-				WHERE id = ? AND user_id = ? AND deleted_at IS NULL
-				UNION
-				SELECT 1 FROM workspace_users 
-				WHERE workspace_id = ? AND user_id = ? AND deleted_at IS NULL
-			)`, workspaceID, userID, workspaceID, userID).Scan(&hasAccess)
-
-		if err != nil || !hasAccess {
+		role, err := stores.Workspaces.GetWorkspaceRole(r.Context(), workspaceID, userID)
+		if err != nil {
 			http.Error(w, "You don't have access to this workspace", http.StatusForbidden)
 			return
 		}
 
-		next(w, r)
+		next(w, r.WithContext(WithWorkspaceRole(r.Context(), role)))
 	}
 }
 
@@ -61,20 +55,24 @@ func ProductWorkspaceMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		workspaceID := vars["workspace_id"]
-		productID, exists := vars["id"]
+		productIDParam, exists := vars["id"]
 
 		// Only check product workspace if product ID exists in the request
-		if exists && productID != "" {
+		if exists && productIDParam != "" {
 			// Check if product exists and belongs to the specified workspace
-			var productWorkspaceID int
-			err := db.DB.QueryRow("SELECT workspace_id FROM products WHERE id = ? AND deleted_at IS NULL", productID).Scan(&productWorkspaceID)
+			productID, err := strconv.Atoi(productIDParam)
+			if err != nil {
+				http.Error(w, "Product not found", http.StatusNotFound)
+				return
+			}
+			product, err := stores.Products.GetProduct(r.Context(), productID)
 			if err != nil {
 				http.Error(w, "Product not found", http.StatusNotFound)
 				return
 			}
 
 			workspaceIDInt, _ := strconv.Atoi(workspaceID)
-			if productWorkspaceID != workspaceIDInt {
+			if product.WorkspaceID != workspaceIDInt {
 				http.Error(w, "Product does not belong to this workspace", http.StatusForbidden)
 				return
 			}
@@ -84,7 +82,13 @@ func ProductWorkspaceMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-// CombinedWorkspaceMiddleware combines both workspace and product workspace checks
+// AuthorizedWorkspaceMiddleware authenticates the user, checks their access to the workspace
+// and requires their role to grant the permission
+func AuthorizedWorkspaceMiddleware(permission Permission, next http.HandlerFunc) http.HandlerFunc {
+	return TokenAuthMiddleware(WorkspaceMiddleware(RequirePermission(permission, next)))
+}
+
+// CombinedWorkspaceMiddleware combines the workspace, product permission and product workspace checks
 func CombinedWorkspaceMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return TokenAuthMiddleware(WorkspaceMiddleware(ProductWorkspaceMiddleware(next)))
+	return TokenAuthMiddleware(WorkspaceMiddleware(RequireWritePermission(PermEditProducts, ProductWorkspaceMiddleware(next))))
 }
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"shopping_list/db"
+	"shopping_list/sql/migrations"
+)
+
+const migrateUsage = `usage: shopping_list migrate [-force] <command>
+
+commands:
+  up                 apply all pending migrations
+  down [steps]       revert the last applied migrations (default 1)
+  status             list migrations and when they were applied
+  baseline <version> mark migrations up to version as applied without running them
+`
+
+// runMigrate handles the "migrate" subcommand
+func runMigrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	force := flags.Bool("force", false, "run destructive migrations even if the database holds data")
+	flags.Usage = func() { fmt.Fprint(os.Stderr, migrateUsage) }
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	db.DbConnect()
+
+	migrator, err := db.NewMigrator(db.DB, migrations.FS)
+	if err != nil {
+		fmt.Printf("error loading migrations: %s\n", err)
+		os.Exit(1)
+	}
+	migrator.AllowDestructive = *force
+
+	ctx := context.Background()
+	switch flags.Arg(0) {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied %d %s\n", migration.Version, migration.Name)
+		}
+		exitOnMigrateError(err)
+		if len(applied) == 0 {
+			fmt.Printf("database is up to date\n")
+		}
+	case "down":
+		steps := 1
+		if flags.NArg() > 1 {
+			steps, err = strconv.Atoi(flags.Arg(1))
+			if err != nil || steps < 1 {
+				fmt.Printf("invalid number of steps: %s\n", flags.Arg(1))
+				os.Exit(2)
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %d %s\n", migration.Version, migration.Name)
+		}
+		exitOnMigrateError(err)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		exitOnMigrateError(err)
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%d %-50s %s\n", status.Version, status.Name, appliedAt)
+		}
+	case "baseline":
+		if flags.NArg() < 2 {
+			flags.Usage()
+			os.Exit(2)
+		}
+		version, err := strconv.ParseInt(flags.Arg(1), 10, 64)
+		if err != nil {
+			fmt.Printf("invalid version: %s\n", flags.Arg(1))
+			os.Exit(2)
+		}
+		marked, err := migrator.Baseline(ctx, version)
+		for _, migration := range marked {
+			fmt.Printf("marked %d %s as applied\n", migration.Version, migration.Name)
+		}
+		exitOnMigrateError(err)
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+}
+
+// autoMigrate applies pending migrations on server start when DB_AUTO_MIGRATE is enabled
+func autoMigrate() {
+	if os.Getenv("DB_AUTO_MIGRATE") != "true" {
+		return
+	}
+
+	migrator, err := db.NewMigrator(db.DB, migrations.FS)
+	if err != nil {
+		fmt.Printf("error loading migrations: %s\n", err)
+		os.Exit(1)
+	}
+
+	applied, err := migrator.Up(context.Background())
+	for _, migration := range applied {
+		fmt.Printf("applied migration %d %s\n", migration.Version, migration.Name)
+	}
+	exitOnMigrateError(err)
+}
+
+func exitOnMigrateError(err error) {
+	if err != nil {
+		fmt.Printf("migration failed: %s\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,175 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrCurrencyMismatch = errors.New("currencies do not match")
+	ErrOverflow         = errors.New("amount out of range")
+)
+
+// DefaultCurrency is used for workspaces that did not choose a currency
+const DefaultCurrency = "USD"
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// exponents lists the ISO 4217 currencies whose minor unit is not a hundredth
+var exponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// ValidCurrency reports whether code looks like an ISO 4217 currency code
+func ValidCurrency(code string) bool {
+	return currencyCode.MatchString(code)
+}
+
+// Exponent returns the number of decimals of the minor unit of a currency
+func Exponent(currency string) int {
+	if exponent, ok := exponents[currency]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// Money is an exact amount in the minor unit of its currency, such as cents.
+// It is encoded in JSON as a decimal string like "12.30".
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// New returns an amount of minor units in a currency
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Parse reads a decimal such as "12.3" into an amount of the currency. It rejects
+// values with more decimals than the currency has, instead of rounding them.
+func Parse(value, currency string) (Money, error) {
+	exponent := Exponent(currency)
+
+	s := strings.TrimSpace(value)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" || len(fraction) > exponent || !digits(whole) || !digits(fraction) {
+		return Money{}, ErrInvalidAmount
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	amount, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, ErrOverflow
+	}
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+func digits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the amount as a decimal with the number of decimals of its currency
+func (m Money) String() string {
+	exponent := Exponent(m.Currency)
+
+	var sign string
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+	}
+	// Format the absolute value without overflowing on the minimum int64
+	s := strconv.FormatUint(absolute(amount), 10)
+	if exponent == 0 {
+		return sign + s
+	}
+	if len(s) <= exponent {
+		s = strings.Repeat("0", exponent-len(s)+1) + s
+	}
+	return sign + s[:len(s)-exponent] + "." + s[len(s)-exponent:]
+}
+
+func absolute(amount int64) uint64 {
+	if amount < 0 {
+		return uint64(-(amount + 1)) + 1
+	}
+	return uint64(amount)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// Add returns the sum of two amounts of the same currency
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	sum := m.Amount + other.Amount
+	if (other.Amount > 0 && sum < m.Amount) || (other.Amount < 0 && sum > m.Amount) {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+// Mul returns the amount multiplied by a quantity
+func (m Money) Mul(quantity int64) (Money, error) {
+	product := m.Amount * quantity
+	// The division undoes the product unless it wrapped, except for the minimum negated
+	if quantity != 0 && (product/quantity != m.Amount || (quantity == -1 && m.Amount == math.MinInt64)) {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: product, Currency: m.Currency}, nil
+}
+
+// Decimal is a decimal amount received in JSON, either as a string such as "12.30"
+// or as a number. The number is kept as written so no precision is lost.
+type Decimal string
+
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		*d = ""
+		return nil
+	}
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*d = Decimal(s)
+		return nil
+	}
+
+	var number json.Number
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err := decoder.Decode(&number); err != nil {
+		return ErrInvalidAmount
+	}
+	*d = Decimal(number.String())
+	return nil
+}
+
+// Money parses the decimal as an amount of the currency
+func (d Decimal) Money(currency string) (Money, error) {
+	return Parse(string(d), currency)
+}
@@ -0,0 +1,152 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value    string
+		currency string
+		want     int64
+		err      error
+	}{
+		{"12.30", "USD", 1230, nil},
+		{"12.3", "USD", 1230, nil},
+		{"12", "USD", 1200, nil},
+		{"12.", "USD", 1200, nil},
+		{" 0.05 ", "EUR", 5, nil},
+		{"-1.50", "USD", -150, nil},
+		{"500", "JPY", 500, nil},
+		{"1.234", "KWD", 1234, nil},
+		{"12.345", "USD", 0, ErrInvalidAmount},
+		{"5.5", "JPY", 0, ErrInvalidAmount},
+		{".50", "USD", 0, ErrInvalidAmount},
+		{"", "USD", 0, ErrInvalidAmount},
+		{"-", "USD", 0, ErrInvalidAmount},
+		{"+1", "USD", 0, ErrInvalidAmount},
+		{"1e3", "USD", 0, ErrInvalidAmount},
+		{"1,50", "USD", 0, ErrInvalidAmount},
+		{"92233720368547758.07", "USD", math.MaxInt64, nil},
+		{"92233720368547758.08", "USD", 0, ErrOverflow},
+		{"92233720368547759", "USD", 0, ErrOverflow},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.value, tt.currency)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Parse(%q, %s) error = %v, want %v", tt.value, tt.currency, err, tt.err)
+			continue
+		}
+		if err == nil && (got.Amount != tt.want || got.Currency != tt.currency) {
+			t.Errorf("Parse(%q, %s) = %+v, want %d %s", tt.value, tt.currency, got, tt.want, tt.currency)
+		}
+	}
+}
+
+func TestDecimalUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		json string
+		want Decimal
+	}{
+		{`"12.30"`, "12.30"},
+		{`12.30`, "12.30"},
+		{`0.1`, "0.1"},
+		{`null`, ""},
+		{`""`, ""},
+	}
+	for _, tt := range tests {
+		var got Decimal
+		if err := json.Unmarshal([]byte(tt.json), &got); err != nil {
+			t.Errorf("Unmarshal(%s): %s", tt.json, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Unmarshal(%s) = %q, want %q", tt.json, got, tt.want)
+		}
+	}
+
+	var d Decimal
+	if err := json.Unmarshal([]byte(`true`), &d); err == nil {
+		t.Errorf("Unmarshal(true) = %q, want an error", d)
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{New(1230, "USD"), "12.30"},
+		{New(5, "USD"), "0.05"},
+		{New(0, "USD"), "0.00"},
+		{New(-150, "USD"), "-1.50"},
+		{New(500, "JPY"), "500"},
+		{New(1, "KWD"), "0.001"},
+		{New(math.MinInt64, "USD"), "-92233720368547758.08"},
+	}
+	for _, tt := range tests {
+		if got := tt.money.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.money, got, tt.want)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Money
+		want int64
+		err  error
+	}{
+		{"sum", New(120, "USD"), New(250, "USD"), 370, nil},
+		{"negative", New(120, "USD"), New(-250, "USD"), -130, nil},
+		{"largest", New(math.MaxInt64-1, "USD"), New(1, "USD"), math.MaxInt64, nil},
+		{"overflow", New(math.MaxInt64, "USD"), New(1, "USD"), 0, ErrOverflow},
+		{"underflow", New(math.MinInt64, "USD"), New(-1, "USD"), 0, ErrOverflow},
+		{"currency mismatch", New(120, "USD"), New(120, "EUR"), 0, ErrCurrencyMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.a.Add(tt.b)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Add error = %v, want %v", err, tt.err)
+			}
+			if err == nil && got.Amount != tt.want {
+				t.Errorf("Add = %d, want %d", got.Amount, tt.want)
+			}
+		})
+	}
+}
+
+func TestMul(t *testing.T) {
+	tests := []struct {
+		name     string
+		money    Money
+		quantity int64
+		want     int64
+		err      error
+	}{
+		{"product", New(120, "USD"), 3, 360, nil},
+		{"zero", New(math.MaxInt64, "USD"), 0, 0, nil},
+		{"negative quantity", New(120, "USD"), -2, -240, nil},
+		{"largest", New(math.MaxInt64/2, "USD"), 2, math.MaxInt64 - 1, nil},
+		{"overflow", New(math.MaxInt64/2+1, "USD"), 2, 0, ErrOverflow},
+		{"underflow", New(math.MinInt64/2-1, "USD"), 2, 0, ErrOverflow},
+		{"negated minimum", New(math.MinInt64, "USD"), -1, 0, ErrOverflow},
+		{"minimum quantity", New(-1, "USD"), math.MinInt64, 0, ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.money.Mul(tt.quantity)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Mul error = %v, want %v", err, tt.err)
+			}
+			if err == nil && (got.Amount != tt.want || got.Currency != tt.money.Currency) {
+				t.Errorf("Mul = %+v, want %d", got, tt.want)
+			}
+		})
+	}
+}
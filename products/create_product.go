@@ -2,24 +2,38 @@ package products
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
-	"shopping_list/db"
+	"shopping_list/etag"
+	"shopping_list/events"
+	"shopping_list/money"
+	"shopping_list/store"
+	"strconv"
+
+	"github.com/gorilla/mux"
 )
 
 type requestData struct {
-	Title       string  `json:"title"`
-	AmountType  string  `json:"amount_type"`
-	Price       float32 `json:"price"`
-	WorkspaceID int     `json:"workspace_id"`
+	Title      string `json:"title"`
+	AmountType string `json:"amount_type"`
+	// Price is a decimal string such as "12.30" in the workspace currency, numbers are accepted too
+	Price money.Decimal `json:"price"`
+	// WorkspaceID is optional, the workspace is the one in the URL
+	WorkspaceID int  `json:"workspace_id"`
+	CategoryID  *int `json:"category_id"`
+	// StoreID is the optional store where the price was seen
+	StoreID *int `json:"store_id"`
 }
 
 type ProductStruct struct {
-	Id          int     `json:"id"`
-	Title       string  `json:"title"`
-	AmountType  string  `json:"amount_type"`
-	Price       float32 `json:"price"`
-	WorkspaceID int     `json:"workspace_id"`
+	Id          int         `json:"id"`
+	Title       string      `json:"title"`
+	AmountType  string      `json:"amount_type"`
+	Price       money.Money `json:"price"`
+	WorkspaceID int         `json:"workspace_id"`
+	CategoryID  *int        `json:"category_id"`
+	Version     int         `json:"version"`
 }
 
 type defaultResponse struct {
@@ -49,45 +63,69 @@ func CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if workspace_id exists in the request body
-	if data.WorkspaceID == 0 {
-		http.Error(w, "Workspace ID is required", http.StatusBadRequest)
+	// The product is created in the workspace of the URL, whose permissions the middleware checked
+	workspaceID, err := strconv.Atoi(mux.Vars(r)["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+	if data.WorkspaceID != 0 && data.WorkspaceID != workspaceID {
+		http.Error(w, "workspace_id does not match the workspace in the URL", http.StatusBadRequest)
 		return
 	}
 
-	var workspaceExists bool
-	err = db.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM workspaces WHERE id = ? AND deleted_at IS NULL)", data.WorkspaceID).Scan(&workspaceExists)
+	workspace, err := stores.Workspaces.GetWorkspace(r.Context(), workspaceID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Workspace does not exist", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to verify workspace existence", http.StatusInternalServerError)
 		return
 	}
-	if !workspaceExists {
-		http.Error(w, "Workspace does not exist", http.StatusBadRequest)
+
+	price, ok := parsePrice(w, data.Price, workspace.Currency)
+	if !ok {
+		return
+	}
+	if !checkCategory(w, r, data.CategoryID, workspaceID) {
+		return
+	}
+	source, ok := priceSource(w, r, data.StoreID, workspaceID)
+	if !ok {
 		return
 	}
 
-	query := `INSERT INTO products (title, amount_type, price, workspace_id) VALUES (?, ?, ?, ?)`
-	result, queryErr := db.DB.Exec(query, data.Title, data.AmountType, data.Price, data.WorkspaceID)
-	if queryErr != nil {
+	product := store.Product{
+		Title:       data.Title,
+		AmountType:  data.AmountType,
+		Price:       price,
+		WorkspaceID: workspaceID,
+		CategoryID:  data.CategoryID,
+	}
+	if err := stores.Products.CreateProduct(r.Context(), &product, source); err != nil {
 		http.Error(w, "Failed to create the product", http.StatusBadRequest)
 		return
 	}
 
-	lastInsertID, _ := result.LastInsertId()
+	publish(r, events.Event{Type: events.ProductCreated, WorkspaceID: product.WorkspaceID, ProductID: product.ID, Data: product})
 
 	// Create a response struct with data
 	response := productResponse{
 		Data: ProductStruct{
-			Id:          int(lastInsertID),
+			Id:          product.ID,
 			Title:       data.Title,
 			AmountType:  data.AmountType,
-			Price:       data.Price,
-			WorkspaceID: data.WorkspaceID,
+			Price:       price,
+			WorkspaceID: workspaceID,
+			CategoryID:  data.CategoryID,
+			Version:     product.Version,
 		},
 		Status: "Success",
 	}
 
 	// Set the response header to indicate the content is JSON
+	etag.Set(w, product.Version)
 	w.Header().Set("Content-Type", "application/json")
 
 	// Set the HTTP status code (optional)
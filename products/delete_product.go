@@ -2,25 +2,43 @@ package products
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"time"
+	"strconv"
 
-	"shopping_list/db"
+	"shopping_list/etag"
+	"shopping_list/events"
+	"shopping_list/store"
 
 	"github.com/gorilla/mux"
 )
 
+// DeleteProduct soft deletes a product. Like UpdateProduct it requires the If-Match header.
 func DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	id := vars["id"]
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	version, ok := etag.IfMatch(w, r)
+	if !ok {
+		return
+	}
 
-	query := `UPDATE products SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`
-	_, err := db.DB.Exec(query, time.Now(), id)
+	err = stores.Products.DeleteProduct(r.Context(), id, version)
+	if errors.Is(err, store.ErrVersionConflict) {
+		writeConflict(w, r, id)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to delete the product", http.StatusBadRequest)
 		return
 	}
 
+	workspaceID, _ := strconv.Atoi(vars["workspace_id"])
+	publish(r, events.Event{Type: events.ProductDeleted, WorkspaceID: workspaceID, ProductID: id})
+
 	// Create a response struct with data
 	response := defaultResponse{
 		Data:   "Product successfully deleted",
@@ -0,0 +1,24 @@
+package products
+
+import (
+	"log"
+	"net/http"
+
+	"shopping_list/events"
+	"shopping_list/middleware"
+)
+
+var broker events.Broker
+
+// SetBroker injects the broker notified of the changes to products
+func SetBroker(b events.Broker) {
+	broker = b
+}
+
+// publish notifies the subscribers of the workspace of a change made by the request
+func publish(r *http.Request, event events.Event) {
+	event.UserID, _ = middleware.UserIDFromContext(r.Context())
+	if err := broker.Publish(r.Context(), event); err != nil {
+		log.Printf("error publishing %s event: %s", event.Type, err)
+	}
+}
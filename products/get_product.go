@@ -0,0 +1,60 @@
+package products
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"shopping_list/etag"
+
+	"github.com/gorilla/mux"
+)
+
+type getProductResponse struct {
+	Status string
+	Data   Product
+}
+
+// GetProduct returns a product with its version in the ETag header, to be sent back in
+// If-Match when updating or deleting it
+func GetProduct(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	product, err := stores.Products.GetProduct(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
+	writeProduct(w, http.StatusOK, product)
+}
+
+// writeConflict answers a write made against an outdated version with 412 and the current
+// product, so the client can merge its changes and retry
+func writeConflict(w http.ResponseWriter, r *http.Request, id int) {
+	product, err := stores.Products.GetProduct(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
+	writeProduct(w, http.StatusPreconditionFailed, product)
+}
+
+func writeProduct(w http.ResponseWriter, status int, product *Product) {
+	response := getProductResponse{
+		Data:   *product,
+		Status: "Success",
+	}
+	if status == http.StatusPreconditionFailed {
+		response.Status = "The product was changed by someone else"
+	}
+
+	etag.Set(w, product.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
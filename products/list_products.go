@@ -2,48 +2,73 @@ package products
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
-	"shopping_list/db"
+	"net/url"
+	"strconv"
+
+	"shopping_list/money"
+	"shopping_list/store"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
 )
 
 type getResponse struct {
-	Status string
-	Data   []Product
+	Status     string
+	Data       []Product
+	Total      int
+	NextCursor string
 }
 
+// ListProducts returns a page of the workspace products. It supports the name, amount_type,
+// min_price and max_price filters, sort=title|price|created with order=asc|desc, and
+// cursor/limit pagination.
 func ListProducts(w http.ResponseWriter, r *http.Request) {
-	var products []Product
-
-	name := "%" + r.URL.Query().Get("name") + "%"
-
-	rows, err := db.DB.Query("SELECT * FROM products WHERE deleted_at IS NULL AND title LIKE ?", name)
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
 
+	// Price bounds are given in the workspace currency
+	workspace, err := stores.Workspaces.GetWorkspace(r.Context(), workspaceID)
 	if err != nil {
 		http.Error(w, "Failed to query", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-	// Loop through rows, using Scan to assign column data to struct fields.
-	for rows.Next() {
-		var product Product
-		if err := rows.Scan(&product.ID, &product.Title, &product.AmoutType, &product.Price, &product.DeletedAt, &product.WorkspaceID); err != nil {
-			fmt.Print(err)
-			http.Error(w, "Failed to create product list 1", http.StatusInternalServerError)
-			return
-		}
 
-		products = append(products, product)
+	filter, err := parseProductFilter(r.URL.Query(), workspace.Currency)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.WorkspaceID = workspaceID
+
+	page, err := stores.Products.ListProducts(r.Context(), filter)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
 	}
-	if err := rows.Err(); err != nil {
-		http.Error(w, "Failed to create product list 2", http.StatusInternalServerError)
+	if err != nil {
+		http.Error(w, "Failed to query", http.StatusInternalServerError)
 		return
 	}
 
 	// Create a response struct with data
 	response := getResponse{
-		Data:   products,
-		Status: "Success",
+		Data:       page.Products,
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
+		Status:     "Success",
+	}
+	if response.Data == nil {
+		response.Data = []Product{}
 	}
 
 	// Set the response header to indicate the content is JSON
@@ -58,3 +83,56 @@ func ListProducts(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
 }
+
+// parseProductFilter reads the filter, sorting and pagination query parameters
+func parseProductFilter(query url.Values, currency string) (store.ProductFilter, error) {
+	filter := store.ProductFilter{
+		Name:       query.Get("name"),
+		AmountType: query.Get("amount_type"),
+		Sort:       store.ProductSortTitle,
+		After:      query.Get("cursor"),
+		Limit:      defaultPageSize,
+	}
+
+	for _, bound := range []struct {
+		param string
+		value **int64
+	}{{"min_price", &filter.MinPrice}, {"max_price", &filter.MaxPrice}} {
+		if raw := query.Get(bound.param); raw != "" {
+			price, err := money.Parse(raw, currency)
+			if err != nil || price.Amount < 0 {
+				return filter, errors.New("Invalid " + bound.param)
+			}
+			*bound.value = &price.Amount
+		}
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return filter, errors.New("Invalid price range, min_price is greater than max_price")
+	}
+
+	switch sort := store.ProductSort(query.Get("sort")); sort {
+	case "":
+	case store.ProductSortTitle, store.ProductSortPrice, store.ProductSortCreated:
+		filter.Sort = sort
+	default:
+		return filter, errors.New("Invalid sort, expected title, price or created")
+	}
+
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return filter, errors.New("Invalid order, expected asc or desc")
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return filter, errors.New("Invalid limit, expected 1 to " + strconv.Itoa(maxPageSize))
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
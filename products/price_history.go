@@ -0,0 +1,111 @@
+package products
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"shopping_list/store"
+
+	"github.com/gorilla/mux"
+)
+
+// PriceHistory is the price series of a product with its statistics
+type PriceHistory struct {
+	ProductID int                `json:"product_id"`
+	Currency  string             `json:"currency"`
+	Points    []store.PricePoint `json:"points"`
+	Stats     store.PriceStats   `json:"stats"`
+}
+
+type priceHistoryResponse struct {
+	Status string
+	Data   PriceHistory
+}
+
+// GetPriceHistory returns the recorded prices of a product, oldest first, with their minimum, maximum
+// and average. The series can be narrowed down with store_id and a from/to range of dates or RFC 3339 times.
+// Both dates are included, so from=2026-10-01&to=2026-10-31 is the whole of October, while a to time
+// is exclusive.
+func GetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	filter := store.PriceHistoryFilter{ProductID: id}
+	query := r.URL.Query()
+	if raw := query.Get("store_id"); raw != "" {
+		shopID, err := strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "Invalid store ID", http.StatusBadRequest)
+			return
+		}
+		filter.ShopID = &shopID
+	}
+	for _, bound := range []struct {
+		param string
+		value **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if raw := query.Get(bound.param); raw != "" {
+			t, date, err := parseTime(raw)
+			if err != nil {
+				http.Error(w, "Invalid "+bound.param+", expected a date or an RFC 3339 time", http.StatusBadRequest)
+				return
+			}
+			// The store excludes the upper bound, which has to be the end of a to date
+			if date && bound.param == "to" {
+				t = t.AddDate(0, 0, 1)
+			}
+			*bound.value = &t
+		}
+	}
+
+	points, err := stores.Products.ListPriceHistory(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Failed to query", http.StatusInternalServerError)
+		return
+	}
+	stats, err := stores.Products.GetPriceStats(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Failed to query", http.StatusInternalServerError)
+		return
+	}
+	if points == nil {
+		points = []store.PricePoint{}
+	}
+
+	// Create a response struct with data
+	response := priceHistoryResponse{
+		Data: PriceHistory{
+			ProductID: id,
+			Currency:  stats.Avg.Currency,
+			Points:    points,
+			Stats:     *stats,
+		},
+		Status: "Success",
+	}
+
+	// Set the response header to indicate the content is JSON
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Encode the struct into JSON and write it to the response
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		// If encoding fails, return an error message
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// parseTime accepts a date such as 2026-01-31, returned as its start in UTC, or an RFC 3339 time.
+// It reports whether the value was a date.
+func parseTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
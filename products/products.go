@@ -1,17 +1,100 @@
 package products
 
 import (
+	"context"
+	"errors"
 	"net/http"
-	"time"
+	"shopping_list/middleware"
+	"shopping_list/money"
+	"shopping_list/store"
 )
 
-type Product struct {
-	ID          int        `json:"id"`
-	Title       string     `json:"title"`
-	AmoutType   string     `json:"amount_type"`
-	Price       float32    `json:"price"`
-	DeletedAt   *time.Time `json:"deleted_at"`
-	WorkspaceID int        `json:"workspace_id"`
+type Product = store.Product
+
+var stores *store.Stores
+
+// SetStores injects the storage backends used by the product handlers
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
+// CheckCategory returns an error if the optional category does not belong to the workspace.
+// The product handlers and sync both validate categories with it.
+func CheckCategory(ctx context.Context, categoryID *int, workspaceID int) error {
+	if categoryID == nil {
+		return nil
+	}
+
+	category, err := stores.Categories.GetCategory(ctx, *categoryID)
+	if err != nil || category.WorkspaceID != workspaceID {
+		return errors.New("Category not found in this workspace")
+	}
+	return nil
+}
+
+// ParsePrice reads a price in the workspace currency, returning an error if it is negative
+// or has more decimals than the currency. A missing price is zero.
+func ParsePrice(value money.Decimal, currency string) (money.Money, error) {
+	if value == "" {
+		return money.New(0, currency), nil
+	}
+
+	price, err := value.Money(currency)
+	if err != nil || price.Amount < 0 {
+		return price, errors.New("Invalid price, expected a non-negative amount in " + currency)
+	}
+	return price, nil
+}
+
+// PriceSource returns who is setting the price and at which optional store, returning an
+// error if the store does not belong to the workspace
+func PriceSource(ctx context.Context, userID *int, shopID *int, workspaceID int) (store.PriceSource, error) {
+	source := store.PriceSource{UserID: userID}
+	if shopID != nil {
+		shop, err := stores.Shops.GetShop(ctx, *shopID)
+		if err != nil || shop.WorkspaceID != workspaceID {
+			return source, errors.New("Store not found in this workspace")
+		}
+		source.ShopID = shopID
+	}
+	return source, nil
+}
+
+// checkCategory verifies that the optional category belongs to the workspace,
+// writing the error response if it does not
+func checkCategory(w http.ResponseWriter, r *http.Request, categoryID *int, workspaceID int) bool {
+	if err := CheckCategory(r.Context(), categoryID, workspaceID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// parsePrice reads a price in the workspace currency, writing the error response if it is
+// negative or has more decimals than the currency. A missing price is zero.
+func parsePrice(w http.ResponseWriter, value money.Decimal, currency string) (money.Money, bool) {
+	price, err := ParsePrice(value, currency)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return price, false
+	}
+	return price, true
+}
+
+// priceSource returns who is setting the price and at which optional store, writing the
+// error response if the store does not belong to the workspace
+func priceSource(w http.ResponseWriter, r *http.Request, shopID *int, workspaceID int) (store.PriceSource, bool) {
+	var userID *int
+	if id, ok := middleware.UserIDFromContext(r.Context()); ok {
+		userID = &id
+	}
+
+	source, err := PriceSource(r.Context(), userID, shopID, workspaceID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return source, false
+	}
+	return source, true
 }
 
 func ProductsHandler(w http.ResponseWriter, r *http.Request) {
@@ -27,6 +110,8 @@ func ProductsHandler(w http.ResponseWriter, r *http.Request) {
 
 func ProductHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
+	case http.MethodGet:
+		GetProduct(w, r)
 	case http.MethodDelete:
 		DeleteProduct(w, r)
 	case http.MethodPatch:
@@ -2,17 +2,31 @@ package products
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"strconv"
 
-	"shopping_list/db"
+	"shopping_list/etag"
+	"shopping_list/events"
+	"shopping_list/store"
 
 	"github.com/gorilla/mux"
 )
 
+// UpdateProduct replaces a product. The If-Match header must hold the ETag of the version
+// being replaced, a product changed since is answered with 412 and its current version.
 func UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	id := vars["id"]
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	version, ok := etag.IfMatch(w, r)
+	if !ok {
+		return
+	}
 
 	// Read request body
 	body, err := io.ReadAll(r.Body)
@@ -29,13 +43,46 @@ func UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := `UPDATE products SET title = ?, amount_type = ?, price = ? WHERE id = ?`
-	_, queryErr := db.DB.Exec(query, data.Title, data.AmountType, data.Price, id)
-	if queryErr != nil {
+	workspaceID, _ := strconv.Atoi(vars["workspace_id"])
+	workspace, err := stores.Workspaces.GetWorkspace(r.Context(), workspaceID)
+	if err != nil {
+		http.Error(w, "Failed to verify workspace existence", http.StatusInternalServerError)
+		return
+	}
+	price, ok := parsePrice(w, data.Price, workspace.Currency)
+	if !ok {
+		return
+	}
+	if !checkCategory(w, r, data.CategoryID, workspaceID) {
+		return
+	}
+	source, ok := priceSource(w, r, data.StoreID, workspaceID)
+	if !ok {
+		return
+	}
+
+	product := store.Product{
+		ID:         id,
+		Title:      data.Title,
+		AmountType: data.AmountType,
+		Price:      price,
+		CategoryID: data.CategoryID,
+		Version:    version,
+	}
+	err = stores.Products.UpdateProduct(r.Context(), &product, source)
+	if errors.Is(err, store.ErrVersionConflict) {
+		writeConflict(w, r, id)
+		return
+	}
+	if err != nil {
 		http.Error(w, "Failed to update the product", http.StatusBadRequest)
 		return
 	}
 
+	if updated, err := stores.Products.GetProduct(r.Context(), id); err == nil {
+		publish(r, events.Event{Type: events.ProductUpdated, WorkspaceID: workspaceID, ProductID: id, Data: updated})
+	}
+
 	// Create a response struct with data
 	response := defaultResponse{
 		Data:   "Product successfully updated",
@@ -43,6 +90,7 @@ func UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Set the response header to indicate the content is JSON
+	etag.Set(w, product.Version)
 	w.Header().Set("Content-Type", "application/json")
 
 	// Set the HTTP status code (optional)
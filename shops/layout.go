@@ -0,0 +1,92 @@
+package shops
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"shopping_list/store"
+)
+
+// LayoutAisle represents a category in the walking order of a store
+type LayoutAisle struct {
+	CategoryID int    `json:"category_id"`
+	Aisle      string `json:"aisle,omitempty"`
+}
+
+// LayoutRequest represents the request body for replacing the layout of a store.
+// Categories are listed in walking order, categories left out are visited last.
+type LayoutRequest struct {
+	Aisles []LayoutAisle `json:"aisles"`
+}
+
+// GetShopLayout returns the categories of a store in walking order
+func GetShopLayout(w http.ResponseWriter, r *http.Request) {
+	shop, ok := shopFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	aisles, err := stores.Shops.GetShopLayout(r.Context(), shop.ID)
+	if err != nil {
+		http.Error(w, "Error fetching store layout", http.StatusInternalServerError)
+		return
+	}
+	if aisles == nil {
+		aisles = []store.ShopAisle{}
+	}
+
+	response := defaultResponse{
+		Data:   aisles,
+		Status: "Success",
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// SetShopLayout replaces the walking order of the categories in a store
+func SetShopLayout(w http.ResponseWriter, r *http.Request) {
+	shop, ok := shopFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req LayoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	// Verify all categories belong to the workspace and appear once
+	aisles := make([]store.ShopAisle, 0, len(req.Aisles))
+	seen := make(map[int]bool, len(req.Aisles))
+	for i, aisle := range req.Aisles {
+		if seen[aisle.CategoryID] {
+			http.Error(w, "Category listed more than once: "+strconv.Itoa(aisle.CategoryID), http.StatusBadRequest)
+			return
+		}
+		seen[aisle.CategoryID] = true
+
+		category, err := stores.Categories.GetCategory(r.Context(), aisle.CategoryID)
+		if err != nil || category.WorkspaceID != shop.WorkspaceID {
+			http.Error(w, "Category not found: "+strconv.Itoa(aisle.CategoryID), http.StatusBadRequest)
+			return
+		}
+		aisles = append(aisles, store.ShopAisle{CategoryID: aisle.CategoryID, Position: i, Aisle: strings.TrimSpace(aisle.Aisle)})
+	}
+
+	if err := stores.Shops.SetShopLayout(r.Context(), shop.ID, aisles); err != nil {
+		http.Error(w, "Error updating store layout", http.StatusInternalServerError)
+		return
+	}
+
+	response := defaultResponse{
+		Data:   aisles,
+		Status: "Success",
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,189 @@
+package shops
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"shopping_list/store"
+
+	"github.com/gorilla/mux"
+)
+
+type Shop = store.Shop
+
+var stores *store.Stores
+
+// SetStores injects the storage backends used by the store handlers
+func SetStores(s *store.Stores) {
+	stores = s
+}
+
+type defaultResponse struct {
+	Status string      `json:"status"`
+	Data   interface{} `json:"data"`
+}
+
+// ShopRequest represents the request body for creating or renaming a store
+type ShopRequest struct {
+	Name string `json:"name"`
+}
+
+// ListShops returns the stores of the workspace
+func ListShops(w http.ResponseWriter, r *http.Request) {
+	// Get workspace ID from URL parameters
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	shops, err := stores.Shops.ListShops(r.Context(), workspaceID)
+	if err != nil {
+		http.Error(w, "Error fetching stores", http.StatusInternalServerError)
+		return
+	}
+	if shops == nil {
+		shops = []Shop{}
+	}
+
+	response := defaultResponse{
+		Data:   shops,
+		Status: "Success",
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateShop adds a store to the workspace
+func CreateShop(w http.ResponseWriter, r *http.Request) {
+	// Get workspace ID from URL parameters
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ShopRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	shop := Shop{WorkspaceID: workspaceID, Name: name}
+	if err := stores.Shops.CreateShop(r.Context(), &shop); err != nil {
+		http.Error(w, "Error creating store", http.StatusInternalServerError)
+		return
+	}
+
+	response := defaultResponse{
+		Data:   shop,
+		Status: "Success",
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetShop returns a store of the workspace
+func GetShop(w http.ResponseWriter, r *http.Request) {
+	shop, ok := shopFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	response := defaultResponse{
+		Data:   shop,
+		Status: "Success",
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateShop renames a store
+func UpdateShop(w http.ResponseWriter, r *http.Request) {
+	shop, ok := shopFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req ShopRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	shop.Name = name
+	if err := stores.Shops.UpdateShop(r.Context(), shop); err != nil {
+		http.Error(w, "Error updating store", http.StatusInternalServerError)
+		return
+	}
+
+	response := defaultResponse{
+		Data:   shop,
+		Status: "Success",
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteShop removes a store and its layout from the workspace
+func DeleteShop(w http.ResponseWriter, r *http.Request) {
+	shop, ok := shopFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := stores.Shops.DeleteShop(r.Context(), shop.ID); err != nil {
+		http.Error(w, "Error deleting store", http.StatusInternalServerError)
+		return
+	}
+
+	response := defaultResponse{
+		Data:   "Store successfully deleted",
+		Status: "Success",
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// shopFromRequest loads the store of the URL and checks it belongs to the workspace,
+// writing the error response if it does not
+func shopFromRequest(w http.ResponseWriter, r *http.Request) (*Shop, bool) {
+	// Get workspace ID and store ID from URL parameters
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return nil, false
+	}
+	shopID, err := strconv.Atoi(vars["store_id"])
+	if err != nil {
+		http.Error(w, "Invalid store ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	shop, err := stores.Shops.GetShop(r.Context(), shopID)
+	if err != nil || shop.WorkspaceID != workspaceID {
+		http.Error(w, "Store not found", http.StatusNotFound)
+		return nil, false
+	}
+	return shop, true
+}
@@ -0,0 +1,3 @@
+ALTER TABLE products
+DROP FOREIGN KEY fk_workspace,
+DROP COLUMN workspace_id;
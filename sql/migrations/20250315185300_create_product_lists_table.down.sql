@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS list_products;
+DROP TABLE IF EXISTS lists;
@@ -0,0 +1,2 @@
+ALTER TABLE users
+MODIFY COLUMN token VARCHAR(255) DEFAULT NULL;
@@ -0,0 +1,3 @@
+-- Access tokens carry issuer, audience and session claims and no longer fit in 255 characters
+ALTER TABLE users
+MODIFY COLUMN token VARCHAR(1024) DEFAULT NULL;
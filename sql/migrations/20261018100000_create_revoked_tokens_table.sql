@@ -0,0 +1,9 @@
+-- Revoked access tokens, kept until the token would have expired anyway
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti CHAR(32) NOT NULL PRIMARY KEY,
+    user_id INT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_revoked_tokens_expires (expires_at),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
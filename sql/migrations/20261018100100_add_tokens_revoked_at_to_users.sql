@@ -0,0 +1,3 @@
+-- Every access token issued before this instant is rejected ("log out all devices")
+ALTER TABLE users
+ADD COLUMN tokens_revoked_at TIMESTAMP NULL DEFAULT NULL AFTER token;
@@ -0,0 +1,2 @@
+ALTER TABLE workspace_users
+DROP COLUMN role;
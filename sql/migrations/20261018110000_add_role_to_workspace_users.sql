@@ -0,0 +1,3 @@
+-- Members added before roles existed keep full editing rights
+ALTER TABLE workspace_users
+ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'editor' AFTER workspace_id;
@@ -0,0 +1,4 @@
+ALTER TABLE products
+DROP INDEX idx_products_workspace,
+DROP COLUMN created_at,
+DROP COLUMN updated_at;
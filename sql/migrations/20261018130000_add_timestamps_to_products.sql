@@ -0,0 +1,5 @@
+-- Existing products get the time of the migration as their creation time
+ALTER TABLE products
+ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+ADD INDEX idx_products_workspace (workspace_id, deleted_at);
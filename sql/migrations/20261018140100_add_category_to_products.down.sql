@@ -0,0 +1,3 @@
+ALTER TABLE products
+DROP FOREIGN KEY fk_products_category,
+DROP COLUMN category_id;
@@ -0,0 +1,3 @@
+ALTER TABLE products
+ADD COLUMN category_id INT NULL DEFAULT NULL,
+ADD CONSTRAINT fk_products_category FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS shop_aisles;
+DROP TABLE IF EXISTS shops;
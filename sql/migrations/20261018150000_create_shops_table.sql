@@ -0,0 +1,22 @@
+-- Shops are the supermarkets of a workspace, exposed as "stores" by the API
+CREATE TABLE IF NOT EXISTS shops (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    workspace_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    INDEX idx_shops_workspace (workspace_id, deleted_at),
+    FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE
+);
+
+-- The walking order of the categories in a shop, with an optional aisle label
+CREATE TABLE IF NOT EXISTS shop_aisles (
+    shop_id INT NOT NULL,
+    category_id INT NOT NULL,
+    position INT NOT NULL,
+    aisle VARCHAR(50) NOT NULL DEFAULT '',
+    PRIMARY KEY (shop_id, category_id),
+    FOREIGN KEY (shop_id) REFERENCES shops(id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS price_history;
+
+-- Fails if a product costs more than 999.99
+ALTER TABLE products
+MODIFY COLUMN price DECIMAL(5,2) NOT NULL;
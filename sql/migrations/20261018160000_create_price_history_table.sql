@@ -0,0 +1,21 @@
+-- DECIMAL(5,2) capped prices at 999.99
+ALTER TABLE products
+MODIFY COLUMN price DECIMAL(12,2) NOT NULL;
+
+CREATE TABLE IF NOT EXISTS price_history (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    product_id INT NOT NULL,
+    shop_id INT NULL DEFAULT NULL,
+    user_id INT NULL DEFAULT NULL,
+    price DECIMAL(12,2) NOT NULL,
+    recorded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_price_history_product (product_id, recorded_at),
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+    FOREIGN KEY (shop_id) REFERENCES shops(id) ON DELETE SET NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Start the history of existing products with their current price, once
+INSERT INTO price_history (product_id, price, recorded_at)
+SELECT id, price, created_at FROM products
+WHERE deleted_at IS NULL AND NOT EXISTS (SELECT 1 FROM price_history WHERE price_history.product_id = products.id);
@@ -0,0 +1,7 @@
+ALTER TABLE price_history
+MODIFY COLUMN price DECIMAL(12,2) NOT NULL;
+ALTER TABLE products
+MODIFY COLUMN price DECIMAL(12,2) NOT NULL;
+
+ALTER TABLE workspaces
+DROP COLUMN currency;
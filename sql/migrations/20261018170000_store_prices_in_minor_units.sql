@@ -0,0 +1,16 @@
+-- Prices become integer amounts in the minor unit of the workspace currency, such as cents.
+-- Existing prices were stored with two decimals, which matches the default currency.
+--
+-- MySQL commits every ALTER TABLE on its own, so the conversion is split in three migrations that
+-- are safe to run again after a failure: this one only has statements that can be repeated, the
+-- multiplication in 20261018171000 has no DDL and commits with its schema_migrations row, and
+-- 20261018172000 changes the columns to integers.
+
+-- Widen first so multiplying by 100 cannot overflow
+ALTER TABLE products
+MODIFY COLUMN price DECIMAL(14,2) NOT NULL;
+ALTER TABLE price_history
+MODIFY COLUMN price DECIMAL(14,2) NOT NULL;
+
+ALTER TABLE workspaces
+ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
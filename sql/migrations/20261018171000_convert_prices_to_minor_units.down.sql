@@ -0,0 +1,7 @@
+UPDATE price_history SET price = price / 100
+WHERE (SELECT DATA_TYPE FROM information_schema.columns
+       WHERE table_schema = DATABASE() AND table_name = 'price_history' AND column_name = 'price') = 'decimal';
+
+UPDATE products SET price = price / 100
+WHERE (SELECT DATA_TYPE FROM information_schema.columns
+       WHERE table_schema = DATABASE() AND table_name = 'products' AND column_name = 'price') = 'decimal';
@@ -0,0 +1,9 @@
+-- Prices in minor units, see 20261018170000. There is no DDL here, so both updates commit together
+-- with the record of this migration. A column that is already an integer has been converted.
+UPDATE products SET price = price * 100
+WHERE (SELECT DATA_TYPE FROM information_schema.columns
+       WHERE table_schema = DATABASE() AND table_name = 'products' AND column_name = 'price') = 'decimal';
+
+UPDATE price_history SET price = price * 100
+WHERE (SELECT DATA_TYPE FROM information_schema.columns
+       WHERE table_schema = DATABASE() AND table_name = 'price_history' AND column_name = 'price') = 'decimal';
@@ -0,0 +1,4 @@
+ALTER TABLE price_history
+MODIFY COLUMN price DECIMAL(14,2) NOT NULL;
+ALTER TABLE products
+MODIFY COLUMN price DECIMAL(14,2) NOT NULL;
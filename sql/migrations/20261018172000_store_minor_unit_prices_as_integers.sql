@@ -0,0 +1,5 @@
+-- Prices in minor units, see 20261018170000. Both statements can be run again.
+ALTER TABLE products
+MODIFY COLUMN price BIGINT NOT NULL;
+ALTER TABLE price_history
+MODIFY COLUMN price BIGINT NOT NULL;
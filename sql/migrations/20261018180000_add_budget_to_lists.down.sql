@@ -0,0 +1,4 @@
+ALTER TABLE lists
+DROP INDEX idx_lists_completed,
+DROP COLUMN budget,
+DROP COLUMN completed_at;
@@ -0,0 +1,6 @@
+-- The budget is in the minor unit of the workspace currency, NULL when the list has none.
+-- completed_at places completed lists in the monthly spending summary.
+ALTER TABLE lists
+ADD COLUMN budget BIGINT NULL DEFAULT NULL,
+ADD COLUMN completed_at TIMESTAMP NULL DEFAULT NULL,
+ADD INDEX idx_lists_completed (workspace_id, status, completed_at);
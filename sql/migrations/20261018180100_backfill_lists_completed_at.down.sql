@@ -0,0 +1 @@
+-- Nothing to revert, reverting 20261018180000 drops the column
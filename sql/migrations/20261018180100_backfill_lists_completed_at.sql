@@ -0,0 +1,2 @@
+-- Lists completed before 20261018180000 were completed at their last update, which must not change
+UPDATE lists SET completed_at = updated_at, updated_at = updated_at WHERE status = 2 AND completed_at IS NULL;
@@ -0,0 +1,10 @@
+ALTER TABLE list_products
+DROP FOREIGN KEY fk_list_products_checked_by,
+DROP FOREIGN KEY fk_list_products_shop,
+DROP FOREIGN KEY fk_list_products_price_point,
+DROP COLUMN checked_at,
+DROP COLUMN checked_by,
+DROP COLUMN actual_price,
+DROP COLUMN actual_quantity,
+DROP COLUMN shop_id,
+DROP COLUMN price_point_id;
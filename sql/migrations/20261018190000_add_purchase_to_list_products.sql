@@ -0,0 +1,12 @@
+-- What was actually bought when an item was checked, NULL when unknown.
+-- actual_price is in the minor unit of the workspace currency.
+ALTER TABLE list_products
+ADD COLUMN checked_at TIMESTAMP NULL DEFAULT NULL,
+ADD COLUMN checked_by INT NULL DEFAULT NULL,
+ADD COLUMN actual_price BIGINT NULL DEFAULT NULL,
+ADD COLUMN actual_quantity INT NULL DEFAULT NULL,
+ADD COLUMN shop_id INT NULL DEFAULT NULL,
+ADD COLUMN price_point_id INT NULL DEFAULT NULL,
+ADD CONSTRAINT fk_list_products_checked_by FOREIGN KEY (checked_by) REFERENCES users(id) ON DELETE SET NULL,
+ADD CONSTRAINT fk_list_products_shop FOREIGN KEY (shop_id) REFERENCES shops(id) ON DELETE SET NULL,
+ADD CONSTRAINT fk_list_products_price_point FOREIGN KEY (price_point_id) REFERENCES price_history(id) ON DELETE SET NULL;
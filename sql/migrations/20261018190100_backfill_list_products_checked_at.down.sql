@@ -0,0 +1 @@
+-- Nothing to revert, reverting 20261018190000 drops the column
@@ -0,0 +1,2 @@
+-- Items checked before 20261018190000 were checked at their last update, which must not change
+UPDATE list_products SET checked_at = updated_at, updated_at = updated_at WHERE checked = TRUE AND checked_at IS NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE lists
+DROP COLUMN version;
@@ -0,0 +1,3 @@
+-- Incremented on every change, it is the ETag of the list
+ALTER TABLE lists
+ADD COLUMN version INT NOT NULL DEFAULT 1;
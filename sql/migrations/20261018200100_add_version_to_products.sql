@@ -0,0 +1,3 @@
+-- Incremented on every change, it is the ETag of the product
+ALTER TABLE products
+ADD COLUMN version INT NOT NULL DEFAULT 1;
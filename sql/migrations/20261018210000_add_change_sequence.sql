@@ -0,0 +1,5 @@
+-- Every write to the products, lists and list items of a workspace takes the next number of
+-- its change sequence, which the sync feed pages through. Existing rows start at 0.
+-- The next migrations add the sequence to the products, lists and list items.
+ALTER TABLE workspaces
+ADD COLUMN change_seq BIGINT NOT NULL DEFAULT 0;
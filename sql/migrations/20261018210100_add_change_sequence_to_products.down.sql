@@ -0,0 +1,3 @@
+ALTER TABLE products
+DROP INDEX idx_products_change,
+DROP COLUMN change_seq;
@@ -0,0 +1,3 @@
+ALTER TABLE lists
+DROP INDEX idx_lists_change,
+DROP COLUMN change_seq;
@@ -0,0 +1,4 @@
+-- The change sequence, see 20261018210000
+ALTER TABLE lists
+ADD COLUMN change_seq BIGINT NOT NULL DEFAULT 0,
+ADD INDEX idx_lists_change (workspace_id, change_seq);
@@ -0,0 +1,3 @@
+ALTER TABLE list_products
+DROP INDEX idx_list_products_change,
+DROP COLUMN change_seq;
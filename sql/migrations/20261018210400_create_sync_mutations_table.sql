@@ -0,0 +1,11 @@
+-- Results of the sync mutations already applied, so retried batches are not applied twice
+CREATE TABLE IF NOT EXISTS sync_mutations (
+    workspace_id INT NOT NULL,
+    user_id INT NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    result TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace_id, user_id, idempotency_key),
+    FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,15 @@
+-- Requests made with an Idempotency-Key and their responses, replayed when a client retries
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id INT NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    -- status_code stays 0 while the request is in progress
+    status_code SMALLINT NOT NULL DEFAULT 0,
+    header TEXT NULL,
+    body MEDIUMBLOB NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, idempotency_key),
+    INDEX idx_idempotency_keys_expires (user_id, expires_at),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS list_template_products;
+DROP TABLE IF EXISTS list_templates;
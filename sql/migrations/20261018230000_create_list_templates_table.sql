@@ -0,0 +1,27 @@
+-- Templates lists are created from, on demand or on a weekly recurrence
+CREATE TABLE IF NOT EXISTS list_templates (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    workspace_id INT NOT NULL,
+    user_id INT NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    -- The recurrence columns are NULL for templates that are only used on demand
+    recurrence_weekday TINYINT NULL,
+    recurrence_interval_weeks INT NULL,
+    next_run_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL,
+    INDEX idx_list_templates_workspace (workspace_id),
+    INDEX idx_list_templates_next_run (next_run_at),
+    FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS list_template_products (
+    template_id INT NOT NULL,
+    product_id INT NOT NULL,
+    quantity INT NOT NULL DEFAULT 1,
+    PRIMARY KEY (template_id, product_id),
+    FOREIGN KEY (template_id) REFERENCES list_templates(id) ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES products(id)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE users
+MODIFY COLUMN tokens_revoked_at TIMESTAMP NULL DEFAULT NULL;
@@ -0,0 +1,4 @@
+-- Tokens carry their issue time in microseconds, a login right after "log out all devices"
+-- must not fall in the same second as the cutoff
+ALTER TABLE users
+MODIFY COLUMN tokens_revoked_at TIMESTAMP(6) NULL DEFAULT NULL;
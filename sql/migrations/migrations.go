@@ -0,0 +1,15 @@
+// Package migrations embeds the SQL migration files so they ship inside the binary.
+//
+// Every migration is a "<version>_<name>.sql" file where the version is the
+// timestamp prefix, with an optional "<version>_<name>.down.sql" file that reverts it.
+//
+// MySQL commits every DDL statement on its own, so a migration that fails halfway stays partly
+// applied. To be able to run it again, only its last statement may fail when repeated: tables are
+// created with IF NOT EXISTS and their indexes, and a migration adds or drops columns of one table
+// at most, in a single ALTER TABLE.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
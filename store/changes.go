@@ -0,0 +1,106 @@
+package store
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kinds of records in the order they are listed within a change sequence number
+const (
+	changeKindProduct = iota
+	changeKindList
+	changeKindListItem
+)
+
+// changeKey is the position of a change in the feed. Records written by the same transaction
+// share a sequence number and are ordered by kind and ID; list items are identified by their
+// list and product IDs.
+type changeKey struct {
+	Seq    int64
+	Kind   int
+	ID     int
+	ItemID int
+}
+
+// startKey is before every change, including the rows written before the sequence existed
+var startKey = changeKey{Seq: -1}
+
+// less reports whether the change at k comes before the one at other
+func (k changeKey) less(other changeKey) bool {
+	if k.Seq != other.Seq {
+		return k.Seq < other.Seq
+	}
+	if k.Kind != other.Kind {
+		return k.Kind < other.Kind
+	}
+	if k.ID != other.ID {
+		return k.ID < other.ID
+	}
+	return k.ItemID < other.ItemID
+}
+
+// changeCursorKey ties the cursors of the sync feed to their workspace
+func changeCursorKey(workspaceID int) string {
+	return "sync:" + strconv.Itoa(workspaceID)
+}
+
+// encodeChangeCursor returns the cursor handed to clients after the change at k
+func encodeChangeCursor(workspaceID int, k changeKey) string {
+	return encodeCursor(cursor{
+		Key:   changeCursorKey(workspaceID),
+		Value: fmt.Sprintf("%d:%d:%d", k.Seq, k.Kind, k.ItemID),
+		ID:    k.ID,
+	})
+}
+
+// decodeChangeCursor parses a cursor of the sync feed, an empty cursor starts from the beginning
+func decodeChangeCursor(workspaceID int, s string) (changeKey, error) {
+	if s == "" {
+		return startKey, nil
+	}
+	c, err := decodeCursor(s, changeCursorKey(workspaceID))
+	if err != nil {
+		return changeKey{}, err
+	}
+
+	parts := strings.Split(c.Value, ":")
+	if len(parts) != 3 {
+		return changeKey{}, ErrInvalidCursor
+	}
+	seq, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return changeKey{}, ErrInvalidCursor
+	}
+	kind, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return changeKey{}, ErrInvalidCursor
+	}
+	itemID, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return changeKey{}, ErrInvalidCursor
+	}
+	return changeKey{Seq: seq, Kind: kind, ID: c.ID, ItemID: itemID}, nil
+}
+
+// keyedChange is a change with its position in the feed
+type keyedChange struct {
+	key changeKey
+	Change
+}
+
+// changePage keeps the first limit changes sorted by key, at most limit+1 of which are given
+func changePage(workspaceID int, after string, changes []keyedChange, limit int) *ChangePage {
+	page := &ChangePage{Cursor: after, Changes: []Change{}}
+	if len(changes) > limit {
+		changes = changes[:limit]
+		page.More = true
+	}
+	for _, change := range changes {
+		page.Changes = append(page.Changes, change.Change)
+	}
+	if len(changes) > 0 {
+		page.Cursor = encodeChangeCursor(workspaceID, changes[len(changes)-1].key)
+	}
+	return page
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// cursor is the position of the last row of a page for keyset pagination.
+// Key records the ordering it was issued for so it cannot be reused with another one.
+type cursor struct {
+	Key   string `json:"k"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// encodeCursor returns the opaque form of a cursor handed to clients
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor parses an opaque cursor and checks it was issued for the ordering key
+func decodeCursor(s, key string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.Key != key {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// productCursorKey identifies the ordering of a product listing
+func productCursorKey(filter ProductFilter) string {
+	if filter.Desc {
+		return "products:" + string(filter.Sort) + ":desc"
+	}
+	return "products:" + string(filter.Sort) + ":asc"
+}
+
+// productSortValue returns the value of the sort field of a product as stored in its cursor
+func productSortValue(product Product, sort ProductSort) string {
+	switch sort {
+	case ProductSortPrice:
+		return strconv.FormatInt(product.Price.Amount, 10)
+	case ProductSortCreated:
+		return product.CreatedAt.UTC().Format(time.RFC3339Nano)
+	default:
+		return product.Title
+	}
+}
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"shopping_list/money"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []cursor{
+		{Key: "products:title:asc", Value: "Milk", ID: 3},
+		{Key: "products:price:desc", Value: "-120", ID: 1},
+		{Key: "products:created:asc", Value: "2026-10-18T12:00:00.123456Z", ID: 42},
+		{Key: "products:title:asc", Value: "crème brûlée / \"quoted\"", ID: 7},
+		{Key: "products:title:asc", Value: "", ID: 0},
+	}
+	for _, c := range tests {
+		s := encodeCursor(c)
+		got, err := decodeCursor(s, c.Key)
+		if err != nil {
+			t.Errorf("decodeCursor(encodeCursor(%+v)): %s", c, err)
+			continue
+		}
+		if got != c {
+			t.Errorf("decodeCursor(encodeCursor(%+v)) = %+v", c, got)
+		}
+	}
+}
+
+func TestDecodeCursorErrors(t *testing.T) {
+	key := productCursorKey(ProductFilter{Sort: ProductSortPrice})
+	valid := encodeCursor(cursor{Key: key, Value: "120", ID: 1})
+
+	tests := []struct {
+		name   string
+		cursor string
+		key    string
+	}{
+		{"not base64", "not a cursor!", key},
+		{"not json", "bm90IGpzb24", key},
+		{"padded base64", valid + "==", key},
+		{"other sort", valid, productCursorKey(ProductFilter{Sort: ProductSortTitle})},
+		{"other direction", valid, productCursorKey(ProductFilter{Sort: ProductSortPrice, Desc: true})},
+		{"change feed", valid, changeCursorKey(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.cursor, tt.key); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("decodeCursor error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestListProductsPages(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+	workspace := &Workspace{Name: "home"}
+	if err := s.Workspaces.CreateWorkspace(ctx, workspace); err != nil {
+		t.Fatalf("CreateWorkspace: %s", err)
+	}
+	// Titles and prices repeat so that the ID has to break ties between pages
+	for i, title := range []string{"milk", "Bread", "milk", "eggs", "apples", "Milk", "butter"} {
+		product := &Product{WorkspaceID: workspace.ID, Title: title, Price: money.New(int64(100*(i%3)), workspace.Currency)}
+		if err := s.Products.CreateProduct(ctx, product, PriceSource{}); err != nil {
+			t.Fatalf("CreateProduct: %s", err)
+		}
+	}
+
+	for _, sort := range []ProductSort{ProductSortTitle, ProductSortPrice, ProductSortCreated} {
+		for _, desc := range []bool{false, true} {
+			filter := ProductFilter{WorkspaceID: workspace.ID, Sort: sort, Desc: desc, Limit: 100}
+			all, err := s.Products.ListProducts(ctx, filter)
+			if err != nil {
+				t.Fatalf("ListProducts(%s, desc %v): %s", sort, desc, err)
+			}
+
+			var paged []int
+			filter.Limit = 2
+			for {
+				page, err := s.Products.ListProducts(ctx, filter)
+				if err != nil {
+					t.Fatalf("ListProducts(%s, desc %v, after %q): %s", sort, desc, filter.After, err)
+				}
+				for _, product := range page.Products {
+					paged = append(paged, product.ID)
+				}
+				if page.Total != len(all.Products) {
+					t.Errorf("ListProducts(%s, desc %v) total = %d, want %d", sort, desc, page.Total, len(all.Products))
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				filter.After = page.NextCursor
+			}
+
+			var want []int
+			for _, product := range all.Products {
+				want = append(want, product.ID)
+			}
+			if !reflect.DeepEqual(paged, want) {
+				t.Errorf("ListProducts(%s, desc %v) pages = %v, want %v", sort, desc, paged, want)
+			}
+		}
+	}
+
+	// A cursor only continues the listing it was issued for
+	page, err := s.Products.ListProducts(ctx, ProductFilter{WorkspaceID: workspace.ID, Sort: ProductSortTitle, Limit: 2})
+	if err != nil {
+		t.Fatalf("ListProducts: %s", err)
+	}
+	filter := ProductFilter{WorkspaceID: workspace.ID, Sort: ProductSortPrice, After: page.NextCursor, Limit: 2}
+	if _, err := s.Products.ListProducts(ctx, filter); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("ListProducts with a title cursor sorted by price error = %v, want ErrInvalidCursor", err)
+	}
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+// Memory implements every store in process memory. It is meant for tests and
+// local development; all data is lost when the process exits.
+type Memory struct {
+	mu sync.Mutex
+
+	users       map[int]*memoryUser
+	tokens      map[int]*RefreshToken
+	revoked     map[string]time.Time
+	workspaces  map[int]*Workspace
+	members     []*memoryMember
+	invitations map[int]*Invitation
+	categories  map[int]*Category
+	shops       map[int]*Shop
+	shopAisles  map[int][]ShopAisle
+	products    map[int]*Product
+	prices      []*PricePoint
+	lists       map[int]*List
+	listItems   map[int][]*memoryItem
+	templates   map[int]*ListTemplate
+	// changeSeqs holds the change sequence of each workspace, productSeqs and listSeqs
+	// the number of the last change to each product and list
+	changeSeqs  map[int]int64
+	productSeqs map[int]int64
+	listSeqs    map[int]int64
+	mutations   map[string][]byte
+	idempotent  map[string]*IdempotentRequest
+
+	nextUserID       int
+	nextTokenID      int
+	nextWorkspaceID  int
+	nextMemberID     int
+	nextInvitationID int
+	nextCategoryID   int
+	nextShopID       int
+	nextProductID    int
+	nextPriceID      int
+	nextListID       int
+	nextTemplateID   int
+}
+
+type memoryUser struct {
+	User
+	Token           *string
+	TokensRevokedAt *time.Time
+	DeletedAt       *time.Time
+}
+
+type memoryItem struct {
+	ListItem
+	// PricePointID is the price point recorded when the item was checked, 0 if none
+	PricePointID int
+	ChangeSeq    int64
+}
+
+type memoryMember struct {
+	ID          int
+	UserID      int
+	WorkspaceID int
+	Role        Role
+	CreatedAt   time.Time
+	DeletedAt   *time.Time
+}
+
+// NewMemory returns empty in-memory stores
+func NewMemory() *Stores {
+	m := &Memory{
+		users:       make(map[int]*memoryUser),
+		tokens:      make(map[int]*RefreshToken),
+		revoked:     make(map[string]time.Time),
+		workspaces:  make(map[int]*Workspace),
+		invitations: make(map[int]*Invitation),
+		categories:  make(map[int]*Category),
+		shops:       make(map[int]*Shop),
+		shopAisles:  make(map[int][]ShopAisle),
+		products:    make(map[int]*Product),
+		lists:       make(map[int]*List),
+		listItems:   make(map[int][]*memoryItem),
+		templates:   make(map[int]*ListTemplate),
+		changeSeqs:  make(map[int]int64),
+		productSeqs: make(map[int]int64),
+		listSeqs:    make(map[int]int64),
+		mutations:   make(map[string][]byte),
+		idempotent:  make(map[string]*IdempotentRequest),
+	}
+	return &Stores{
+		Users:       m,
+		Tokens:      m,
+		Workspaces:  m,
+		Invitations: m,
+		Categories:  m,
+		Shops:       m,
+		Products:    m,
+		Lists:       m,
+		Templates:   m,
+		Sync:        m,
+		Idempotency: m,
+	}
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+func (m *Memory) CreateCategory(ctx context.Context, category *Category) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.categoryNameTaken(category.WorkspaceID, category.Name, 0) {
+		return ErrDuplicate
+	}
+
+	now := time.Now()
+	m.nextCategoryID++
+	category.ID = m.nextCategoryID
+	category.CreatedAt = now
+	category.UpdatedAt = now
+
+	stored := *category
+	m.categories[category.ID] = &stored
+	return nil
+}
+
+func (m *Memory) GetCategory(ctx context.Context, id int) (*Category, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	category, ok := m.categories[id]
+	if !ok || category.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	found := *category
+	return &found, nil
+}
+
+func (m *Memory) ListCategories(ctx context.Context, workspaceID int) ([]Category, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var categories []Category
+	for _, category := range m.categories {
+		if category.WorkspaceID == workspaceID && category.DeletedAt == nil {
+			categories = append(categories, *category)
+		}
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		a, b := categories[i], categories[j]
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+	return categories, nil
+}
+
+func (m *Memory) UpdateCategory(ctx context.Context, category *Category) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.categoryNameTaken(category.WorkspaceID, category.Name, category.ID) {
+		return ErrDuplicate
+	}
+
+	if stored, ok := m.categories[category.ID]; ok && stored.DeletedAt == nil {
+		stored.Name = category.Name
+		stored.Position = category.Position
+		stored.UpdatedAt = time.Now()
+		category.UpdatedAt = stored.UpdatedAt
+	}
+	return nil
+}
+
+func (m *Memory) DeleteCategory(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	category, ok := m.categories[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if category.DeletedAt == nil {
+		now := time.Now()
+		category.DeletedAt = &now
+	}
+
+	// The products leaving the category are changes of its workspace
+	seq := m.nextChange(category.WorkspaceID)
+	for _, product := range m.products {
+		if product.CategoryID != nil && *product.CategoryID == id {
+			product.CategoryID = nil
+			product.Version++
+			m.productSeqs[product.ID] = seq
+		}
+	}
+	return nil
+}
+
+// categoryNameTaken reports whether another active category of the workspace has the name, the caller must hold m.mu
+func (m *Memory) categoryNameTaken(workspaceID int, name string, exceptID int) bool {
+	for _, category := range m.categories {
+		if category.WorkspaceID == workspaceID && strings.EqualFold(category.Name, name) && category.ID != exceptID && category.DeletedAt == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+func (m *Memory) BeginIdempotentRequest(ctx context.Context, request *IdempotentRequest) (*IdempotentRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Expired keys of the user are forgotten first so they can be used again
+	now := time.Now()
+	for k, existing := range m.idempotent {
+		if existing.UserID == request.UserID && !existing.ExpiresAt.After(now) {
+			delete(m.idempotent, k)
+		}
+	}
+
+	k := idempotencyKey(request.UserID, request.Key)
+	if existing, ok := m.idempotent[k]; ok {
+		found := *existing
+		return &found, ErrDuplicate
+	}
+
+	stored := *request
+	stored.StatusCode = 0
+	stored.Header = nil
+	stored.Body = nil
+	stored.CreatedAt = now
+	m.idempotent[k] = &stored
+	return nil, nil
+}
+
+func (m *Memory) CompleteIdempotentRequest(ctx context.Context, request *IdempotentRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.idempotent[idempotencyKey(request.UserID, request.Key)]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.StatusCode = request.StatusCode
+	stored.Header = request.Header
+	stored.Body = append([]byte(nil), request.Body...)
+	return nil
+}
+
+func (m *Memory) DeleteIdempotentRequest(ctx context.Context, userID int, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.idempotent, idempotencyKey(userID, key))
+	return nil
+}
+
+// idempotencyKey identifies the Idempotency-Key of a user
+func idempotencyKey(userID int, key string) string {
+	return strconv.Itoa(userID) + ":" + key
+}
@@ -0,0 +1,157 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+func (m *Memory) CreateInvitation(ctx context.Context, invitation *Invitation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextInvitationID++
+	invitation.ID = m.nextInvitationID
+	invitation.Status = InvitationPending
+	invitation.CreatedAt = time.Now()
+
+	stored := *invitation
+	m.invitations[invitation.ID] = &stored
+	return nil
+}
+
+func (m *Memory) GetInvitation(ctx context.Context, id int) (*Invitation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	invitation, ok := m.invitations[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return m.invitationView(invitation)
+}
+
+func (m *Memory) GetOpenInvitation(ctx context.Context, workspaceID int, email string) (*Invitation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, invitation := range m.invitations {
+		if invitation.WorkspaceID == workspaceID && invitation.Email == email && invitation.Open(now) {
+			return m.invitationView(invitation)
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) ListWorkspaceInvitations(ctx context.Context, workspaceID int) ([]Invitation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.filterInvitations(func(invitation *Invitation) bool {
+		return invitation.WorkspaceID == workspaceID && invitation.Status == InvitationPending
+	}), nil
+}
+
+func (m *Memory) ListInvitationsByEmail(ctx context.Context, email string) ([]Invitation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	return m.filterInvitations(func(invitation *Invitation) bool {
+		return invitation.Email == email && invitation.Open(now)
+	}), nil
+}
+
+func (m *Memory) AcceptInvitation(ctx context.Context, id, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	invitation, ok := m.invitations[id]
+	if !ok || !invitation.Open(now) {
+		return ErrNotFound
+	}
+	if _, err := m.invitationView(invitation); err != nil {
+		return err
+	}
+
+	m.acceptInvitation(invitation, userID, now)
+	return nil
+}
+
+func (m *Memory) CloseInvitation(ctx context.Context, id int, status InvitationStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	invitation, ok := m.invitations[id]
+	if !ok || invitation.Status != InvitationPending {
+		return ErrNotFound
+	}
+
+	now := time.Now()
+	invitation.Status = status
+	invitation.RespondedAt = &now
+	return nil
+}
+
+func (m *Memory) AcceptPendingInvitations(ctx context.Context, email string, userID int) ([]Invitation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	invitations := m.filterInvitations(func(invitation *Invitation) bool {
+		return invitation.Email == email && invitation.Open(now)
+	})
+	for i := range invitations {
+		m.acceptInvitation(m.invitations[invitations[i].ID], userID, now)
+		invitations[i].Status = InvitationAccepted
+		invitations[i].RespondedAt = &now
+	}
+	return invitations, nil
+}
+
+// acceptInvitation adds the user to the invited workspace unless they already have access, the caller must hold m.mu
+func (m *Memory) acceptInvitation(invitation *Invitation, userID int, now time.Time) {
+	workspace := m.workspaces[invitation.WorkspaceID]
+	if workspace.UserID != userID && m.activeMember(invitation.WorkspaceID, userID) == nil {
+		m.nextMemberID++
+		m.members = append(m.members, &memoryMember{
+			ID:          m.nextMemberID,
+			UserID:      userID,
+			WorkspaceID: invitation.WorkspaceID,
+			Role:        invitation.Role,
+			CreatedAt:   now,
+		})
+	}
+
+	invitation.Status = InvitationAccepted
+	invitation.RespondedAt = &now
+}
+
+// invitationView returns a copy of an invitation with its workspace name, or ErrNotFound if the
+// workspace was deleted. The caller must hold m.mu.
+func (m *Memory) invitationView(invitation *Invitation) (*Invitation, error) {
+	workspace, ok := m.workspaces[invitation.WorkspaceID]
+	if !ok || workspace.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	found := *invitation
+	found.WorkspaceName = workspace.Name
+	return &found, nil
+}
+
+// filterInvitations returns copies of the matching invitations ordered by ID, the caller must hold m.mu
+func (m *Memory) filterInvitations(match func(*Invitation) bool) []Invitation {
+	var invitations []Invitation
+	for _, invitation := range m.invitations {
+		if !match(invitation) {
+			continue
+		}
+		if found, err := m.invitationView(invitation); err == nil {
+			invitations = append(invitations, *found)
+		}
+	}
+	sort.Slice(invitations, func(i, j int) bool { return invitations[i].ID < invitations[j].ID })
+	return invitations
+}
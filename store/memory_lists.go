@@ -0,0 +1,641 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"shopping_list/money"
+)
+
+func (m *Memory) CreateList(ctx context.Context, list *List, items []ItemQuantity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.nextListID++
+	list.ID = m.nextListID
+	list.Version = 1
+	list.Status = ListStatusActive
+	list.CreatedAt = now
+	list.UpdatedAt = now
+
+	stored := *list
+	if list.Budget != nil {
+		budget := *list.Budget
+		stored.Budget = &budget
+	}
+	m.lists[list.ID] = &stored
+
+	seq := m.changeList(&stored)
+	for _, item := range items {
+		m.putItem(list.ID, item, now).ChangeSeq = seq
+	}
+	return nil
+}
+
+func (m *Memory) GetList(ctx context.Context, id int) (*List, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list, ok := m.lists[id]
+	if !ok || list.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	return m.listView(list), nil
+}
+
+func (m *Memory) ListItems(ctx context.Context, listID int) ([]ListItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.activeItems(listID), nil
+}
+
+func (m *Memory) ListListsWithItems(ctx context.Context, workspaceID int) ([]ListWithItems, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var lists []ListWithItems
+	for _, list := range m.lists {
+		if list.WorkspaceID != workspaceID || list.DeletedAt != nil {
+			continue
+		}
+		lists = append(lists, ListWithItems{List: *m.listView(list), Items: m.activeItems(list.ID)})
+	}
+	sort.Slice(lists, func(i, j int) bool { return lists[i].ID > lists[j].ID })
+	return lists, nil
+}
+
+func (m *Memory) UpdateList(ctx context.Context, update *ListUpdate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list, ok := m.lists[update.ListID]
+	if !ok || list.WorkspaceID != update.WorkspaceID || list.DeletedAt != nil {
+		return ErrNotFound
+	}
+	if update.Version != 0 && update.Version != list.Version {
+		return ErrVersionConflict
+	}
+
+	// Nothing fails past the checks above, so like in MySQL the update is applied whole or not at all
+	now := time.Now()
+	list.Title = update.Title
+	setStatus(list, update.Status, now)
+	list.Version++
+	seq := m.changeList(list)
+
+	keep := make(map[int]bool, len(update.Items))
+	for _, item := range update.Items {
+		keep[item.ProductID] = true
+		stored := m.putItem(update.ListID, item, now)
+		stored.ChangeSeq = seq
+		if item.Check != nil {
+			m.applyCheck(stored, *item.Check, now)
+		}
+	}
+
+	// Soft delete products that are not in the update
+	for _, item := range m.listItems[update.ListID] {
+		if !keep[item.ProductID] && item.DeletedAt == nil {
+			item.DeletedAt = &now
+			item.ChangeSeq = seq
+		}
+	}
+	update.Version = list.Version
+	return nil
+}
+
+func (m *Memory) SetListStatus(ctx context.Context, listID, status, version int) (*List, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list, ok := m.lists[listID]
+	if !ok || list.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	if version != 0 && version != list.Version {
+		return nil, ErrVersionConflict
+	}
+
+	now := time.Now()
+	setStatus(list, status, now)
+	list.Version++
+	m.changeList(list)
+	return m.listView(list), nil
+}
+
+func (m *Memory) PatchList(ctx context.Context, patch ListPatch) (*List, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list, ok := m.lists[patch.ListID]
+	if !ok || list.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	if patch.Version != 0 && patch.Version != list.Version {
+		return nil, ErrVersionConflict
+	}
+
+	now := time.Now()
+	if patch.Title != nil {
+		list.Title = *patch.Title
+	}
+	status := list.Status
+	if patch.Status != nil {
+		status = *patch.Status
+	}
+	setStatus(list, status, now)
+	list.Version++
+	m.changeList(list)
+	return m.listView(list), nil
+}
+
+func (m *Memory) SetListBudget(ctx context.Context, listID int, budget *money.Money) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list, ok := m.lists[listID]
+	if !ok || list.DeletedAt != nil {
+		return ErrNotFound
+	}
+
+	list.Budget = nil
+	if budget != nil {
+		stored := *budget
+		list.Budget = &stored
+	}
+	list.UpdatedAt = time.Now()
+	list.Version++
+	m.changeList(list)
+	return nil
+}
+
+func (m *Memory) ListMonthlySpending(ctx context.Context, workspaceID int, from, to time.Time) ([]MonthlySpending, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	currency := m.currency(workspaceID)
+	byMonth := make(map[string]*MonthlySpending)
+	for _, list := range m.lists {
+		if list.WorkspaceID != workspaceID || list.Status != ListStatusCompleted || list.DeletedAt != nil || list.CompletedAt == nil {
+			continue
+		}
+		if list.CompletedAt.Before(from) || !list.CompletedAt.Before(to) {
+			continue
+		}
+
+		key := list.CompletedAt.UTC().Format("2006-01")
+		month, ok := byMonth[key]
+		if !ok {
+			month = &MonthlySpending{Month: key, Total: money.New(0, currency)}
+			byMonth[key] = month
+		}
+		month.Lists++
+
+		// Only checked items were bought. Products deleted since were still bought, so their price is counted.
+		for _, item := range m.listItems[list.ID] {
+			product, ok := m.products[item.ProductID]
+			if item.DeletedAt != nil || !item.Checked || !ok {
+				continue
+			}
+			quantity, price := int64(item.Quantity), product.Price.Amount
+			if item.Purchase != nil && item.Purchase.Quantity != nil {
+				quantity = int64(*item.Purchase.Quantity)
+			}
+			if item.Purchase != nil && item.Purchase.UnitPrice != nil {
+				price = item.Purchase.UnitPrice.Amount
+			}
+			month.Total.Amount += quantity * price
+		}
+	}
+
+	months := make([]MonthlySpending, 0, len(byMonth))
+	for _, month := range byMonth {
+		months = append(months, *month)
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].Month < months[j].Month })
+	return months, nil
+}
+
+// setStatus changes the status of a list, keeping when it was first completed and soft
+// deleting the list for the deleted status
+func setStatus(list *List, status int, now time.Time) {
+	if status != ListStatusCompleted {
+		list.CompletedAt = nil
+	} else if list.CompletedAt == nil {
+		list.CompletedAt = &now
+	}
+	if status == ListStatusDeleted {
+		list.DeletedAt = &now
+	}
+	list.Status = status
+	list.UpdatedAt = now
+}
+
+// listView returns a copy of a list with the currency of its budget, the caller must hold m.mu
+func (m *Memory) listView(list *List) *List {
+	found := *list
+	if list.Budget != nil {
+		budget := money.New(list.Budget.Amount, m.currency(list.WorkspaceID))
+		found.Budget = &budget
+	}
+	return &found
+}
+
+func (m *Memory) RemoveListItem(ctx context.Context, listID, productID, version int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list, err := m.listVersion(listID, version)
+	if err != nil {
+		return 0, err
+	}
+	for _, item := range m.listItems[listID] {
+		if item.ProductID == productID && item.DeletedAt == nil {
+			now := time.Now()
+			item.DeletedAt = &now
+			item.ChangeSeq = m.touchList(listID, now)
+		}
+	}
+	return list.Version, nil
+}
+
+// listVersion returns a list that is not deleted, or ErrVersionConflict if version is not 0 and
+// the list has another version. The caller must hold m.mu.
+func (m *Memory) listVersion(listID, version int) (*List, error) {
+	list, ok := m.lists[listID]
+	if !ok || list.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	if version != 0 && version != list.Version {
+		return nil, ErrVersionConflict
+	}
+	return list, nil
+}
+
+func (m *Memory) PutListItem(ctx context.Context, listID int, item ItemQuantity) (*ListItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.lists[listID]; !ok {
+		return nil, ErrNotFound
+	}
+
+	now := time.Now()
+	stored := m.putItem(listID, item, now)
+	if item.Check != nil {
+		m.applyCheck(stored, *item.Check, now)
+	}
+	stored.ChangeSeq = m.touchList(listID, now)
+
+	found := m.itemView(stored)
+	return &found, nil
+}
+
+// touchList records a change to the items of a list and returns its change number, the caller must hold m.mu
+func (m *Memory) touchList(listID int, now time.Time) int64 {
+	list, ok := m.lists[listID]
+	if !ok {
+		return 0
+	}
+	list.Version++
+	list.UpdatedAt = now
+	return m.changeList(list)
+}
+
+// changeList gives a list the next change number of its workspace and returns it, the caller must hold m.mu
+func (m *Memory) changeList(list *List) int64 {
+	seq := m.nextChange(list.WorkspaceID)
+	m.listSeqs[list.ID] = seq
+	return seq
+}
+
+// nextChange returns the next number of the change sequence of a workspace, the caller must hold m.mu
+func (m *Memory) nextChange(workspaceID int) int64 {
+	m.changeSeqs[workspaceID]++
+	return m.changeSeqs[workspaceID]
+}
+
+func (m *Memory) UpdateListItem(ctx context.Context, listID, productID int, change *ItemChange) (*ListItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list, err := m.listVersion(listID, change.Version)
+	if err != nil {
+		return nil, err
+	}
+	var item *memoryItem
+	for _, existing := range m.listItems[listID] {
+		if existing.ProductID == productID && existing.DeletedAt == nil {
+			item = existing
+		}
+	}
+	if item == nil {
+		return nil, ErrNotFound
+	}
+
+	now := time.Now()
+	if change.Quantity != nil || change.QuantityDelta != 0 {
+		quantity, err := change.apply(item.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		item.Quantity = quantity
+		item.UpdatedAt = now
+	}
+	if change.Check != nil {
+		if err := m.checkItem(listID, productID, *change.Check, now); err != nil {
+			return nil, err
+		}
+	}
+	item.ChangeSeq = m.touchList(listID, now)
+	change.Version = list.Version
+
+	for _, found := range m.activeItems(listID) {
+		if found.ProductID == productID {
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// putItem inserts a product into a list or updates its quantity and returns the stored item,
+// the caller must hold m.mu
+func (m *Memory) putItem(listID int, item ItemQuantity, now time.Time) *memoryItem {
+	for _, existing := range m.listItems[listID] {
+		if existing.ProductID == item.ProductID {
+			if existing.DeletedAt != nil {
+				existing.DeletedAt = nil
+				existing.Checked = false
+				existing.Purchase = nil
+				existing.PricePointID = 0
+			}
+			existing.Quantity = item.Quantity
+			existing.UpdatedAt = now
+			return existing
+		}
+	}
+
+	stored := &memoryItem{ListItem: ListItem{
+		ListID:    listID,
+		ProductID: item.ProductID,
+		Quantity:  item.Quantity,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}}
+	m.listItems[listID] = append(m.listItems[listID], stored)
+	return stored
+}
+
+// checkItem checks or unchecks an item of a list, replacing the price point recorded by a
+// previous check. The caller must hold m.mu.
+func (m *Memory) checkItem(listID, productID int, check ItemCheck, now time.Time) error {
+	var item *memoryItem
+	for _, existing := range m.listItems[listID] {
+		if existing.ProductID == productID && existing.DeletedAt == nil {
+			item = existing
+		}
+	}
+	if item == nil {
+		return ErrNotFound
+	}
+	m.applyCheck(item, check, now)
+	return nil
+}
+
+// applyCheck checks or unchecks a stored item, replacing the price point recorded by a previous
+// check. The caller must hold m.mu.
+func (m *Memory) applyCheck(item *memoryItem, check ItemCheck, now time.Time) {
+	// Checking a checked item again without details keeps its purchase
+	if check.Checked && item.Checked && check.Purchase == nil {
+		return
+	}
+
+	if item.PricePointID != 0 {
+		for i, point := range m.prices {
+			if point.ID == item.PricePointID {
+				m.prices = append(m.prices[:i], m.prices[i+1:]...)
+				break
+			}
+		}
+	}
+	item.Checked = check.Checked
+	item.Purchase = nil
+	item.PricePointID = 0
+	item.UpdatedAt = now
+	if !check.Checked {
+		return
+	}
+
+	purchase := &Purchase{CheckedBy: check.CheckedBy, CheckedAt: now}
+	if check.Purchase != nil {
+		purchase.PurchaseDetails = *check.Purchase
+	}
+	if purchase.UnitPrice != nil {
+		point := &PricePoint{ProductID: item.ProductID, ShopID: purchase.ShopID, UserID: check.CheckedBy, Price: *purchase.UnitPrice, RecordedAt: now}
+		m.addPricePoint(point)
+		item.PricePointID = point.ID
+	}
+	item.Purchase = purchase
+}
+
+// activeItems returns copies of the items of a list that are not soft deleted, the caller must hold m.mu
+func (m *Memory) activeItems(listID int) []ListItem {
+	var items []ListItem
+	for _, item := range m.listItems[listID] {
+		if item.DeletedAt != nil {
+			continue
+		}
+		items = append(items, m.itemView(item))
+	}
+	return items
+}
+
+// itemView returns a copy of an item with its product, the caller must hold m.mu
+func (m *Memory) itemView(item *memoryItem) ListItem {
+	found := item.ListItem
+	if product, ok := m.products[item.ProductID]; ok && product.DeletedAt == nil {
+		found.ProductTitle = product.Title
+		found.Price = money.New(product.Price.Amount, m.currency(product.WorkspaceID))
+		if product.CategoryID != nil {
+			if category, ok := m.categories[*product.CategoryID]; ok && category.DeletedAt == nil {
+				c := *category
+				found.Category = &c
+			}
+		}
+	}
+	return found
+}
+
+func (m *Memory) CompleteAndCarryOver(ctx context.Context, carry CarryOver) (*List, *List, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list, ok := m.lists[carry.ListID]
+	if !ok || list.DeletedAt != nil {
+		return nil, nil, ErrNotFound
+	}
+	if carry.Version != 0 && carry.Version != list.Version {
+		return nil, nil, ErrVersionConflict
+	}
+
+	// Check the target before changing anything, there is nothing to roll back
+	var target *List
+	if carry.TargetID != 0 {
+		target, ok = m.lists[carry.TargetID]
+		if !ok || target.DeletedAt != nil || target.ID == list.ID || target.WorkspaceID != list.WorkspaceID || target.Status != ListStatusActive {
+			return nil, nil, ErrInactiveList
+		}
+	}
+
+	now := time.Now()
+	setStatus(list, ListStatusCompleted, now)
+	list.Version++
+	m.changeList(list)
+
+	if target == nil {
+		m.nextListID++
+		target = &List{
+			ID:          m.nextListID,
+			WorkspaceID: list.WorkspaceID,
+			UserID:      carry.NewList.UserID,
+			Title:       carry.NewList.Title,
+			Status:      ListStatusActive,
+			Version:     1,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if carry.NewList.Budget != nil {
+			budget := *carry.NewList.Budget
+			target.Budget = &budget
+		}
+		m.lists[target.ID] = target
+	} else {
+		target.Version++
+		target.UpdatedAt = now
+	}
+	seq := m.changeList(target)
+
+	for _, item := range m.listItems[list.ID] {
+		if item.DeletedAt == nil && !item.Checked {
+			m.moveItem(target.ID, item, seq, now)
+		}
+	}
+	return m.listView(list), m.listView(target), nil
+}
+
+func (m *Memory) MergeLists(ctx context.Context, merge ListMerge) (*List, []ListItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	target, ok := m.lists[merge.TargetID]
+	if !ok || target.DeletedAt != nil {
+		return nil, nil, ErrNotFound
+	}
+	if merge.Version != 0 && merge.Version != target.Version {
+		return nil, nil, ErrVersionConflict
+	}
+	if target.Status != ListStatusActive {
+		return nil, nil, ErrInactiveList
+	}
+	source, ok := m.lists[merge.SourceID]
+	if !ok || source.DeletedAt != nil || source.ID == target.ID || source.WorkspaceID != target.WorkspaceID {
+		return nil, nil, ErrNotFound
+	}
+
+	now := time.Now()
+	if merge.DryRun {
+		merged := m.listView(target)
+		merged.Version++
+		merged.UpdatedAt = now
+		return merged, m.mergedItems(target.ID, source.ID), nil
+	}
+
+	setStatus(source, ListStatusDeleted, now)
+	source.Version++
+	m.changeList(source)
+
+	target.Version++
+	target.UpdatedAt = now
+	seq := m.changeList(target)
+	for _, item := range m.listItems[source.ID] {
+		if item.DeletedAt == nil {
+			m.moveItem(target.ID, item, seq, now)
+		}
+	}
+	return m.listView(target), m.activeItems(target.ID), nil
+}
+
+// mergedItems returns the items a list would have with the items of another list moved into it,
+// following the rules of moveItem. The caller must hold m.mu.
+func (m *Memory) mergedItems(targetID, sourceID int) []ListItem {
+	items := m.activeItems(targetID)
+	for _, item := range m.activeItems(sourceID) {
+		found := false
+		for i := range items {
+			if items[i].ProductID != item.ProductID {
+				continue
+			}
+			found = true
+			items[i].Quantity += item.Quantity
+			if !items[i].Checked || !item.Checked {
+				items[i].Checked = false
+				items[i].Purchase = nil
+			}
+		}
+		if !found {
+			item.ListID = targetID
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// uncheckItem undoes the purchase of a stored item like uncheckItemTx of the MySQL store, keeping
+// its price point. The caller must hold m.mu.
+func uncheckItem(item *memoryItem, now time.Time) {
+	item.Checked = false
+	item.Purchase = nil
+	item.PricePointID = 0
+	item.UpdatedAt = now
+}
+
+// moveItem moves an active item to another list, like moveItem of the MySQL store. The caller
+// must hold m.mu and bump the versions of both lists.
+func (m *Memory) moveItem(toID int, item *memoryItem, seq int64, now time.Time) {
+	var target *memoryItem
+	for _, existing := range m.listItems[toID] {
+		if existing.ProductID == item.ProductID {
+			target = existing
+		}
+	}
+
+	switch {
+	case target == nil || target.DeletedAt != nil:
+		if target == nil {
+			target = &memoryItem{ListItem: ListItem{ListID: toID, ProductID: item.ProductID, CreatedAt: now}}
+			m.listItems[toID] = append(m.listItems[toID], target)
+		}
+		target.DeletedAt = nil
+		target.Quantity = item.Quantity
+		target.Checked = item.Checked
+		target.Purchase = item.Purchase
+		target.PricePointID = item.PricePointID
+		item.PricePointID = 0
+	default:
+		if target.Checked && !item.Checked {
+			uncheckItem(target, now)
+		}
+		if item.Checked && !target.Checked {
+			uncheckItem(item, now)
+		}
+		target.Quantity += item.Quantity
+	}
+	target.UpdatedAt = now
+	target.ChangeSeq = seq
+
+	item.DeletedAt = &now
+	item.ChangeSeq = seq
+}
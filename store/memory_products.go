@@ -0,0 +1,275 @@
+package store
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"shopping_list/money"
+)
+
+func (m *Memory) CreateProduct(ctx context.Context, product *Product, source PriceSource) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextProductID++
+	product.ID = m.nextProductID
+	product.Version = 1
+	product.Price.Currency = m.currency(product.WorkspaceID)
+	product.CreatedAt = time.Now()
+	m.addPricePoint(&PricePoint{ProductID: product.ID, ShopID: source.ShopID, UserID: source.UserID, Price: product.Price, RecordedAt: product.CreatedAt})
+
+	stored := *product
+	m.products[product.ID] = &stored
+	m.productSeqs[product.ID] = m.nextChange(product.WorkspaceID)
+	return nil
+}
+
+func (m *Memory) GetProduct(ctx context.Context, id int) (*Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	product, ok := m.products[id]
+	if !ok || product.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	found := *product
+	found.Price.Currency = m.currency(product.WorkspaceID)
+	return &found, nil
+}
+
+func (m *Memory) ListProducts(ctx context.Context, filter ProductFilter) (*ProductPage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name := strings.ToLower(filter.Name)
+
+	var products []Product
+	for _, product := range m.products {
+		if product.WorkspaceID != filter.WorkspaceID || product.DeletedAt != nil || !strings.Contains(strings.ToLower(product.Title), name) {
+			continue
+		}
+		if filter.AmountType != "" && product.AmountType != filter.AmountType {
+			continue
+		}
+		if filter.MinPrice != nil && product.Price.Amount < *filter.MinPrice {
+			continue
+		}
+		if filter.MaxPrice != nil && product.Price.Amount > *filter.MaxPrice {
+			continue
+		}
+		found := *product
+		found.Price.Currency = m.currency(product.WorkspaceID)
+		products = append(products, found)
+	}
+
+	// less orders products by the sort field, the ID breaks ties
+	less := func(a, b Product) bool {
+		var cmp int
+		switch filter.Sort {
+		case ProductSortPrice:
+			cmp = compareInt64(a.Price.Amount, b.Price.Amount)
+		case ProductSortCreated:
+			cmp = a.CreatedAt.Compare(b.CreatedAt)
+		default:
+			cmp = strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+		}
+		if cmp == 0 {
+			cmp = a.ID - b.ID
+		}
+		if filter.Desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	}
+	sort.Slice(products, func(i, j int) bool { return less(products[i], products[j]) })
+
+	page := &ProductPage{Total: len(products)}
+
+	// Continue after the last product of the previous page
+	if filter.After != "" {
+		after, err := decodeCursor(filter.After, productCursorKey(filter))
+		if err != nil {
+			return nil, err
+		}
+		// Rebuild the last product from the cursor, it may have changed or been deleted since
+		pivot := Product{ID: after.ID, Title: after.Value}
+		switch filter.Sort {
+		case ProductSortPrice:
+			price, err := strconv.ParseInt(after.Value, 10, 64)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			pivot.Price.Amount = price
+		case ProductSortCreated:
+			if pivot.CreatedAt, err = time.Parse(time.RFC3339Nano, after.Value); err != nil {
+				return nil, ErrInvalidCursor
+			}
+		}
+		start := sort.Search(len(products), func(i int) bool { return less(pivot, products[i]) })
+		products = products[start:]
+	}
+
+	if len(products) > filter.Limit {
+		products = products[:filter.Limit]
+		last := products[len(products)-1]
+		page.NextCursor = encodeCursor(cursor{Key: productCursorKey(filter), Value: productSortValue(last, filter.Sort), ID: last.ID})
+	}
+	page.Products = products
+	return page, nil
+}
+
+// compareInt64 returns -1, 0 or +1 depending on whether a is less than, equal to or greater than b
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (m *Memory) UpdateProduct(ctx context.Context, product *Product, source PriceSource) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.products[product.ID]
+	if !ok || stored.DeletedAt != nil {
+		return ErrNotFound
+	}
+	if product.Version != 0 && product.Version != stored.Version {
+		return ErrVersionConflict
+	}
+
+	product.Price.Currency = m.currency(stored.WorkspaceID)
+	if stored.Price.Amount != product.Price.Amount {
+		m.addPricePoint(&PricePoint{ProductID: product.ID, ShopID: source.ShopID, UserID: source.UserID, Price: product.Price})
+	}
+	stored.Title = product.Title
+	stored.AmountType = product.AmountType
+	stored.Price = product.Price
+	stored.CategoryID = product.CategoryID
+	stored.Version++
+	product.Version = stored.Version
+	m.productSeqs[product.ID] = m.nextChange(stored.WorkspaceID)
+	return nil
+}
+
+func (m *Memory) DeleteProduct(ctx context.Context, id, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	product, ok := m.products[id]
+	if !ok || product.DeletedAt != nil {
+		return ErrNotFound
+	}
+	if version != 0 && version != product.Version {
+		return ErrVersionConflict
+	}
+
+	now := time.Now()
+	product.DeletedAt = &now
+	product.Version++
+	m.productSeqs[id] = m.nextChange(product.WorkspaceID)
+	return nil
+}
+
+func (m *Memory) AddPricePoint(ctx context.Context, point *PricePoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.addPricePoint(point)
+	return nil
+}
+
+func (m *Memory) ListPriceHistory(ctx context.Context, filter PriceHistoryFilter) ([]PricePoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.filterPrices(filter), nil
+}
+
+func (m *Memory) GetPriceStats(ctx context.Context, filter PriceHistoryFilter) (*PriceStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	currency := money.DefaultCurrency
+	if product, ok := m.products[filter.ProductID]; ok {
+		currency = m.currency(product.WorkspaceID)
+	}
+	stats := &PriceStats{Min: money.New(0, currency), Max: money.New(0, currency), Avg: money.New(0, currency)}
+
+	sum := new(big.Int)
+	for i, point := range m.filterPrices(filter) {
+		if i == 0 || point.Price.Amount < stats.Min.Amount {
+			stats.Min.Amount = point.Price.Amount
+		}
+		if i == 0 || point.Price.Amount > stats.Max.Amount {
+			stats.Max.Amount = point.Price.Amount
+		}
+		sum.Add(sum, big.NewInt(point.Price.Amount))
+		stats.Count++
+	}
+	if stats.Count > 0 {
+		// Round half away from zero like MySQL does on the exact average
+		count := big.NewInt(int64(stats.Count))
+		quotient, remainder := new(big.Int).QuoRem(sum, count, new(big.Int))
+		if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(count) >= 0 {
+			quotient.Add(quotient, big.NewInt(int64(sum.Sign())))
+		}
+		stats.Avg.Amount = quotient.Int64()
+	}
+	return stats, nil
+}
+
+// addPricePoint records a price point, the caller must hold m.mu
+func (m *Memory) addPricePoint(point *PricePoint) {
+	if point.RecordedAt.IsZero() {
+		point.RecordedAt = time.Now()
+	}
+	m.nextPriceID++
+	point.ID = m.nextPriceID
+
+	stored := *point
+	m.prices = append(m.prices, &stored)
+}
+
+// filterPrices returns copies of the matching price points oldest first, the caller must hold m.mu
+func (m *Memory) filterPrices(filter PriceHistoryFilter) []PricePoint {
+	var points []PricePoint
+	for _, point := range m.prices {
+		if point.ProductID != filter.ProductID {
+			continue
+		}
+		if filter.ShopID != nil && (point.ShopID == nil || *point.ShopID != *filter.ShopID) {
+			continue
+		}
+		if filter.From != nil && point.RecordedAt.Before(*filter.From) {
+			continue
+		}
+		if filter.To != nil && !point.RecordedAt.Before(*filter.To) {
+			continue
+		}
+		points = append(points, *point)
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].RecordedAt.Before(points[j].RecordedAt) })
+
+	if product, ok := m.products[filter.ProductID]; ok {
+		for i := range points {
+			points[i].Price.Currency = m.currency(product.WorkspaceID)
+		}
+	}
+	return points
+}
+
+// currency returns the currency of a workspace, the caller must hold m.mu
+func (m *Memory) currency(workspaceID int) string {
+	if workspace, ok := m.workspaces[workspaceID]; ok {
+		return workspace.Currency
+	}
+	return money.DefaultCurrency
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+func (m *Memory) CreateShop(ctx context.Context, shop *Shop) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.nextShopID++
+	shop.ID = m.nextShopID
+	shop.CreatedAt = now
+	shop.UpdatedAt = now
+
+	stored := *shop
+	m.shops[shop.ID] = &stored
+	return nil
+}
+
+func (m *Memory) GetShop(ctx context.Context, id int) (*Shop, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	shop, ok := m.shops[id]
+	if !ok || shop.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	found := *shop
+	return &found, nil
+}
+
+func (m *Memory) ListShops(ctx context.Context, workspaceID int) ([]Shop, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var shops []Shop
+	for _, shop := range m.shops {
+		if shop.WorkspaceID == workspaceID && shop.DeletedAt == nil {
+			shops = append(shops, *shop)
+		}
+	}
+	sort.Slice(shops, func(i, j int) bool {
+		if shops[i].Name != shops[j].Name {
+			return shops[i].Name < shops[j].Name
+		}
+		return shops[i].ID < shops[j].ID
+	})
+	return shops, nil
+}
+
+func (m *Memory) UpdateShop(ctx context.Context, shop *Shop) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored, ok := m.shops[shop.ID]; ok && stored.DeletedAt == nil {
+		stored.Name = shop.Name
+		stored.UpdatedAt = time.Now()
+		shop.UpdatedAt = stored.UpdatedAt
+	}
+	return nil
+}
+
+func (m *Memory) DeleteShop(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if shop, ok := m.shops[id]; ok && shop.DeletedAt == nil {
+		now := time.Now()
+		shop.DeletedAt = &now
+	}
+	return nil
+}
+
+func (m *Memory) GetShopLayout(ctx context.Context, shopID int) ([]ShopAisle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Aisles of deleted categories are left out
+	var aisles []ShopAisle
+	for _, aisle := range m.shopAisles[shopID] {
+		if category, ok := m.categories[aisle.CategoryID]; ok && category.DeletedAt == nil {
+			aisles = append(aisles, aisle)
+		}
+	}
+	sort.Slice(aisles, func(i, j int) bool {
+		if aisles[i].Position != aisles[j].Position {
+			return aisles[i].Position < aisles[j].Position
+		}
+		return aisles[i].CategoryID < aisles[j].CategoryID
+	})
+	return aisles, nil
+}
+
+func (m *Memory) SetShopLayout(ctx context.Context, shopID int, aisles []ShopAisle) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seen := make(map[int]bool, len(aisles))
+	for _, aisle := range aisles {
+		if seen[aisle.CategoryID] {
+			return ErrDuplicate
+		}
+		seen[aisle.CategoryID] = true
+	}
+
+	m.shopAisles[shopID] = append([]ShopAisle(nil), aisles...)
+	return nil
+}
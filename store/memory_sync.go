@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+func (m *Memory) ListChanges(ctx context.Context, workspaceID int, after string, limit int) (*ChangePage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	from, err := decodeChangeCursor(workspaceID, after)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []keyedChange
+	add := func(key changeKey, change Change) {
+		if from.less(key) {
+			changes = append(changes, keyedChange{key: key, Change: change})
+		}
+	}
+
+	for _, product := range m.products {
+		if product.WorkspaceID != workspaceID {
+			continue
+		}
+		found := *product
+		found.Price.Currency = m.currency(workspaceID)
+		add(changeKey{Seq: m.productSeqs[product.ID], Kind: changeKindProduct, ID: product.ID},
+			Change{Type: ChangeProduct, Deleted: found.DeletedAt != nil, Product: &found})
+	}
+	for _, list := range m.lists {
+		if list.WorkspaceID != workspaceID {
+			continue
+		}
+		add(changeKey{Seq: m.listSeqs[list.ID], Kind: changeKindList, ID: list.ID},
+			Change{Type: ChangeList, Deleted: list.DeletedAt != nil, List: m.listView(list)})
+
+		for _, item := range m.listItems[list.ID] {
+			found := m.itemView(item)
+			add(changeKey{Seq: item.ChangeSeq, Kind: changeKindListItem, ID: item.ListID, ItemID: item.ProductID},
+				Change{Type: ChangeListItem, Deleted: found.DeletedAt != nil, Item: &found})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].key.less(changes[j].key) })
+	if len(changes) > limit+1 {
+		changes = changes[:limit+1]
+	}
+	return changePage(workspaceID, after, changes, limit), nil
+}
+
+func (m *Memory) GetMutationResult(ctx context.Context, workspaceID, userID int, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result, ok := m.mutations[mutationKey(workspaceID, userID, key)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return result, nil
+}
+
+func (m *Memory) SaveMutationResult(ctx context.Context, workspaceID, userID int, key string, result []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := mutationKey(workspaceID, userID, key)
+	if _, ok := m.mutations[k]; ok {
+		return ErrDuplicate
+	}
+	m.mutations[k] = result
+	return nil
+}
+
+// mutationKey identifies the idempotency key of a user in a workspace
+func mutationKey(workspaceID, userID int, key string) string {
+	return fmt.Sprintf("%d:%d:%s", workspaceID, userID, key)
+}
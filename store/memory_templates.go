@@ -0,0 +1,145 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+func (m *Memory) CreateTemplate(ctx context.Context, template *ListTemplate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.nextTemplateID++
+	template.ID = m.nextTemplateID
+	template.CreatedAt = now
+	template.UpdatedAt = now
+
+	stored := *template
+	m.templates[template.ID] = &stored
+	m.setTemplateFields(&stored, template)
+	return nil
+}
+
+func (m *Memory) GetTemplate(ctx context.Context, id int) (*ListTemplate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	template, ok := m.templates[id]
+	if !ok || template.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	return m.templateView(template), nil
+}
+
+func (m *Memory) ListTemplates(ctx context.Context, workspaceID int) ([]ListTemplate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var templates []ListTemplate
+	for _, template := range m.templates {
+		if template.WorkspaceID == workspaceID && template.DeletedAt == nil {
+			templates = append(templates, *m.templateView(template))
+		}
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if templates[i].Title != templates[j].Title {
+			return templates[i].Title < templates[j].Title
+		}
+		return templates[i].ID < templates[j].ID
+	})
+	return templates, nil
+}
+
+func (m *Memory) UpdateTemplate(ctx context.Context, template *ListTemplate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.templates[template.ID]
+	if !ok || stored.DeletedAt != nil {
+		return ErrNotFound
+	}
+	stored.Title = template.Title
+	stored.UpdatedAt = time.Now()
+	m.setTemplateFields(stored, template)
+	return nil
+}
+
+func (m *Memory) DeleteTemplate(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	template, ok := m.templates[id]
+	if !ok || template.DeletedAt != nil {
+		return ErrNotFound
+	}
+	now := time.Now()
+	template.DeletedAt = &now
+	return nil
+}
+
+func (m *Memory) ListDueTemplates(ctx context.Context, now time.Time) ([]ListTemplate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var templates []ListTemplate
+	for _, template := range m.templates {
+		if template.DeletedAt == nil && template.NextRunAt != nil && !template.NextRunAt.After(now) {
+			templates = append(templates, *m.templateView(template))
+		}
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].ID < templates[j].ID })
+	return templates, nil
+}
+
+func (m *Memory) AdvanceTemplateRun(ctx context.Context, id int, run, next time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	template, ok := m.templates[id]
+	if !ok || template.DeletedAt != nil || template.NextRunAt == nil || !template.NextRunAt.Equal(run) {
+		return ErrNotFound
+	}
+	template.NextRunAt = &next
+	return nil
+}
+
+// setTemplateFields copies the items, recurrence and next run of a template, the caller must hold m.mu
+func (m *Memory) setTemplateFields(stored, template *ListTemplate) {
+	stored.Items = append([]TemplateItem(nil), template.Items...)
+	stored.Recurrence = nil
+	if template.Recurrence != nil {
+		recurrence := *template.Recurrence
+		stored.Recurrence = &recurrence
+	}
+	stored.NextRunAt = nil
+	if template.NextRunAt != nil {
+		next := *template.NextRunAt
+		stored.NextRunAt = &next
+	}
+}
+
+// templateView returns a copy of a template whose items are the products that still exist,
+// with their titles, the caller must hold m.mu
+func (m *Memory) templateView(template *ListTemplate) *ListTemplate {
+	found := *template
+	found.Items = nil
+	for _, item := range template.Items {
+		product, ok := m.products[item.ProductID]
+		if !ok || product.DeletedAt != nil {
+			continue
+		}
+		item.ProductTitle = product.Title
+		found.Items = append(found.Items, item)
+	}
+	if template.Recurrence != nil {
+		recurrence := *template.Recurrence
+		found.Recurrence = &recurrence
+	}
+	if template.NextRunAt != nil {
+		next := *template.NextRunAt
+		found.NextRunAt = &next
+	}
+	return &found
+}
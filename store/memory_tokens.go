@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+func (m *Memory) CreateRefreshToken(ctx context.Context, token *RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.insertRefreshToken(token, time.Now())
+	return nil
+}
+
+func (m *Memory) GetRefreshTokenByHash(ctx context.Context, hash string) (*RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, token := range m.tokens {
+		if token.TokenHash == hash {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) RotateRefreshToken(ctx context.Context, oldID int, next *RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.tokens[oldID]
+	if !ok {
+		return ErrNotFound
+	}
+	if old.UsedAt != nil || old.RevokedAt != nil {
+		return ErrTokenUsed
+	}
+
+	now := time.Now()
+	old.UsedAt = &now
+	m.insertRefreshToken(next, now)
+	return nil
+}
+
+func (m *Memory) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, token := range m.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *Memory) RevokeAccessToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Tokens that expired since they were revoked are rejected anyway
+	now := time.Now()
+	for revokedJTI, revokedUntil := range m.revoked {
+		if revokedUntil.Before(now) {
+			delete(m.revoked, revokedJTI)
+		}
+	}
+	m.revoked[jti] = expiresAt
+	return nil
+}
+
+func (m *Memory) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, revoked := m.revoked[jti]
+	return revoked, nil
+}
+
+func (m *Memory) RevokeUserTokens(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if user, ok := m.users[userID]; ok {
+		user.Token = nil
+		user.TokensRevokedAt = &now
+	}
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *Memory) GetUserTokensRevokedAt(ctx context.Context, userID int) (*time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return user.TokensRevokedAt, nil
+}
+
+// insertRefreshToken stores a copy of token, the caller must hold m.mu
+func (m *Memory) insertRefreshToken(token *RefreshToken, now time.Time) {
+	m.nextTokenID++
+	token.ID = m.nextTokenID
+	token.CreatedAt = now
+
+	stored := *token
+	m.tokens[token.ID] = &stored
+}
@@ -0,0 +1,54 @@
+package store
+
+import "context"
+
+func (m *Memory) CreateUser(ctx context.Context, user *User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.users {
+		if existing.Email == user.Email {
+			return ErrDuplicate
+		}
+	}
+
+	m.nextUserID++
+	user.ID = m.nextUserID
+	m.users[user.ID] = &memoryUser{User: *user}
+	return nil
+}
+
+func (m *Memory) GetUserByID(ctx context.Context, id int) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok || user.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	found := user.User
+	return &found, nil
+}
+
+func (m *Memory) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.users {
+		if user.Email == email && user.DeletedAt == nil {
+			found := user.User
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) SetUserToken(ctx context.Context, userID int, token *string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if user, ok := m.users[userID]; ok {
+		user.Token = token
+	}
+	return nil
+}
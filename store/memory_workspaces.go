@@ -0,0 +1,256 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"shopping_list/money"
+)
+
+func (m *Memory) CreateWorkspace(ctx context.Context, workspace *Workspace) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if workspace.Currency == "" {
+		workspace.Currency = money.DefaultCurrency
+	}
+
+	now := time.Now()
+	m.nextWorkspaceID++
+	workspace.ID = m.nextWorkspaceID
+	workspace.CreatedAt = now
+	workspace.UpdatedAt = now
+
+	stored := *workspace
+	m.workspaces[workspace.ID] = &stored
+	return nil
+}
+
+func (m *Memory) GetWorkspace(ctx context.Context, id int) (*Workspace, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	workspace, ok := m.workspaces[id]
+	if !ok || workspace.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	found := *workspace
+	return &found, nil
+}
+
+func (m *Memory) ListWorkspacesForUser(ctx context.Context, userID int, ownership WorkspaceOwnership) ([]WorkspaceSummary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var workspaces []WorkspaceSummary
+	for _, workspace := range m.workspaces {
+		if workspace.DeletedAt != nil {
+			continue
+		}
+
+		var role Role
+		if workspace.UserID == userID {
+			role = RoleOwner
+		} else if member := m.activeMember(workspace.ID, userID); member != nil {
+			role = member.Role
+		} else {
+			continue
+		}
+		if (ownership == WorkspacesOwned && role != RoleOwner) || (ownership == WorkspacesShared && role == RoleOwner) {
+			continue
+		}
+
+		summary := WorkspaceSummary{Workspace: *workspace, Role: role, MemberCount: 1, LastActivityAt: workspace.UpdatedAt}
+		for _, member := range m.members {
+			if member.WorkspaceID == workspace.ID && member.DeletedAt == nil {
+				summary.MemberCount++
+			}
+		}
+		for _, list := range m.lists {
+			if list.WorkspaceID != workspace.ID {
+				continue
+			}
+			if list.UpdatedAt.After(summary.LastActivityAt) {
+				summary.LastActivityAt = list.UpdatedAt
+			}
+			for _, item := range m.listItems[list.ID] {
+				if item.UpdatedAt.After(summary.LastActivityAt) {
+					summary.LastActivityAt = item.UpdatedAt
+				}
+			}
+		}
+		workspaces = append(workspaces, summary)
+	}
+
+	sort.Slice(workspaces, func(i, j int) bool {
+		if !workspaces[i].LastActivityAt.Equal(workspaces[j].LastActivityAt) {
+			return workspaces[i].LastActivityAt.After(workspaces[j].LastActivityAt)
+		}
+		return workspaces[i].ID > workspaces[j].ID
+	})
+	return workspaces, nil
+}
+
+func (m *Memory) UpdateWorkspace(ctx context.Context, id, ownerID int, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if workspace, ok := m.workspaces[id]; ok && workspace.UserID == ownerID && workspace.DeletedAt == nil {
+		workspace.Name = name
+		workspace.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (m *Memory) SetWorkspaceCurrency(ctx context.Context, id int, currency string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	workspace, ok := m.workspaces[id]
+	if !ok || workspace.DeletedAt != nil {
+		return ErrNotFound
+	}
+	if workspace.Currency == currency {
+		return nil
+	}
+	for _, product := range m.products {
+		if product.WorkspaceID == id && product.DeletedAt == nil {
+			return ErrCurrencyInUse
+		}
+	}
+
+	workspace.Currency = currency
+	workspace.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *Memory) DeleteWorkspace(ctx context.Context, id, ownerID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if workspace, ok := m.workspaces[id]; ok && workspace.UserID == ownerID {
+		now := time.Now()
+		workspace.DeletedAt = &now
+	}
+	return nil
+}
+
+func (m *Memory) GetWorkspaceRole(ctx context.Context, workspaceID, userID int) (Role, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	workspace, ok := m.workspaces[workspaceID]
+	if !ok || workspace.DeletedAt != nil {
+		return "", ErrNotFound
+	}
+	if workspace.UserID == userID {
+		return RoleOwner, nil
+	}
+	if member := m.activeMember(workspaceID, userID); member != nil {
+		return member.Role, nil
+	}
+	return "", ErrNotFound
+}
+
+func (m *Memory) IsWorkspaceMember(ctx context.Context, workspaceID, userID int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.activeMember(workspaceID, userID) != nil, nil
+}
+
+func (m *Memory) AddWorkspaceMember(ctx context.Context, workspaceID, userID int, role Role) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextMemberID++
+	m.members = append(m.members, &memoryMember{
+		ID:          m.nextMemberID,
+		UserID:      userID,
+		WorkspaceID: workspaceID,
+		Role:        role,
+		CreatedAt:   time.Now(),
+	})
+	return nil
+}
+
+func (m *Memory) SetWorkspaceMemberRole(ctx context.Context, workspaceID, userID int, role Role) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	member := m.activeMember(workspaceID, userID)
+	if member == nil {
+		return ErrNotFound
+	}
+	member.Role = role
+	return nil
+}
+
+func (m *Memory) RemoveWorkspaceMember(ctx context.Context, workspaceID, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, member := range m.members {
+		if member.WorkspaceID == workspaceID && member.UserID == userID {
+			member.DeletedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *Memory) TransferWorkspaceOwnership(ctx context.Context, workspaceID, ownerID, newOwnerID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	workspace, ok := m.workspaces[workspaceID]
+	if !ok || workspace.UserID != ownerID || workspace.DeletedAt != nil {
+		return ErrNotFound
+	}
+	member := m.activeMember(workspaceID, newOwnerID)
+	if member == nil {
+		return ErrNotFound
+	}
+
+	now := time.Now()
+	member.DeletedAt = &now
+	workspace.UserID = newOwnerID
+	workspace.UpdatedAt = now
+
+	m.nextMemberID++
+	m.members = append(m.members, &memoryMember{
+		ID:          m.nextMemberID,
+		UserID:      ownerID,
+		WorkspaceID: workspaceID,
+		Role:        RoleEditor,
+		CreatedAt:   now,
+	})
+	return nil
+}
+
+func (m *Memory) ListWorkspaceMembers(ctx context.Context, workspaceID int) ([]Member, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var members []Member
+	for _, member := range m.members {
+		if member.WorkspaceID != workspaceID || member.DeletedAt != nil {
+			continue
+		}
+		if user, ok := m.users[member.UserID]; ok {
+			members = append(members, Member{User: user.User, Role: member.Role})
+		}
+	}
+	return members, nil
+}
+
+// activeMember returns the membership of a user in a workspace, the caller must hold m.mu
+func (m *Memory) activeMember(workspaceID, userID int) *memoryMember {
+	for _, member := range m.members {
+		if member.WorkspaceID == workspaceID && member.UserID == userID && member.DeletedAt == nil {
+			return member
+		}
+	}
+	return nil
+}
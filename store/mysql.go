@@ -0,0 +1,48 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL implements every store on top of a MySQL connection
+type MySQL struct {
+	db *sql.DB
+}
+
+// NewMySQL returns the MySQL backed stores
+func NewMySQL(db *sql.DB) *Stores {
+	m := &MySQL{db: db}
+	return &Stores{
+		Users:       m,
+		Tokens:      m,
+		Workspaces:  m,
+		Invitations: m,
+		Categories:  m,
+		Shops:       m,
+		Products:    m,
+		Lists:       m,
+		Templates:   m,
+		Sync:        m,
+		Idempotency: m,
+	}
+}
+
+// notFound maps sql.ErrNoRows to ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// duplicate maps MySQL duplicate key errors to ErrDuplicate
+func duplicate(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		return ErrDuplicate
+	}
+	return err
+}
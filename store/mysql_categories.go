@@ -0,0 +1,121 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+func (m *MySQL) CreateCategory(ctx context.Context, category *Category) error {
+	// Insert only if the name is not taken by another active category of the workspace
+	now := time.Now()
+	result, err := m.db.ExecContext(ctx, `
+		INSERT INTO categories (workspace_id, name, position, created_at, updated_at)
+		SELECT ?, ?, ?, ?, ? FROM DUAL
+		WHERE NOT EXISTS (SELECT 1 FROM categories WHERE workspace_id = ? AND name = ? AND deleted_at IS NULL)`,
+		category.WorkspaceID, category.Name, category.Position, now, now, category.WorkspaceID, category.Name,
+	)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrDuplicate
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	category.ID = int(id)
+	category.CreatedAt = now
+	category.UpdatedAt = now
+	return nil
+}
+
+func (m *MySQL) GetCategory(ctx context.Context, id int) (*Category, error) {
+	var category Category
+	err := m.db.QueryRowContext(ctx,
+		"SELECT id, workspace_id, name, position, created_at, updated_at, deleted_at FROM categories WHERE id = ? AND deleted_at IS NULL",
+		id,
+	).Scan(&category.ID, &category.WorkspaceID, &category.Name, &category.Position, &category.CreatedAt, &category.UpdatedAt, &category.DeletedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &category, nil
+}
+
+func (m *MySQL) ListCategories(ctx context.Context, workspaceID int) ([]Category, error) {
+	rows, err := m.db.QueryContext(ctx,
+		"SELECT id, workspace_id, name, position, created_at, updated_at, deleted_at FROM categories WHERE workspace_id = ? AND deleted_at IS NULL ORDER BY position, name, id",
+		workspaceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []Category
+	for rows.Next() {
+		var category Category
+		if err := rows.Scan(&category.ID, &category.WorkspaceID, &category.Name, &category.Position, &category.CreatedAt, &category.UpdatedAt, &category.DeletedAt); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+func (m *MySQL) UpdateCategory(ctx context.Context, category *Category) error {
+	var taken bool
+	err := m.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM categories WHERE workspace_id = ? AND name = ? AND id <> ? AND deleted_at IS NULL)",
+		category.WorkspaceID, category.Name, category.ID,
+	).Scan(&taken)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrDuplicate
+	}
+
+	now := time.Now()
+	_, err = m.db.ExecContext(ctx,
+		"UPDATE categories SET name = ?, position = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL",
+		category.Name, category.Position, now, category.ID,
+	)
+	if err != nil {
+		return err
+	}
+	category.UpdatedAt = now
+	return nil
+}
+
+func (m *MySQL) DeleteCategory(ctx context.Context, id int) error {
+	// Begin transaction
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	// The products leaving the category are changes of its workspace
+	var workspaceID int
+	if err := tx.QueryRowContext(ctx, "SELECT workspace_id FROM categories WHERE id = ?", id).Scan(&workspaceID); err != nil {
+		return notFound(err)
+	}
+	seq, err := nextChange(ctx, tx, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE categories SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL", time.Now(), id); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE products SET category_id = NULL, version = version + 1, change_seq = ? WHERE category_id = ?", seq, id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
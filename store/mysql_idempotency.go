@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+func (m *MySQL) BeginIdempotentRequest(ctx context.Context, request *IdempotentRequest) (*IdempotentRequest, error) {
+	// Expired keys of the user are forgotten first so they can be used again
+	now := time.Now()
+	if _, err := m.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE user_id = ? AND expires_at <= ?", request.UserID, now); err != nil {
+		return nil, err
+	}
+
+	_, err := m.db.ExecContext(ctx,
+		"INSERT INTO idempotency_keys (user_id, idempotency_key, fingerprint, created_at, expires_at) VALUES (?, ?, ?, ?, ?)",
+		request.UserID, request.Key, request.Fingerprint, now, request.ExpiresAt,
+	)
+	if err = duplicate(err); !errors.Is(err, ErrDuplicate) {
+		return nil, err
+	}
+
+	existing := IdempotentRequest{UserID: request.UserID, Key: request.Key}
+	var header sql.NullString
+	err = m.db.QueryRowContext(ctx,
+		"SELECT fingerprint, status_code, header, body, created_at, expires_at FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ?",
+		request.UserID, request.Key,
+	).Scan(&existing.Fingerprint, &existing.StatusCode, &header, &existing.Body, &existing.CreatedAt, &existing.ExpiresAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if header.Valid {
+		if err := json.Unmarshal([]byte(header.String), &existing.Header); err != nil {
+			return nil, err
+		}
+	}
+	return &existing, ErrDuplicate
+}
+
+func (m *MySQL) CompleteIdempotentRequest(ctx context.Context, request *IdempotentRequest) error {
+	header, err := json.Marshal(request.Header)
+	if err != nil {
+		return err
+	}
+
+	result, err := m.db.ExecContext(ctx,
+		"UPDATE idempotency_keys SET status_code = ?, header = ?, body = ? WHERE user_id = ? AND idempotency_key = ?",
+		request.StatusCode, header, request.Body, request.UserID, request.Key,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *MySQL) DeleteIdempotentRequest(ctx context.Context, userID int, key string) error {
+	_, err := m.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ?", userID, key)
+	return err
+}
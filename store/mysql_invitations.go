@@ -0,0 +1,215 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const invitationColumns = `
+	i.id, i.workspace_id, w.name, i.email, i.role, i.invited_by, i.status, i.expires_at, i.responded_at, i.created_at
+	FROM workspace_invitations i
+	JOIN workspaces w ON w.id = i.workspace_id AND w.deleted_at IS NULL`
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanInvitation(row scanner) (*Invitation, error) {
+	var invitation Invitation
+	err := row.Scan(&invitation.ID, &invitation.WorkspaceID, &invitation.WorkspaceName, &invitation.Email, &invitation.Role,
+		&invitation.InvitedBy, &invitation.Status, &invitation.ExpiresAt, &invitation.RespondedAt, &invitation.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (m *MySQL) CreateInvitation(ctx context.Context, invitation *Invitation) error {
+	now := time.Now()
+	result, err := m.db.ExecContext(ctx,
+		"INSERT INTO workspace_invitations (workspace_id, email, role, invited_by, status, expires_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		invitation.WorkspaceID, invitation.Email, invitation.Role, invitation.InvitedBy, InvitationPending, invitation.ExpiresAt, now, now,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	invitation.ID = int(id)
+	invitation.Status = InvitationPending
+	invitation.CreatedAt = now
+	return nil
+}
+
+func (m *MySQL) GetInvitation(ctx context.Context, id int) (*Invitation, error) {
+	invitation, err := scanInvitation(m.db.QueryRowContext(ctx, "SELECT"+invitationColumns+" WHERE i.id = ?", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return invitation, nil
+}
+
+func (m *MySQL) GetOpenInvitation(ctx context.Context, workspaceID int, email string) (*Invitation, error) {
+	invitation, err := scanInvitation(m.db.QueryRowContext(ctx,
+		"SELECT"+invitationColumns+" WHERE i.workspace_id = ? AND i.email = ? AND i.status = ? AND i.expires_at > ? LIMIT 1",
+		workspaceID, email, InvitationPending, time.Now(),
+	))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return invitation, nil
+}
+
+func (m *MySQL) ListWorkspaceInvitations(ctx context.Context, workspaceID int) ([]Invitation, error) {
+	return m.queryInvitations(ctx, "SELECT"+invitationColumns+" WHERE i.workspace_id = ? AND i.status = ? ORDER BY i.id",
+		workspaceID, InvitationPending)
+}
+
+func (m *MySQL) ListInvitationsByEmail(ctx context.Context, email string) ([]Invitation, error) {
+	return m.queryInvitations(ctx, "SELECT"+invitationColumns+" WHERE i.email = ? AND i.status = ? AND i.expires_at > ? ORDER BY i.id",
+		email, InvitationPending, time.Now())
+}
+
+func (m *MySQL) queryInvitations(ctx context.Context, query string, args ...interface{}) ([]Invitation, error) {
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invitations []Invitation
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, *invitation)
+	}
+	return invitations, rows.Err()
+}
+
+func (m *MySQL) AcceptInvitation(ctx context.Context, id, userID int) error {
+	// Begin transaction
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	now := time.Now()
+	invitation, err := scanInvitation(tx.QueryRowContext(ctx,
+		"SELECT"+invitationColumns+" WHERE i.id = ? AND i.status = ? AND i.expires_at > ? FOR UPDATE",
+		id, InvitationPending, now,
+	))
+	if err != nil {
+		return notFound(err)
+	}
+
+	if err := acceptInvitationTx(ctx, tx, invitation, userID, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *MySQL) CloseInvitation(ctx context.Context, id int, status InvitationStatus) error {
+	now := time.Now()
+	result, err := m.db.ExecContext(ctx,
+		"UPDATE workspace_invitations SET status = ?, responded_at = ?, updated_at = ? WHERE id = ? AND status = ?",
+		status, now, now, id, InvitationPending,
+	)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *MySQL) AcceptPendingInvitations(ctx context.Context, email string, userID int) ([]Invitation, error) {
+	// Begin transaction
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	now := time.Now()
+	rows, err := tx.QueryContext(ctx,
+		"SELECT"+invitationColumns+" WHERE i.email = ? AND i.status = ? AND i.expires_at > ? ORDER BY i.id FOR UPDATE",
+		email, InvitationPending, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read every invitation before issuing other statements on the transaction
+	var invitations []Invitation
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		invitations = append(invitations, *invitation)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range invitations {
+		if err := acceptInvitationTx(ctx, tx, &invitations[i], userID, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// acceptInvitationTx adds the user to the invited workspace unless they already have access, and closes the invitation
+func acceptInvitationTx(ctx context.Context, tx *sql.Tx, invitation *Invitation, userID int, now time.Time) error {
+	var hasAccess bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM workspaces WHERE id = ? AND user_id = ?)
+		    OR EXISTS(SELECT 1 FROM workspace_users WHERE workspace_id = ? AND user_id = ? AND deleted_at IS NULL)`,
+		invitation.WorkspaceID, userID, invitation.WorkspaceID, userID,
+	).Scan(&hasAccess)
+	if err != nil {
+		return err
+	}
+
+	if !hasAccess {
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO workspace_users (user_id, workspace_id, role) VALUES (?, ?, ?)",
+			userID, invitation.WorkspaceID, invitation.Role,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE workspace_invitations SET status = ?, responded_at = ?, updated_at = ? WHERE id = ?",
+		InvitationAccepted, now, now, invitation.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	invitation.Status = InvitationAccepted
+	invitation.RespondedAt = &now
+	return nil
+}
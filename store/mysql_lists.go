@@ -0,0 +1,1020 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"shopping_list/money"
+)
+
+// listColumns selects a list with the currency of its workspace
+const listColumns = `id, workspace_id, user_id, title, status, budget, completed_at, created_at, updated_at, deleted_at, version,
+	COALESCE((SELECT w.currency FROM workspaces w WHERE w.id = lists.workspace_id), '` + money.DefaultCurrency + `')`
+
+func scanList(row scanner) (*List, error) {
+	var list List
+	var budget sql.NullInt64
+	var currency string
+	err := row.Scan(&list.ID, &list.WorkspaceID, &list.UserID, &list.Title, &list.Status, &budget, &list.CompletedAt,
+		&list.CreatedAt, &list.UpdatedAt, &list.DeletedAt, &list.Version, &currency)
+	if err != nil {
+		return nil, err
+	}
+	if budget.Valid {
+		list.Budget = &money.Money{Amount: budget.Int64, Currency: currency}
+	}
+	return &list, nil
+}
+
+// nullPurchase scans the purchase columns of a list item, which are NULL while it is unchecked
+type nullPurchase struct {
+	CheckedAt                            sql.NullTime
+	CheckedBy, UnitPrice, Quantity, Shop sql.NullInt64
+}
+
+func (p *nullPurchase) dest() []interface{} {
+	return []interface{}{&p.CheckedAt, &p.CheckedBy, &p.UnitPrice, &p.Quantity, &p.Shop}
+}
+
+func (p *nullPurchase) purchase(currency string) *Purchase {
+	if !p.CheckedAt.Valid {
+		return nil
+	}
+	purchase := &Purchase{CheckedAt: p.CheckedAt.Time}
+	if p.CheckedBy.Valid {
+		checkedBy := int(p.CheckedBy.Int64)
+		purchase.CheckedBy = &checkedBy
+	}
+	if p.UnitPrice.Valid {
+		price := money.New(p.UnitPrice.Int64, currency)
+		purchase.UnitPrice = &price
+	}
+	if p.Quantity.Valid {
+		quantity := int(p.Quantity.Int64)
+		purchase.Quantity = &quantity
+	}
+	if p.Shop.Valid {
+		shopID := int(p.Shop.Int64)
+		purchase.ShopID = &shopID
+	}
+	return purchase
+}
+
+// budgetAmount returns the column value of a budget
+func budgetAmount(budget *money.Money) interface{} {
+	if budget == nil {
+		return nil
+	}
+	return budget.Amount
+}
+
+func (m *MySQL) CreateList(ctx context.Context, list *List, items []ItemQuantity) error {
+	// Begin transaction
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	seq, err := nextChange(ctx, tx, list.WorkspaceID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO lists (workspace_id, user_id, title, budget, created_at, updated_at, change_seq) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		list.WorkspaceID, list.UserID, list.Title, budgetAmount(list.Budget), now, now, seq,
+	)
+	if err != nil {
+		return err
+	}
+
+	listID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO list_products (list_id, product_id, quantity, checked, created_at, updated_at, change_seq) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			listID, item.ProductID, item.Quantity, false, now, now, seq,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	list.ID = int(listID)
+	list.Version = 1
+	list.Status = ListStatusActive
+	list.CreatedAt = now
+	list.UpdatedAt = now
+	return nil
+}
+
+func (m *MySQL) GetList(ctx context.Context, id int) (*List, error) {
+	list, err := scanList(m.db.QueryRowContext(ctx, "SELECT "+listColumns+" FROM lists WHERE id = ? AND deleted_at IS NULL", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return list, nil
+}
+
+// listItemColumns selects the items of lists with their product and purchase
+const listItemColumns = listItemFields + listItemTables
+
+const listItemFields = `
+	lp.list_id, lp.product_id, lp.quantity, lp.checked, lp.created_at, lp.updated_at, p.title, p.price, w.currency,
+	lp.checked_at, lp.checked_by, lp.actual_price, lp.actual_quantity, lp.shop_id`
+
+const listItemTables = `
+	FROM list_products lp
+	JOIN products p ON p.id = lp.product_id
+	JOIN workspaces w ON w.id = p.workspace_id`
+
+func scanListItem(row scanner) (*ListItem, error) {
+	var item ListItem
+	var purchase nullPurchase
+	dest := []interface{}{&item.ListID, &item.ProductID, &item.Quantity, &item.Checked, &item.CreatedAt, &item.UpdatedAt,
+		&item.ProductTitle, &item.Price.Amount, &item.Price.Currency}
+	if err := row.Scan(append(dest, purchase.dest()...)...); err != nil {
+		return nil, err
+	}
+	item.Purchase = purchase.purchase(item.Price.Currency)
+	return &item, nil
+}
+
+func (m *MySQL) ListItems(ctx context.Context, listID int) ([]ListItem, error) {
+	return queryListItems(ctx, m.db, listID)
+}
+
+// queryer is implemented by *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// queryListItems returns the active items of a list
+func queryListItems(ctx context.Context, q queryer, listID int) ([]ListItem, error) {
+	rows, err := q.QueryContext(ctx, "SELECT"+listItemColumns+" WHERE lp.list_id = ? AND lp.deleted_at IS NULL", listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []ListItem
+	for rows.Next() {
+		item, err := scanListItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *item)
+	}
+	return items, rows.Err()
+}
+
+func (m *MySQL) UpdateListItem(ctx context.Context, listID, productID int, change *ItemChange) (*ListItem, error) {
+	// Begin transaction
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	seq, err := nextListChange(ctx, tx, listID)
+	if err != nil {
+		return nil, err
+	}
+	version, err := lockListVersion(ctx, tx, listID, change.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lock the item so concurrent changes apply one after the other
+	var quantity int
+	err = tx.QueryRowContext(ctx,
+		"SELECT quantity FROM list_products WHERE list_id = ? AND product_id = ? AND deleted_at IS NULL FOR UPDATE",
+		listID, productID,
+	).Scan(&quantity)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	now := time.Now()
+	if change.Quantity != nil || change.QuantityDelta != 0 {
+		quantity, err = change.apply(quantity)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx,
+			"UPDATE list_products SET quantity = ?, updated_at = ? WHERE list_id = ? AND product_id = ?",
+			quantity, now, listID, productID,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if change.Check != nil {
+		if err := checkItemTx(ctx, tx, listID, productID, *change.Check, now); err != nil {
+			return nil, err
+		}
+	}
+	if err := touchListItem(ctx, tx, listID, productID, seq, now); err != nil {
+		return nil, err
+	}
+
+	item, err := scanListItem(tx.QueryRowContext(ctx, "SELECT"+listItemColumns+" WHERE lp.list_id = ? AND lp.product_id = ?", listID, productID))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	change.Version = version + 1
+	return item, nil
+}
+
+func (m *MySQL) ListListsWithItems(ctx context.Context, workspaceID int) ([]ListWithItems, error) {
+	// Retrieve all lists and their products in the workspace
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT l.id, l.workspace_id, l.user_id, l.title, l.status, l.budget, l.completed_at, l.created_at, l.updated_at, l.deleted_at,
+		       l.version, w.currency, lp.product_id, lp.quantity, lp.checked, lp.created_at, lp.updated_at, lp.deleted_at,
+		       p.title, p.price, c.id, c.name, c.position,
+		       lp.checked_at, lp.checked_by, lp.actual_price, lp.actual_quantity, lp.shop_id
+		FROM lists l
+		JOIN workspaces w ON w.id = l.workspace_id
+		LEFT JOIN list_products lp ON l.id = lp.list_id AND lp.deleted_at IS NULL
+		LEFT JOIN products p ON lp.product_id = p.id AND p.deleted_at IS NULL
+		LEFT JOIN categories c ON p.category_id = c.id AND c.deleted_at IS NULL
+		WHERE l.workspace_id = ? AND l.deleted_at IS NULL
+		ORDER BY l.id DESC
+	`, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lists []ListWithItems
+	for rows.Next() {
+		var list List
+		var budget sql.NullInt64
+		var currency string
+
+		// Use nullable types for product fields to handle lists without products
+		var productID, quantity, price sql.NullInt64
+		var checked sql.NullBool
+		var productCreatedAt, productUpdatedAt, productDeletedAt sql.NullTime
+		var productTitle, categoryName sql.NullString
+		var categoryID, categoryPosition sql.NullInt64
+		var purchase nullPurchase
+
+		dest := []interface{}{
+			&list.ID, &list.WorkspaceID, &list.UserID, &list.Title, &list.Status, &budget, &list.CompletedAt, &list.CreatedAt, &list.UpdatedAt, &list.DeletedAt,
+			&list.Version, &currency, &productID, &quantity, &checked, &productCreatedAt, &productUpdatedAt, &productDeletedAt,
+			&productTitle, &price, &categoryID, &categoryName, &categoryPosition,
+		}
+		if err := rows.Scan(append(dest, purchase.dest()...)...); err != nil {
+			return nil, err
+		}
+		if budget.Valid {
+			list.Budget = &money.Money{Amount: budget.Int64, Currency: currency}
+		}
+
+		// Rows are ordered by list, so a new list starts whenever the ID changes
+		if len(lists) == 0 || lists[len(lists)-1].ID != list.ID {
+			lists = append(lists, ListWithItems{List: list})
+		}
+
+		if productID.Valid {
+			item := ListItem{
+				ListID:       list.ID,
+				ProductID:    int(productID.Int64),
+				Quantity:     int(quantity.Int64),
+				Checked:      checked.Bool,
+				CreatedAt:    productCreatedAt.Time,
+				UpdatedAt:    productUpdatedAt.Time,
+				ProductTitle: productTitle.String,
+				Price:        money.New(price.Int64, currency),
+				Purchase:     purchase.purchase(currency),
+			}
+			if productDeletedAt.Valid {
+				item.DeletedAt = &productDeletedAt.Time
+			}
+			if categoryID.Valid {
+				item.Category = &Category{
+					ID:          int(categoryID.Int64),
+					WorkspaceID: list.WorkspaceID,
+					Name:        categoryName.String,
+					Position:    int(categoryPosition.Int64),
+				}
+			}
+			current := &lists[len(lists)-1]
+			current.Items = append(current.Items, item)
+		}
+	}
+	return lists, rows.Err()
+}
+
+func (m *MySQL) UpdateList(ctx context.Context, update *ListUpdate) error {
+	// Begin transaction
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	seq, err := nextChange(ctx, tx, update.WorkspaceID)
+	if err != nil {
+		return err
+	}
+
+	// Update the list title and status, keeping when it was first completed and soft deleting
+	// the list if status is "deleted" like SetListStatus
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `
+		UPDATE lists SET title = ?, status = ?, updated_at = ?, version = version + 1, change_seq = ?,
+		    completed_at = CASE WHEN ? = ? THEN COALESCE(completed_at, ?) END,
+		    deleted_at = CASE WHEN ? = ? THEN ? END
+		WHERE id = ? AND workspace_id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)`,
+		update.Title, update.Status, now, seq, update.Status, ListStatusCompleted, now, update.Status, ListStatusDeleted, now,
+		update.ListID, update.WorkspaceID, update.Version, update.Version,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return listVersionError(ctx, tx, update.ListID)
+	}
+
+	// Handle products in the list
+	for _, item := range update.Items {
+		if err := putItemTx(ctx, tx, update.ListID, item, seq, now); err != nil {
+			return err
+		}
+
+		if item.Check != nil {
+			if err := checkItemTx(ctx, tx, update.ListID, item.ProductID, *item.Check, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Soft delete products that are not in the request
+	query := "UPDATE list_products SET deleted_at = ?, change_seq = ? WHERE list_id = ? AND deleted_at IS NULL"
+	args := make([]interface{}, 0, len(update.Items)+3)
+	args = append(args, now, seq, update.ListID)
+
+	if len(update.Items) > 0 {
+		query += " AND product_id NOT IN ("
+		for i, item := range update.Items {
+			if i > 0 {
+				query += ", "
+			}
+			query += "?"
+			args = append(args, item.ProductID)
+		}
+		query += ")"
+	}
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+
+	// The list row stays locked by the update above, so this is the version written
+	var version int
+	if err := tx.QueryRowContext(ctx, "SELECT version FROM lists WHERE id = ?", update.ListID).Scan(&version); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	update.Version = version
+	return nil
+}
+
+func (m *MySQL) SetListStatus(ctx context.Context, listID, status, version int) (*List, error) {
+	// Begin transaction
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	seq, err := nextListChange(ctx, tx, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	// If status is "deleted", soft delete the list
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `
+		UPDATE lists SET status = ?, updated_at = ?, version = version + 1, change_seq = ?,
+		    completed_at = CASE WHEN ? = ? THEN COALESCE(completed_at, ?) END,
+		    deleted_at = CASE WHEN ? = ? THEN ? END
+		WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)`,
+		status, now, seq, status, ListStatusCompleted, now, status, ListStatusDeleted, now, listID, version, version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if affected == 0 {
+		return nil, listVersionError(ctx, tx, listID)
+	}
+
+	list, err := scanList(tx.QueryRowContext(ctx, "SELECT "+listColumns+" FROM lists WHERE id = ?", listID))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (m *MySQL) PatchList(ctx context.Context, patch ListPatch) (*List, error) {
+	// Begin transaction
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	seq, err := nextListChange(ctx, tx, patch.ListID)
+	if err != nil {
+		return nil, err
+	}
+
+	// MySQL assigns from left to right, so completed_at sees the new status
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `
+		UPDATE lists SET title = COALESCE(?, title), status = COALESCE(?, status),
+		    completed_at = CASE WHEN status = ? THEN COALESCE(completed_at, ?) END,
+		    updated_at = ?, version = version + 1, change_seq = ?
+		WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)`,
+		patch.Title, patch.Status, ListStatusCompleted, now, now, seq, patch.ListID, patch.Version, patch.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if affected == 0 {
+		return nil, listVersionError(ctx, tx, patch.ListID)
+	}
+
+	list, err := scanList(tx.QueryRowContext(ctx, "SELECT "+listColumns+" FROM lists WHERE id = ?", patch.ListID))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (m *MySQL) SetListBudget(ctx context.Context, listID int, budget *money.Money) error {
+	// Begin transaction
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	seq, err := nextListChange(ctx, tx, listID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE lists SET budget = ?, updated_at = ?, version = version + 1, change_seq = ? WHERE id = ? AND deleted_at IS NULL",
+		budgetAmount(budget), time.Now(), seq, listID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNotFound
+	}
+	return tx.Commit()
+}
+
+func (m *MySQL) ListMonthlySpending(ctx context.Context, workspaceID int, from, to time.Time) ([]MonthlySpending, error) {
+	// Only checked items were bought. Products deleted since were still bought, so their price is
+	// counted. Months are in UTC, the time zone of the connection.
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT DATE_FORMAT(l.completed_at, '%Y-%m') AS month, w.currency, COUNT(DISTINCT l.id),
+		       COALESCE(SUM(COALESCE(lp.actual_quantity, lp.quantity) * COALESCE(lp.actual_price, p.price)), 0)
+		FROM lists l
+		JOIN workspaces w ON w.id = l.workspace_id
+		LEFT JOIN list_products lp ON lp.list_id = l.id AND lp.deleted_at IS NULL AND lp.checked
+		LEFT JOIN products p ON p.id = lp.product_id
+		WHERE l.workspace_id = ? AND l.status = ? AND l.deleted_at IS NULL AND l.completed_at >= ? AND l.completed_at < ?
+		GROUP BY month, w.currency
+		ORDER BY month`,
+		workspaceID, ListStatusCompleted, from, to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var months []MonthlySpending
+	for rows.Next() {
+		var month MonthlySpending
+		if err := rows.Scan(&month.Month, &month.Total.Currency, &month.Lists, &month.Total.Amount); err != nil {
+			return nil, err
+		}
+		months = append(months, month)
+	}
+	return months, rows.Err()
+}
+
+func (m *MySQL) RemoveListItem(ctx context.Context, listID, productID, version int) (int, error) {
+	// Begin transaction
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	seq, err := nextListChange(ctx, tx, listID)
+	if err != nil {
+		return 0, err
+	}
+	version, err = lockListVersion(ctx, tx, listID, version)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx,
+		"UPDATE list_products SET deleted_at = ?, change_seq = ? WHERE list_id = ? AND product_id = ? AND deleted_at IS NULL",
+		now, seq, listID, productID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return 0, err
+	} else if affected > 0 {
+		if err := bumpListVersion(ctx, tx, listID, seq, now); err != nil {
+			return 0, err
+		}
+		version++
+	}
+	return version, tx.Commit()
+}
+
+func (m *MySQL) PutListItem(ctx context.Context, listID int, item ItemQuantity) (*ListItem, error) {
+	// Begin transaction
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	seq, err := nextListChange(ctx, tx, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := putItemTx(ctx, tx, listID, item, seq, now); err != nil {
+		return nil, err
+	}
+
+	if item.Check != nil {
+		if err := checkItemTx(ctx, tx, listID, item.ProductID, *item.Check, now); err != nil {
+			return nil, err
+		}
+	}
+	if err := bumpListVersion(ctx, tx, listID, seq, now); err != nil {
+		return nil, err
+	}
+
+	found, err := scanListItem(tx.QueryRowContext(ctx, "SELECT"+listItemColumns+" WHERE lp.list_id = ? AND lp.product_id = ?", listID, item.ProductID))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// putItemTx inserts a product into a list or sets its quantity as part of a transaction. A removed
+// item comes back unchecked, deleted_at is reset last so the other assignments still see whether it
+// was removed.
+func putItemTx(ctx context.Context, tx *sql.Tx, listID int, item ItemQuantity, seq int64, now time.Time) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO list_products (list_id, product_id, quantity, checked, created_at, updated_at, change_seq)
+		VALUES (?, ?, ?, FALSE, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+		    checked = IF(deleted_at IS NULL, checked, FALSE),
+		    checked_at = IF(deleted_at IS NULL, checked_at, NULL),
+		    checked_by = IF(deleted_at IS NULL, checked_by, NULL),
+		    actual_price = IF(deleted_at IS NULL, actual_price, NULL),
+		    actual_quantity = IF(deleted_at IS NULL, actual_quantity, NULL),
+		    shop_id = IF(deleted_at IS NULL, shop_id, NULL),
+		    price_point_id = IF(deleted_at IS NULL, price_point_id, NULL),
+		    quantity = VALUES(quantity), updated_at = VALUES(updated_at), change_seq = VALUES(change_seq),
+		    deleted_at = NULL`,
+		listID, item.ProductID, item.Quantity, now, now, seq,
+	)
+	return err
+}
+
+// bumpListVersion records a change to the items of a list
+func bumpListVersion(ctx context.Context, tx execer, listID int, seq int64, now time.Time) error {
+	_, err := tx.ExecContext(ctx, "UPDATE lists SET version = version + 1, updated_at = ?, change_seq = ? WHERE id = ?", now, seq, listID)
+	return err
+}
+
+// touchListItem records a change to an item in the change sequence and the version of its list
+func touchListItem(ctx context.Context, tx execer, listID, productID int, seq int64, now time.Time) error {
+	_, err := tx.ExecContext(ctx, "UPDATE list_products SET change_seq = ? WHERE list_id = ? AND product_id = ?", seq, listID, productID)
+	if err != nil {
+		return err
+	}
+	return bumpListVersion(ctx, tx, listID, seq, now)
+}
+
+// lockListVersion locks a list until the end of the transaction and returns its version. It returns
+// ErrNotFound if the list is deleted and ErrVersionConflict if version is not 0 and the list has another.
+func lockListVersion(ctx context.Context, tx *sql.Tx, listID, version int) (int, error) {
+	var current int
+	err := tx.QueryRowContext(ctx, "SELECT version FROM lists WHERE id = ? AND deleted_at IS NULL FOR UPDATE", listID).Scan(&current)
+	if err != nil {
+		return 0, notFound(err)
+	}
+	if version != 0 && version != current {
+		return 0, ErrVersionConflict
+	}
+	return current, nil
+}
+
+// rowQueryer is implemented by *sql.DB and *sql.Tx
+type rowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// listVersionError tells why a conditional update of a list changed nothing:
+// ErrNotFound if the list is gone, ErrVersionConflict otherwise
+func listVersionError(ctx context.Context, q rowQueryer, listID int) error {
+	var exists bool
+	err := q.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM lists WHERE id = ? AND deleted_at IS NULL)", listID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return ErrVersionConflict
+}
+
+// checkItemTx checks or unchecks an item of a list as part of a transaction, replacing the
+// price point recorded by a previous check
+func checkItemTx(ctx context.Context, tx *sql.Tx, listID, productID int, check ItemCheck, now time.Time) error {
+	var checked bool
+	var pricePointID sql.NullInt64
+	err := tx.QueryRowContext(ctx,
+		"SELECT checked, price_point_id FROM list_products WHERE list_id = ? AND product_id = ? AND deleted_at IS NULL FOR UPDATE",
+		listID, productID,
+	).Scan(&checked, &pricePointID)
+	if err != nil {
+		return notFound(err)
+	}
+
+	// Checking a checked item again without details keeps its purchase
+	if check.Checked && checked && check.Purchase == nil {
+		return nil
+	}
+
+	if pricePointID.Valid {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM price_history WHERE id = ?", pricePointID.Int64); err != nil {
+			return err
+		}
+	}
+
+	if !check.Checked {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE list_products SET checked = FALSE, checked_at = NULL, checked_by = NULL, actual_price = NULL,
+			    actual_quantity = NULL, shop_id = NULL, price_point_id = NULL, updated_at = ?
+			WHERE list_id = ? AND product_id = ?`,
+			now, listID, productID,
+		)
+		return err
+	}
+
+	var details PurchaseDetails
+	if check.Purchase != nil {
+		details = *check.Purchase
+	}
+
+	var unitPrice, pointID interface{}
+	if details.UnitPrice != nil {
+		point := &PricePoint{ProductID: productID, ShopID: details.ShopID, UserID: check.CheckedBy, Price: *details.UnitPrice, RecordedAt: now}
+		if err := insertPricePoint(ctx, tx, point); err != nil {
+			return err
+		}
+		unitPrice, pointID = details.UnitPrice.Amount, point.ID
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE list_products SET checked = TRUE, checked_at = ?, checked_by = ?, actual_price = ?,
+		    actual_quantity = ?, shop_id = ?, price_point_id = ?, updated_at = ?
+		WHERE list_id = ? AND product_id = ?`,
+		now, check.CheckedBy, unitPrice, details.Quantity, details.ShopID, pointID, now, listID, productID,
+	)
+	return err
+}
+
+// uncheckItemTx undoes the purchase of an item as part of a transaction. Unlike checkItemTx it
+// keeps the price point of the purchase, the price was paid even if the item is to be bought again.
+func uncheckItemTx(ctx context.Context, tx *sql.Tx, listID, productID int, now time.Time) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE list_products SET checked = FALSE, checked_at = NULL, checked_by = NULL, actual_price = NULL,
+		    actual_quantity = NULL, shop_id = NULL, price_point_id = NULL, updated_at = ?
+		WHERE list_id = ? AND product_id = ?`,
+		now, listID, productID,
+	)
+	return err
+}
+
+func (m *MySQL) CompleteAndCarryOver(ctx context.Context, carry CarryOver) (*List, *List, error) {
+	// Begin transaction
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	seq, err := nextListChange(ctx, tx, carry.ListID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `
+		UPDATE lists SET status = ?, updated_at = ?, version = version + 1, change_seq = ?, completed_at = COALESCE(completed_at, ?)
+		WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)`,
+		ListStatusCompleted, now, seq, now, carry.ListID, carry.Version, carry.Version,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return nil, nil, err
+	} else if affected == 0 {
+		return nil, nil, listVersionError(ctx, tx, carry.ListID)
+	}
+
+	completed, err := scanList(tx.QueryRowContext(ctx, "SELECT "+listColumns+" FROM lists WHERE id = ?", carry.ListID))
+	if err != nil {
+		return nil, nil, notFound(err)
+	}
+
+	targetID := carry.TargetID
+	if targetID == 0 {
+		result, err := tx.ExecContext(ctx,
+			"INSERT INTO lists (workspace_id, user_id, title, budget, created_at, updated_at, change_seq) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			completed.WorkspaceID, carry.NewList.UserID, carry.NewList.Title, budgetAmount(carry.NewList.Budget), now, now, seq,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, nil, err
+		}
+		targetID = int(id)
+	} else {
+		// The list was completed above, so carrying it over to itself fails here too
+		var workspaceID, status int
+		err := tx.QueryRowContext(ctx,
+			"SELECT workspace_id, status FROM lists WHERE id = ? AND deleted_at IS NULL FOR UPDATE", targetID,
+		).Scan(&workspaceID, &status)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrInactiveList
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if workspaceID != completed.WorkspaceID || status != ListStatusActive {
+			return nil, nil, ErrInactiveList
+		}
+	}
+
+	productIDs, err := queryProductIDs(ctx, tx,
+		"SELECT product_id FROM list_products WHERE list_id = ? AND deleted_at IS NULL AND checked = FALSE FOR UPDATE",
+		carry.ListID,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, productID := range productIDs {
+		if err := moveItem(ctx, tx, carry.ListID, targetID, productID, seq, now); err != nil {
+			return nil, nil, err
+		}
+	}
+	// A new list starts at its first version with the items in it
+	if carry.TargetID != 0 {
+		if err := bumpListVersion(ctx, tx, targetID, seq, now); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	target, err := scanList(tx.QueryRowContext(ctx, "SELECT "+listColumns+" FROM lists WHERE id = ?", targetID))
+	if err != nil {
+		return nil, nil, notFound(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return completed, target, nil
+}
+
+func (m *MySQL) MergeLists(ctx context.Context, merge ListMerge) (*List, []ListItem, error) {
+	// Begin transaction, a dry run is rolled back once the merged list is read
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	seq, err := nextListChange(ctx, tx, merge.TargetID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var workspaceID, status, version int
+	err = tx.QueryRowContext(ctx,
+		"SELECT workspace_id, status, version FROM lists WHERE id = ? AND deleted_at IS NULL FOR UPDATE", merge.TargetID,
+	).Scan(&workspaceID, &status, &version)
+	if err != nil {
+		return nil, nil, notFound(err)
+	}
+	if merge.Version != 0 && merge.Version != version {
+		return nil, nil, ErrVersionConflict
+	}
+	if status != ListStatusActive {
+		return nil, nil, ErrInactiveList
+	}
+
+	// Soft delete the source list
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `
+		UPDATE lists SET status = ?, deleted_at = ?, updated_at = ?, version = version + 1, change_seq = ?
+		WHERE id = ? AND workspace_id = ? AND id <> ? AND deleted_at IS NULL`,
+		ListStatusDeleted, now, now, seq, merge.SourceID, workspaceID, merge.TargetID,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return nil, nil, err
+	} else if affected == 0 {
+		return nil, nil, ErrNotFound
+	}
+
+	productIDs, err := queryProductIDs(ctx, tx,
+		"SELECT product_id FROM list_products WHERE list_id = ? AND deleted_at IS NULL FOR UPDATE",
+		merge.SourceID,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, productID := range productIDs {
+		if err := moveItem(ctx, tx, merge.SourceID, merge.TargetID, productID, seq, now); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := bumpListVersion(ctx, tx, merge.TargetID, seq, now); err != nil {
+		return nil, nil, err
+	}
+
+	target, err := scanList(tx.QueryRowContext(ctx, "SELECT "+listColumns+" FROM lists WHERE id = ?", merge.TargetID))
+	if err != nil {
+		return nil, nil, notFound(err)
+	}
+	items, err := queryListItems(ctx, tx, merge.TargetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if merge.DryRun {
+		return target, items, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return target, items, nil
+}
+
+// queryProductIDs returns the product IDs selected by a query
+func queryProductIDs(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var productIDs []int
+	for rows.Next() {
+		var productID int
+		if err := rows.Scan(&productID); err != nil {
+			return nil, err
+		}
+		productIDs = append(productIDs, productID)
+	}
+	return productIDs, rows.Err()
+}
+
+// moveItem moves an active item of a list to another list as part of a transaction. If the product
+// is not in the other list the item moves with its purchase and price point. Otherwise the
+// quantities are added up, and the item stays checked only if it was checked in both lists,
+// keeping the purchase of the other list; a purchase that no longer applies is undone, but its
+// price point stays in the price history. The item is soft deleted from the list it leaves. The caller bumps the
+// versions of both lists.
+func moveItem(ctx context.Context, tx *sql.Tx, fromID, toID, productID int, seq int64, now time.Time) error {
+	var quantity int
+	var checked bool
+	var purchase nullPurchase
+	var pricePointID sql.NullInt64
+	err := tx.QueryRowContext(ctx, `
+		SELECT quantity, checked, checked_at, checked_by, actual_price, actual_quantity, shop_id, price_point_id
+		FROM list_products WHERE list_id = ? AND product_id = ? AND deleted_at IS NULL FOR UPDATE`,
+		fromID, productID,
+	).Scan(append(append([]interface{}{&quantity, &checked}, purchase.dest()...), &pricePointID)...)
+	if err != nil {
+		return notFound(err)
+	}
+
+	var targetChecked, targetDeleted bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT checked, deleted_at IS NOT NULL FROM list_products WHERE list_id = ? AND product_id = ? FOR UPDATE",
+		toID, productID,
+	).Scan(&targetChecked, &targetDeleted)
+	moved := errors.Is(err, sql.ErrNoRows) || (err == nil && targetDeleted)
+	switch {
+	case moved:
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO list_products (list_id, product_id, quantity, checked, checked_at, checked_by, actual_price,
+			    actual_quantity, shop_id, price_point_id, created_at, updated_at, change_seq)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE
+			    quantity = VALUES(quantity), checked = VALUES(checked), checked_at = VALUES(checked_at),
+			    checked_by = VALUES(checked_by), actual_price = VALUES(actual_price),
+			    actual_quantity = VALUES(actual_quantity), shop_id = VALUES(shop_id),
+			    price_point_id = VALUES(price_point_id), updated_at = VALUES(updated_at),
+			    change_seq = VALUES(change_seq), deleted_at = NULL`,
+			toID, productID, quantity, checked, purchase.CheckedAt, purchase.CheckedBy, purchase.UnitPrice,
+			purchase.Quantity, purchase.Shop, pricePointID, now, now, seq,
+		)
+		if err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		if targetChecked && !checked {
+			if err := uncheckItemTx(ctx, tx, toID, productID, now); err != nil {
+				return err
+			}
+		}
+		if checked && !targetChecked {
+			if err := uncheckItemTx(ctx, tx, fromID, productID, now); err != nil {
+				return err
+			}
+		}
+		_, err = tx.ExecContext(ctx,
+			"UPDATE list_products SET quantity = quantity + ?, updated_at = ?, change_seq = ? WHERE list_id = ? AND product_id = ?",
+			quantity, now, seq, toID, productID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// A moved price point belongs to the item in the other list now
+	_, err = tx.ExecContext(ctx, `
+		UPDATE list_products SET deleted_at = ?, change_seq = ?, price_point_id = IF(?, NULL, price_point_id)
+		WHERE list_id = ? AND product_id = ?`,
+		now, seq, moved, fromID, productID,
+	)
+	return err
+}
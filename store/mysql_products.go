@@ -0,0 +1,361 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"shopping_list/money"
+)
+
+// productColumns selects a product with the currency of its workspace
+const productColumns = `id, title, amount_type, price, deleted_at, workspace_id, category_id, created_at, version,
+	COALESCE((SELECT w.currency FROM workspaces w WHERE w.id = products.workspace_id), '` + money.DefaultCurrency + `')`
+
+func scanProduct(row scanner) (*Product, error) {
+	var product Product
+	err := row.Scan(&product.ID, &product.Title, &product.AmountType, &product.Price.Amount, &product.DeletedAt,
+		&product.WorkspaceID, &product.CategoryID, &product.CreatedAt, &product.Version, &product.Price.Currency)
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (m *MySQL) CreateProduct(ctx context.Context, product *Product, source PriceSource) error {
+	// Begin transaction
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	seq, err := nextChange(ctx, tx, product.WorkspaceID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	query := `INSERT INTO products (title, amount_type, price, workspace_id, category_id, created_at, change_seq) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	result, err := tx.ExecContext(ctx, query, product.Title, product.AmountType, product.Price.Amount, product.WorkspaceID, product.CategoryID, now, seq)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if err := insertPricePoint(ctx, tx, &PricePoint{ProductID: int(id), ShopID: source.ShopID, UserID: source.UserID, Price: product.Price, RecordedAt: now}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	product.ID = int(id)
+	product.CreatedAt = now
+	product.Version = 1
+	return nil
+}
+
+func (m *MySQL) GetProduct(ctx context.Context, id int) (*Product, error) {
+	product, err := scanProduct(m.db.QueryRowContext(ctx, "SELECT "+productColumns+" FROM products WHERE id = ? AND deleted_at IS NULL", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return product, nil
+}
+
+// productSortColumns maps the product sort fields to their columns
+var productSortColumns = map[ProductSort]string{
+	ProductSortTitle:   "title",
+	ProductSortPrice:   "price",
+	ProductSortCreated: "created_at",
+}
+
+func (m *MySQL) ListProducts(ctx context.Context, filter ProductFilter) (*ProductPage, error) {
+	where := "workspace_id = ? AND deleted_at IS NULL"
+	args := []interface{}{filter.WorkspaceID}
+
+	if filter.Name != "" {
+		where += " AND title LIKE ?"
+		args = append(args, "%"+filter.Name+"%")
+	}
+	if filter.AmountType != "" {
+		where += " AND amount_type = ?"
+		args = append(args, filter.AmountType)
+	}
+	if filter.MinPrice != nil {
+		where += " AND price >= ?"
+		args = append(args, *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		where += " AND price <= ?"
+		args = append(args, *filter.MaxPrice)
+	}
+
+	page := &ProductPage{}
+	if err := m.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM products WHERE "+where, args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	column := productSortColumns[filter.Sort]
+	direction, compare := "ASC", ">"
+	if filter.Desc {
+		direction, compare = "DESC", "<"
+	}
+
+	// Continue after the last product of the previous page, the ID breaks ties
+	if filter.After != "" {
+		after, err := decodeCursor(filter.After, productCursorKey(filter))
+		if err != nil {
+			return nil, err
+		}
+
+		var sortValue interface{} = after.Value
+		switch filter.Sort {
+		case ProductSortPrice:
+			price, err := strconv.ParseInt(after.Value, 10, 64)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			sortValue = price
+		case ProductSortCreated:
+			createdAt, err := time.Parse(time.RFC3339Nano, after.Value)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			sortValue = createdAt
+		}
+		where += fmt.Sprintf(" AND (%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, compare)
+		args = append(args, sortValue, sortValue, after.ID)
+	}
+
+	// Fetch one extra product to know whether there is a next page
+	query := fmt.Sprintf(
+		"SELECT %s FROM products WHERE %s ORDER BY %s %s, id %s LIMIT ?",
+		productColumns,
+		where, column, direction, direction,
+	)
+	args = append(args, filter.Limit+1)
+
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Products = append(page.Products, *product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Products) > filter.Limit {
+		page.Products = page.Products[:filter.Limit]
+		last := page.Products[len(page.Products)-1]
+		page.NextCursor = encodeCursor(cursor{Key: productCursorKey(filter), Value: productSortValue(last, filter.Sort), ID: last.ID})
+	}
+	return page, nil
+}
+
+func (m *MySQL) UpdateProduct(ctx context.Context, product *Product, source PriceSource) error {
+	// Begin transaction
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	seq, err := nextProductChange(ctx, tx, product.ID)
+	if err != nil {
+		return err
+	}
+
+	// Lock the product so concurrent updates record the price changes in order
+	var previous int64
+	var version int
+	err = tx.QueryRowContext(ctx, "SELECT price, version FROM products WHERE id = ? AND deleted_at IS NULL FOR UPDATE", product.ID).Scan(&previous, &version)
+	if err != nil {
+		return notFound(err)
+	}
+	if product.Version != 0 && product.Version != version {
+		return ErrVersionConflict
+	}
+
+	query := `UPDATE products SET title = ?, amount_type = ?, price = ?, category_id = ?, version = version + 1, change_seq = ? WHERE id = ?`
+	_, err = tx.ExecContext(ctx, query, product.Title, product.AmountType, product.Price.Amount, product.CategoryID, seq, product.ID)
+	if err != nil {
+		return err
+	}
+
+	if previous != product.Price.Amount {
+		err := insertPricePoint(ctx, tx, &PricePoint{ProductID: product.ID, ShopID: source.ShopID, UserID: source.UserID, Price: product.Price, RecordedAt: time.Now()})
+		if err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	product.Version = version + 1
+	return nil
+}
+
+func (m *MySQL) DeleteProduct(ctx context.Context, id, version int) error {
+	// Begin transaction
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	seq, err := nextProductChange(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE products SET deleted_at = ?, version = version + 1, change_seq = ? WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)`
+	result, err := tx.ExecContext(ctx, query, time.Now(), seq, id, version, version)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		var exists bool
+		err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM products WHERE id = ? AND deleted_at IS NULL)", id).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrNotFound
+		}
+		return ErrVersionConflict
+	}
+	return tx.Commit()
+}
+
+func (m *MySQL) AddPricePoint(ctx context.Context, point *PricePoint) error {
+	return insertPricePoint(ctx, m.db, point)
+}
+
+func (m *MySQL) ListPriceHistory(ctx context.Context, filter PriceHistoryFilter) ([]PricePoint, error) {
+	currency, err := m.productCurrency(ctx, filter.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	where, args := priceHistoryWhere(filter)
+	rows, err := m.db.QueryContext(ctx,
+		"SELECT id, product_id, shop_id, user_id, price, recorded_at FROM price_history WHERE "+where+" ORDER BY recorded_at, id",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []PricePoint
+	for rows.Next() {
+		point := PricePoint{Price: money.New(0, currency)}
+		if err := rows.Scan(&point.ID, &point.ProductID, &point.ShopID, &point.UserID, &point.Price.Amount, &point.RecordedAt); err != nil {
+			return nil, err
+		}
+		points = append(points, point)
+	}
+	return points, rows.Err()
+}
+
+func (m *MySQL) GetPriceStats(ctx context.Context, filter PriceHistoryFilter) (*PriceStats, error) {
+	currency, err := m.productCurrency(ctx, filter.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	where, args := priceHistoryWhere(filter)
+
+	// ROUND on the exact DECIMAL average rounds half away from zero
+	var stats PriceStats
+	var min, max, avg sql.NullInt64
+	err = m.db.QueryRowContext(ctx,
+		"SELECT COUNT(*), MIN(price), MAX(price), CAST(ROUND(AVG(price)) AS SIGNED) FROM price_history WHERE "+where,
+		args...,
+	).Scan(&stats.Count, &min, &max, &avg)
+	if err != nil {
+		return nil, err
+	}
+	stats.Min = money.New(min.Int64, currency)
+	stats.Max = money.New(max.Int64, currency)
+	stats.Avg = money.New(avg.Int64, currency)
+	return &stats, nil
+}
+
+// productCurrency returns the currency of the workspace of a product
+func (m *MySQL) productCurrency(ctx context.Context, productID int) (string, error) {
+	var currency string
+	err := m.db.QueryRowContext(ctx,
+		"SELECT w.currency FROM products p JOIN workspaces w ON w.id = p.workspace_id WHERE p.id = ?",
+		productID,
+	).Scan(&currency)
+	if err == sql.ErrNoRows {
+		return money.DefaultCurrency, nil
+	}
+	return currency, err
+}
+
+// priceHistoryWhere builds the condition selecting the price points matching the filter
+func priceHistoryWhere(filter PriceHistoryFilter) (string, []interface{}) {
+	where := "product_id = ?"
+	args := []interface{}{filter.ProductID}
+	if filter.ShopID != nil {
+		where += " AND shop_id = ?"
+		args = append(args, *filter.ShopID)
+	}
+	if filter.From != nil {
+		where += " AND recorded_at >= ?"
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		where += " AND recorded_at < ?"
+		args = append(args, *filter.To)
+	}
+	return where, args
+}
+
+// execer is implemented by *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertPricePoint records a price point, on its own or as part of a transaction
+func insertPricePoint(ctx context.Context, tx execer, point *PricePoint) error {
+	if point.RecordedAt.IsZero() {
+		point.RecordedAt = time.Now()
+	}
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO price_history (product_id, shop_id, user_id, price, recorded_at) VALUES (?, ?, ?, ?, ?)",
+		point.ProductID, point.ShopID, point.UserID, point.Price.Amount, point.RecordedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	point.ID = int(id)
+	return nil
+}
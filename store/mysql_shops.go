@@ -0,0 +1,121 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+func (m *MySQL) CreateShop(ctx context.Context, shop *Shop) error {
+	now := time.Now()
+	result, err := m.db.ExecContext(ctx,
+		"INSERT INTO shops (workspace_id, name, created_at, updated_at) VALUES (?, ?, ?, ?)",
+		shop.WorkspaceID, shop.Name, now, now,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	shop.ID = int(id)
+	shop.CreatedAt = now
+	shop.UpdatedAt = now
+	return nil
+}
+
+func (m *MySQL) GetShop(ctx context.Context, id int) (*Shop, error) {
+	var shop Shop
+	err := m.db.QueryRowContext(ctx,
+		"SELECT id, workspace_id, name, created_at, updated_at, deleted_at FROM shops WHERE id = ? AND deleted_at IS NULL",
+		id,
+	).Scan(&shop.ID, &shop.WorkspaceID, &shop.Name, &shop.CreatedAt, &shop.UpdatedAt, &shop.DeletedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &shop, nil
+}
+
+func (m *MySQL) ListShops(ctx context.Context, workspaceID int) ([]Shop, error) {
+	rows, err := m.db.QueryContext(ctx,
+		"SELECT id, workspace_id, name, created_at, updated_at, deleted_at FROM shops WHERE workspace_id = ? AND deleted_at IS NULL ORDER BY name, id",
+		workspaceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shops []Shop
+	for rows.Next() {
+		var shop Shop
+		if err := rows.Scan(&shop.ID, &shop.WorkspaceID, &shop.Name, &shop.CreatedAt, &shop.UpdatedAt, &shop.DeletedAt); err != nil {
+			return nil, err
+		}
+		shops = append(shops, shop)
+	}
+	return shops, rows.Err()
+}
+
+func (m *MySQL) UpdateShop(ctx context.Context, shop *Shop) error {
+	now := time.Now()
+	_, err := m.db.ExecContext(ctx, "UPDATE shops SET name = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL", shop.Name, now, shop.ID)
+	if err != nil {
+		return err
+	}
+	shop.UpdatedAt = now
+	return nil
+}
+
+func (m *MySQL) DeleteShop(ctx context.Context, id int) error {
+	_, err := m.db.ExecContext(ctx, "UPDATE shops SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL", time.Now(), id)
+	return err
+}
+
+func (m *MySQL) GetShopLayout(ctx context.Context, shopID int) ([]ShopAisle, error) {
+	// Aisles of deleted categories are left out
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT sa.category_id, sa.position, sa.aisle
+		FROM shop_aisles sa
+		JOIN categories c ON c.id = sa.category_id AND c.deleted_at IS NULL
+		WHERE sa.shop_id = ?
+		ORDER BY sa.position, sa.category_id`, shopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aisles []ShopAisle
+	for rows.Next() {
+		var aisle ShopAisle
+		if err := rows.Scan(&aisle.CategoryID, &aisle.Position, &aisle.Aisle); err != nil {
+			return nil, err
+		}
+		aisles = append(aisles, aisle)
+	}
+	return aisles, rows.Err()
+}
+
+func (m *MySQL) SetShopLayout(ctx context.Context, shopID int, aisles []ShopAisle) error {
+	// Begin transaction
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM shop_aisles WHERE shop_id = ?", shopID); err != nil {
+		return err
+	}
+	for _, aisle := range aisles {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO shop_aisles (shop_id, category_id, position, aisle) VALUES (?, ?, ?, ?)",
+			shopID, aisle.CategoryID, aisle.Position, aisle.Aisle,
+		)
+		if err != nil {
+			return duplicate(err)
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,182 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// nextChange returns the next number of the change sequence of a workspace. The counter row
+// stays locked until the transaction ends, so the writes of a workspace commit in sequence
+// order and a reader never sees a number before the ones below it. Transactions take it
+// before any other lock of the workspace so they queue up instead of deadlocking.
+func nextChange(ctx context.Context, tx *sql.Tx, workspaceID int) (int64, error) {
+	_, err := tx.ExecContext(ctx, "UPDATE workspaces SET change_seq = change_seq + 1, updated_at = updated_at WHERE id = ?", workspaceID)
+	if err != nil {
+		return 0, err
+	}
+
+	var seq int64
+	err = tx.QueryRowContext(ctx, "SELECT change_seq FROM workspaces WHERE id = ?", workspaceID).Scan(&seq)
+	if err != nil {
+		return 0, notFound(err)
+	}
+	return seq, nil
+}
+
+// nextListChange returns the next change number of the workspace of a list
+func nextListChange(ctx context.Context, tx *sql.Tx, listID int) (int64, error) {
+	var workspaceID int
+	if err := tx.QueryRowContext(ctx, "SELECT workspace_id FROM lists WHERE id = ?", listID).Scan(&workspaceID); err != nil {
+		return 0, notFound(err)
+	}
+	return nextChange(ctx, tx, workspaceID)
+}
+
+// nextProductChange returns the next change number of the workspace of a product
+func nextProductChange(ctx context.Context, tx *sql.Tx, productID int) (int64, error) {
+	var workspaceID int
+	if err := tx.QueryRowContext(ctx, "SELECT workspace_id FROM products WHERE id = ?", productID).Scan(&workspaceID); err != nil {
+		return 0, notFound(err)
+	}
+	return nextChange(ctx, tx, workspaceID)
+}
+
+// extraColumns scans the columns selected after the ones read by a scan function
+type extraColumns struct {
+	row   scanner
+	extra []interface{}
+}
+
+func (e extraColumns) Scan(dest ...interface{}) error {
+	return e.row.Scan(append(dest, e.extra...)...)
+}
+
+// changesAfter builds the condition selecting the rows of a kind that come after a position of
+// the feed, given the sequence and ID columns of the kind
+func changesAfter(kind int, from changeKey, seqColumn, idColumn, itemColumn string) (string, []interface{}) {
+	switch {
+	case kind > from.Kind:
+		return seqColumn + " >= ?", []interface{}{from.Seq}
+	case kind < from.Kind:
+		return seqColumn + " > ?", []interface{}{from.Seq}
+	case itemColumn == "":
+		return fmt.Sprintf("(%[1]s > ? OR (%[1]s = ? AND %[2]s > ?))", seqColumn, idColumn),
+			[]interface{}{from.Seq, from.Seq, from.ID}
+	default:
+		return fmt.Sprintf("(%[1]s > ? OR (%[1]s = ? AND (%[2]s > ? OR (%[2]s = ? AND %[3]s > ?))))", seqColumn, idColumn, itemColumn),
+			[]interface{}{from.Seq, from.Seq, from.ID, from.ID, from.ItemID}
+	}
+}
+
+func (m *MySQL) ListChanges(ctx context.Context, workspaceID int, after string, limit int) (*ChangePage, error) {
+	from, err := decodeChangeCursor(workspaceID, after)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each kind is read up to one change past the page, the page is cut after merging them
+	var changes []keyedChange
+
+	where, args := changesAfter(changeKindProduct, from, "change_seq", "id", "")
+	rows, err := m.db.QueryContext(ctx,
+		"SELECT "+productColumns+", change_seq FROM products WHERE workspace_id = ? AND "+where+" ORDER BY change_seq, id LIMIT ?",
+		append(append([]interface{}{workspaceID}, args...), limit+1)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var seq int64
+		product, err := scanProduct(extraColumns{rows, []interface{}{&seq}})
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, keyedChange{
+			key:    changeKey{Seq: seq, Kind: changeKindProduct, ID: product.ID},
+			Change: Change{Type: ChangeProduct, Deleted: product.DeletedAt != nil, Product: product},
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	where, args = changesAfter(changeKindList, from, "change_seq", "id", "")
+	rows, err = m.db.QueryContext(ctx,
+		"SELECT "+listColumns+", change_seq FROM lists WHERE workspace_id = ? AND "+where+" ORDER BY change_seq, id LIMIT ?",
+		append(append([]interface{}{workspaceID}, args...), limit+1)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var seq int64
+		list, err := scanList(extraColumns{rows, []interface{}{&seq}})
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, keyedChange{
+			key:    changeKey{Seq: seq, Kind: changeKindList, ID: list.ID},
+			Change: Change{Type: ChangeList, Deleted: list.DeletedAt != nil, List: list},
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	where, args = changesAfter(changeKindListItem, from, "lp.change_seq", "lp.list_id", "lp.product_id")
+	rows, err = m.db.QueryContext(ctx,
+		"SELECT"+listItemFields+", lp.deleted_at, lp.change_seq"+listItemTables+
+			" JOIN lists l ON l.id = lp.list_id WHERE l.workspace_id = ? AND "+where+
+			" ORDER BY lp.change_seq, lp.list_id, lp.product_id LIMIT ?",
+		append(append([]interface{}{workspaceID}, args...), limit+1)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var seq int64
+		var deletedAt sql.NullTime
+		item, err := scanListItem(extraColumns{rows, []interface{}{&deletedAt, &seq}})
+		if err != nil {
+			return nil, err
+		}
+		if deletedAt.Valid {
+			item.DeletedAt = &deletedAt.Time
+		}
+		changes = append(changes, keyedChange{
+			key:    changeKey{Seq: seq, Kind: changeKindListItem, ID: item.ListID, ItemID: item.ProductID},
+			Change: Change{Type: ChangeListItem, Deleted: item.DeletedAt != nil, Item: item},
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].key.less(changes[j].key) })
+	return changePage(workspaceID, after, changes, limit), nil
+}
+
+func (m *MySQL) GetMutationResult(ctx context.Context, workspaceID, userID int, key string) ([]byte, error) {
+	var result []byte
+	err := m.db.QueryRowContext(ctx,
+		"SELECT result FROM sync_mutations WHERE workspace_id = ? AND user_id = ? AND idempotency_key = ?",
+		workspaceID, userID, key,
+	).Scan(&result)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return result, nil
+}
+
+func (m *MySQL) SaveMutationResult(ctx context.Context, workspaceID, userID int, key string, result []byte) error {
+	_, err := m.db.ExecContext(ctx,
+		"INSERT INTO sync_mutations (workspace_id, user_id, idempotency_key, result) VALUES (?, ?, ?, ?)",
+		workspaceID, userID, key, result,
+	)
+	return duplicate(err)
+}
@@ -0,0 +1,227 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const templateColumns = "id, workspace_id, user_id, title, recurrence_weekday, recurrence_interval_weeks, next_run_at, created_at, updated_at, deleted_at"
+
+func scanTemplate(row scanner) (*ListTemplate, error) {
+	var template ListTemplate
+	var weekday, interval sql.NullInt64
+	err := row.Scan(&template.ID, &template.WorkspaceID, &template.UserID, &template.Title, &weekday, &interval,
+		&template.NextRunAt, &template.CreatedAt, &template.UpdatedAt, &template.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+	if weekday.Valid && interval.Valid {
+		template.Recurrence = &Recurrence{Weekday: time.Weekday(weekday.Int64), IntervalWeeks: int(interval.Int64)}
+	}
+	return &template, nil
+}
+
+// recurrenceColumns returns the values of the recurrence columns of a template
+func recurrenceColumns(template *ListTemplate) (weekday, interval, nextRunAt interface{}) {
+	if template.Recurrence == nil {
+		return nil, nil, nil
+	}
+	return int(template.Recurrence.Weekday), template.Recurrence.IntervalWeeks, template.NextRunAt
+}
+
+func (m *MySQL) CreateTemplate(ctx context.Context, template *ListTemplate) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	now := time.Now()
+	weekday, interval, nextRunAt := recurrenceColumns(template)
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO list_templates (workspace_id, user_id, title, recurrence_weekday, recurrence_interval_weeks, next_run_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		template.WorkspaceID, template.UserID, template.Title, weekday, interval, nextRunAt, now, now,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if err := insertTemplateItems(ctx, tx, int(id), template.Items); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	template.ID = int(id)
+	template.CreatedAt = now
+	template.UpdatedAt = now
+	return nil
+}
+
+func (m *MySQL) GetTemplate(ctx context.Context, id int) (*ListTemplate, error) {
+	template, err := scanTemplate(m.db.QueryRowContext(ctx, "SELECT "+templateColumns+" FROM list_templates WHERE id = ? AND deleted_at IS NULL", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	templates := []ListTemplate{*template}
+	if err := m.loadTemplateItems(ctx, templates); err != nil {
+		return nil, err
+	}
+	return &templates[0], nil
+}
+
+func (m *MySQL) ListTemplates(ctx context.Context, workspaceID int) ([]ListTemplate, error) {
+	return m.queryTemplates(ctx,
+		"SELECT "+templateColumns+" FROM list_templates WHERE workspace_id = ? AND deleted_at IS NULL ORDER BY title, id",
+		workspaceID,
+	)
+}
+
+func (m *MySQL) UpdateTemplate(ctx context.Context, template *ListTemplate) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	weekday, interval, nextRunAt := recurrenceColumns(template)
+	result, err := tx.ExecContext(ctx,
+		"UPDATE list_templates SET title = ?, recurrence_weekday = ?, recurrence_interval_weeks = ?, next_run_at = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL",
+		template.Title, weekday, interval, nextRunAt, time.Now(), template.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM list_template_products WHERE template_id = ?", template.ID); err != nil {
+		return err
+	}
+	if err := insertTemplateItems(ctx, tx, template.ID, template.Items); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *MySQL) DeleteTemplate(ctx context.Context, id int) error {
+	result, err := m.db.ExecContext(ctx,
+		"UPDATE list_templates SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL",
+		time.Now(), id,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *MySQL) ListDueTemplates(ctx context.Context, now time.Time) ([]ListTemplate, error) {
+	return m.queryTemplates(ctx,
+		"SELECT "+templateColumns+" FROM list_templates WHERE next_run_at <= ? AND deleted_at IS NULL ORDER BY id",
+		now,
+	)
+}
+
+func (m *MySQL) AdvanceTemplateRun(ctx context.Context, id int, run, next time.Time) error {
+	result, err := m.db.ExecContext(ctx,
+		"UPDATE list_templates SET next_run_at = ?, updated_at = updated_at WHERE id = ? AND next_run_at = ? AND deleted_at IS NULL",
+		next, id, run,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// queryTemplates returns the templates selected by a query of templateColumns with their items
+func (m *MySQL) queryTemplates(ctx context.Context, query string, args ...interface{}) ([]ListTemplate, error) {
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []ListTemplate
+	for rows.Next() {
+		template, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *template)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := m.loadTemplateItems(ctx, templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// loadTemplateItems sets the items of the templates, leaving out deleted products
+func (m *MySQL) loadTemplateItems(ctx context.Context, templates []ListTemplate) error {
+	for i := range templates {
+		rows, err := m.db.QueryContext(ctx,
+			`SELECT ltp.product_id, ltp.quantity, p.title
+			FROM list_template_products ltp
+			JOIN products p ON p.id = ltp.product_id
+			WHERE ltp.template_id = ? AND p.deleted_at IS NULL
+			ORDER BY p.title, p.id`,
+			templates[i].ID,
+		)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var item TemplateItem
+			if err := rows.Scan(&item.ProductID, &item.Quantity, &item.ProductTitle); err != nil {
+				rows.Close()
+				return err
+			}
+			templates[i].Items = append(templates[i].Items, item)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertTemplateItems stores the items of a template
+func insertTemplateItems(ctx context.Context, tx *sql.Tx, templateID int, items []TemplateItem) error {
+	for _, item := range items {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO list_template_products (template_id, product_id, quantity) VALUES (?, ?, ?)",
+			templateID, item.ProductID, item.Quantity,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
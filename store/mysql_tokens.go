@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+func (m *MySQL) CreateRefreshToken(ctx context.Context, token *RefreshToken) error {
+	token.CreatedAt = time.Now()
+	result, err := m.db.ExecContext(ctx,
+		"INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?, ?)",
+		token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt, token.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	token.ID = int(id)
+	return nil
+}
+
+func (m *MySQL) GetRefreshTokenByHash(ctx context.Context, hash string) (*RefreshToken, error) {
+	var token RefreshToken
+	err := m.db.QueryRowContext(ctx,
+		"SELECT id, user_id, family_id, token_hash, expires_at, used_at, revoked_at, created_at FROM refresh_tokens WHERE token_hash = ?",
+		hash,
+	).Scan(&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash, &token.ExpiresAt, &token.UsedAt, &token.RevokedAt, &token.CreatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &token, nil
+}
+
+func (m *MySQL) RotateRefreshToken(ctx context.Context, oldID int, next *RefreshToken) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx,
+		"UPDATE refresh_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL AND revoked_at IS NULL",
+		now, oldID,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Another request rotated or revoked the token first
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrTokenUsed
+	}
+
+	result, err = tx.ExecContext(ctx,
+		"INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?, ?)",
+		next.UserID, next.FamilyID, next.TokenHash, next.ExpiresAt, now,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	next.ID = int(id)
+	next.CreatedAt = now
+	return nil
+}
+
+func (m *MySQL) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	_, err := m.db.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL",
+		time.Now(), familyID,
+	)
+	return err
+}
+
+func (m *MySQL) RevokeAccessToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	// Tokens that expired since they were revoked are rejected anyway
+	now := time.Now()
+	if _, err := m.db.ExecContext(ctx, "DELETE FROM revoked_tokens WHERE expires_at < ?", now); err != nil {
+		return err
+	}
+
+	_, err := m.db.ExecContext(ctx,
+		"INSERT IGNORE INTO revoked_tokens (jti, user_id, expires_at, revoked_at) VALUES (?, ?, ?, ?)",
+		jti, userID, expiresAt, now,
+	)
+	return err
+}
+
+func (m *MySQL) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	err := m.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = ?)", jti).Scan(&revoked)
+	return revoked, err
+}
+
+func (m *MySQL) RevokeUserTokens(ctx context.Context, userID int) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET token = NULL, tokens_revoked_at = ? WHERE id = ?", now, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL", now, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *MySQL) GetUserTokensRevokedAt(ctx context.Context, userID int) (*time.Time, error) {
+	var revokedAt *time.Time
+	err := m.db.QueryRowContext(ctx, "SELECT tokens_revoked_at FROM users WHERE id = ?", userID).Scan(&revokedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return revokedAt, nil
+}
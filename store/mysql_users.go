@@ -0,0 +1,40 @@
+package store
+
+import "context"
+
+func (m *MySQL) CreateUser(ctx context.Context, user *User) error {
+	result, err := m.db.ExecContext(ctx, "INSERT INTO users (email, password, name) VALUES (?, ?, ?)", user.Email, user.PasswordHash, user.Name)
+	if err != nil {
+		return duplicate(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	user.ID = int(id)
+	return nil
+}
+
+func (m *MySQL) GetUserByID(ctx context.Context, id int) (*User, error) {
+	var user User
+	err := m.db.QueryRowContext(ctx, "SELECT id, name, email, password FROM users WHERE id = ? AND deleted_at IS NULL", id).Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (m *MySQL) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	var user User
+	err := m.db.QueryRowContext(ctx, "SELECT id, name, email, password FROM users WHERE email = ? AND deleted_at IS NULL", email).Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (m *MySQL) SetUserToken(ctx context.Context, userID int, token *string) error {
+	_, err := m.db.ExecContext(ctx, "UPDATE users SET token = ? WHERE id = ?", token, userID)
+	return err
+}
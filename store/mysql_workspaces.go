@@ -0,0 +1,222 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"shopping_list/money"
+)
+
+func (m *MySQL) CreateWorkspace(ctx context.Context, workspace *Workspace) error {
+	if workspace.Currency == "" {
+		workspace.Currency = money.DefaultCurrency
+	}
+
+	now := time.Now()
+	result, err := m.db.ExecContext(ctx, "INSERT INTO workspaces (name, user_id, currency, created_at, updated_at) VALUES (?, ?, ?, ?, ?)", workspace.Name, workspace.UserID, workspace.Currency, now, now)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	workspace.ID = int(id)
+	workspace.CreatedAt = now
+	workspace.UpdatedAt = now
+	return nil
+}
+
+func (m *MySQL) GetWorkspace(ctx context.Context, id int) (*Workspace, error) {
+	var workspace Workspace
+	err := m.db.QueryRowContext(ctx, "SELECT id, name, currency, created_at, updated_at, deleted_at, user_id FROM workspaces WHERE id = ? AND deleted_at IS NULL", id).Scan(&workspace.ID, &workspace.Name, &workspace.Currency, &workspace.CreatedAt, &workspace.UpdatedAt, &workspace.DeletedAt, &workspace.UserID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &workspace, nil
+}
+
+func (m *MySQL) ListWorkspacesForUser(ctx context.Context, userID int, ownership WorkspaceOwnership) ([]WorkspaceSummary, error) {
+	// The caller has access either as the owner or as a workspace user
+	query := `
+		SELECT w.id, w.name, w.currency, w.created_at, w.updated_at, w.deleted_at, w.user_id, access.role,
+		       1 + (SELECT COUNT(*) FROM workspace_users wu WHERE wu.workspace_id = w.id AND wu.deleted_at IS NULL),
+		       GREATEST(
+		           w.updated_at,
+		           COALESCE((SELECT MAX(l.updated_at) FROM lists l WHERE l.workspace_id = w.id), w.updated_at),
+		           COALESCE((SELECT MAX(lp.updated_at) FROM list_products lp JOIN lists l ON l.id = lp.list_id WHERE l.workspace_id = w.id), w.updated_at)
+		       ) AS last_activity_at
+		FROM workspaces w
+		JOIN (
+		    SELECT id AS workspace_id, 'owner' AS role FROM workspaces WHERE user_id = ? AND deleted_at IS NULL
+		    UNION ALL
+		    SELECT workspace_id, role FROM workspace_users WHERE user_id = ? AND deleted_at IS NULL
+		) access ON access.workspace_id = w.id
+		WHERE w.deleted_at IS NULL`
+
+	switch ownership {
+	case WorkspacesOwned:
+		query += " AND access.role = 'owner'"
+	case WorkspacesShared:
+		query += " AND access.role <> 'owner'"
+	}
+	query += " ORDER BY last_activity_at DESC, w.id DESC"
+
+	rows, err := m.db.QueryContext(ctx, query, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var workspaces []WorkspaceSummary
+	for rows.Next() {
+		var workspace WorkspaceSummary
+		err := rows.Scan(&workspace.ID, &workspace.Name, &workspace.Currency, &workspace.CreatedAt, &workspace.UpdatedAt, &workspace.DeletedAt, &workspace.UserID,
+			&workspace.Role, &workspace.MemberCount, &workspace.LastActivityAt)
+		if err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, workspace)
+	}
+	return workspaces, rows.Err()
+}
+
+func (m *MySQL) UpdateWorkspace(ctx context.Context, id, ownerID int, name string) error {
+	_, err := m.db.ExecContext(ctx, "UPDATE workspaces SET name = ?, updated_at = ? WHERE id = ? AND user_id = ? AND deleted_at IS NULL", name, time.Now(), id, ownerID)
+	return err
+}
+
+func (m *MySQL) SetWorkspaceCurrency(ctx context.Context, id int, currency string) error {
+	// Check for products in the same statement so a product created meanwhile cannot keep the old currency
+	result, err := m.db.ExecContext(ctx, `
+		UPDATE workspaces w SET w.currency = ?, w.updated_at = ?
+		WHERE w.id = ? AND w.deleted_at IS NULL
+		  AND (w.currency = ? OR NOT EXISTS(SELECT 1 FROM products p WHERE p.workspace_id = w.id AND p.deleted_at IS NULL))`,
+		currency, time.Now(), id, currency,
+	)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		if _, err := m.GetWorkspace(ctx, id); err != nil {
+			return err
+		}
+		return ErrCurrencyInUse
+	}
+	return nil
+}
+
+func (m *MySQL) DeleteWorkspace(ctx context.Context, id, ownerID int) error {
+	_, err := m.db.ExecContext(ctx, "UPDATE workspaces SET deleted_at = ? WHERE id = ? AND user_id = ?", time.Now(), id, ownerID)
+	return err
+}
+
+func (m *MySQL) GetWorkspaceRole(ctx context.Context, workspaceID, userID int) (Role, error) {
+	// Either as the owner or as a workspace user
+	var role Role
+	err := m.db.QueryRowContext(ctx, `
+		SELECT 'owner' FROM workspaces
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+		UNION ALL
+		SELECT wu.role FROM workspace_users wu
+		JOIN workspaces w ON w.id = wu.workspace_id AND w.deleted_at IS NULL
+		WHERE wu.workspace_id = ? AND wu.user_id = ? AND wu.deleted_at IS NULL
+		LIMIT 1`, workspaceID, userID, workspaceID, userID).Scan(&role)
+	if err != nil {
+		return "", notFound(err)
+	}
+	return role, nil
+}
+
+func (m *MySQL) IsWorkspaceMember(ctx context.Context, workspaceID, userID int) (bool, error) {
+	var isMember bool
+	err := m.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM workspace_users WHERE user_id = ? AND workspace_id = ? AND deleted_at IS NULL)", userID, workspaceID).Scan(&isMember)
+	return isMember, err
+}
+
+func (m *MySQL) AddWorkspaceMember(ctx context.Context, workspaceID, userID int, role Role) error {
+	_, err := m.db.ExecContext(ctx, "INSERT INTO workspace_users (user_id, workspace_id, role) VALUES (?, ?, ?)", userID, workspaceID, role)
+	return err
+}
+
+func (m *MySQL) SetWorkspaceMemberRole(ctx context.Context, workspaceID, userID int, role Role) error {
+	result, err := m.db.ExecContext(ctx, "UPDATE workspace_users SET role = ?, updated_at = ? WHERE user_id = ? AND workspace_id = ? AND deleted_at IS NULL", role, time.Now(), userID, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *MySQL) RemoveWorkspaceMember(ctx context.Context, workspaceID, userID int) error {
+	_, err := m.db.ExecContext(ctx, "UPDATE workspace_users SET deleted_at = ? WHERE user_id = ? AND workspace_id = ?", time.Now(), userID, workspaceID)
+	return err
+}
+
+func (m *MySQL) TransferWorkspaceOwnership(ctx context.Context, workspaceID, ownerID, newOwnerID int) error {
+	// Begin transaction
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	// Lock the workspace so concurrent transfers cannot both succeed
+	var id int
+	err = tx.QueryRowContext(ctx, "SELECT id FROM workspaces WHERE id = ? AND user_id = ? AND deleted_at IS NULL FOR UPDATE", workspaceID, ownerID).Scan(&id)
+	if err != nil {
+		return notFound(err)
+	}
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, "UPDATE workspace_users SET deleted_at = ? WHERE user_id = ? AND workspace_id = ? AND deleted_at IS NULL", now, newOwnerID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE workspaces SET user_id = ?, updated_at = ? WHERE id = ?", newOwnerID, now, workspaceID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO workspace_users (user_id, workspace_id, role) VALUES (?, ?, ?)", ownerID, workspaceID, RoleEditor); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *MySQL) ListWorkspaceMembers(ctx context.Context, workspaceID int) ([]Member, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT u.id, u.name, u.email, wu.role
+		FROM workspace_users wu
+		JOIN users u ON wu.user_id = u.id
+		WHERE wu.workspace_id = ? AND wu.deleted_at IS NULL`, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []Member
+	for rows.Next() {
+		var member Member
+		if err := rows.Scan(&member.ID, &member.Name, &member.Email, &member.Role); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
@@ -0,0 +1,710 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"shopping_list/money"
+)
+
+// ErrNotFound is returned when the requested record does not exist or has been soft deleted
+var ErrNotFound = errors.New("record not found")
+
+// ErrDuplicate is returned when a unique value is already taken
+var ErrDuplicate = errors.New("duplicate record")
+
+// ErrInvalidCursor is returned when a pagination cursor is malformed or was issued for another ordering
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrCurrencyInUse is returned when changing the currency of a workspace that already has priced products
+var ErrCurrencyInUse = errors.New("currency in use")
+
+// ErrInvalidQuantity is returned when a change would leave a list item without a positive quantity
+var ErrInvalidQuantity = errors.New("invalid quantity")
+
+// ErrVersionConflict is returned when a record changed since the version the caller expected
+var ErrVersionConflict = errors.New("version conflict")
+
+// ErrInactiveList is returned when moving items into a list that is not an active list of the workspace
+var ErrInactiveList = errors.New("list is not active")
+
+// ErrTokenUsed is returned when rotating a refresh token that was already used or revoked
+var ErrTokenUsed = errors.New("refresh token already used")
+
+// User represents a registered user
+type User struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	PasswordHash string `json:"-"`
+}
+
+// RefreshToken is an opaque refresh token, only its hash is stored
+type RefreshToken struct {
+	ID        int
+	UserID    int
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// Role is the role of a user in a workspace
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+// ValidMemberRole reports whether role can be given to a workspace member.
+// The owner role belongs to the user in workspaces.user_id and cannot be assigned.
+func ValidMemberRole(role Role) bool {
+	return role == RoleEditor || role == RoleViewer
+}
+
+// Member is a user belonging to a workspace with their role
+type Member struct {
+	User
+	Role Role `json:"role"`
+}
+
+// Workspace represents a workspace owned by a user
+type Workspace struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	UserID int    `json:"user_id"`
+	// Currency is the ISO 4217 code of every price in the workspace
+	Currency  string     `json:"currency"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// WorkspaceOwnership selects the workspaces listed by ListWorkspacesForUser
+type WorkspaceOwnership string
+
+const (
+	WorkspacesAll    WorkspaceOwnership = ""
+	WorkspacesOwned  WorkspaceOwnership = "owned"
+	WorkspacesShared WorkspaceOwnership = "shared"
+)
+
+// WorkspaceSummary is a workspace as listed to one of its users
+type WorkspaceSummary struct {
+	Workspace
+	Role Role `json:"role"`
+	// MemberCount includes the owner
+	MemberCount int `json:"member_count"`
+	// LastActivityAt is the latest change to the workspace, its lists or their products
+	LastActivityAt time.Time `json:"last_activity_at"`
+}
+
+// InvitationStatus is the state of a workspace invitation
+type InvitationStatus string
+
+const (
+	InvitationPending  InvitationStatus = "pending"
+	InvitationAccepted InvitationStatus = "accepted"
+	InvitationDeclined InvitationStatus = "declined"
+	InvitationRevoked  InvitationStatus = "revoked"
+)
+
+// Invitation is an invitation to join a workspace sent to an email address,
+// which may not belong to a registered user yet
+type Invitation struct {
+	ID            int              `json:"id"`
+	WorkspaceID   int              `json:"workspace_id"`
+	WorkspaceName string           `json:"workspace_name"`
+	Email         string           `json:"email"`
+	Role          Role             `json:"role"`
+	InvitedBy     int              `json:"invited_by"`
+	Status        InvitationStatus `json:"status"`
+	ExpiresAt     time.Time        `json:"expires_at"`
+	RespondedAt   *time.Time       `json:"responded_at,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+}
+
+// Open reports whether the invitation can still be accepted or declined
+func (i *Invitation) Open(now time.Time) bool {
+	return i.Status == InvitationPending && now.Before(i.ExpiresAt)
+}
+
+// Category groups the products of a workspace, such as Produce or Dairy.
+// Position orders the categories when no store layout is used.
+type Category struct {
+	ID          int        `json:"id"`
+	WorkspaceID int        `json:"workspace_id"`
+	Name        string     `json:"name"`
+	Position    int        `json:"position"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Shop is a supermarket of a workspace, called a store in the API
+type Shop struct {
+	ID          int        `json:"id"`
+	WorkspaceID int        `json:"workspace_id"`
+	Name        string     `json:"name"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+}
+
+// ShopAisle places a category in the walking order of a shop
+type ShopAisle struct {
+	CategoryID int    `json:"category_id"`
+	Position   int    `json:"position"`
+	Aisle      string `json:"aisle,omitempty"`
+}
+
+// Product represents a product in a workspace catalogue
+type Product struct {
+	ID          int         `json:"id"`
+	Title       string      `json:"title"`
+	AmountType  string      `json:"amount_type"`
+	Price       money.Money `json:"price"`
+	DeletedAt   *time.Time  `json:"deleted_at"`
+	WorkspaceID int         `json:"workspace_id"`
+	CategoryID  *int        `json:"category_id"`
+	CreatedAt   time.Time   `json:"created_at"`
+	// Version is incremented on every change
+	Version int `json:"version"`
+}
+
+// PriceSource records who changed a price and at which shop
+type PriceSource struct {
+	UserID *int
+	ShopID *int
+}
+
+// PricePoint is a price of a product recorded at a point in time
+type PricePoint struct {
+	ID         int         `json:"id"`
+	ProductID  int         `json:"product_id"`
+	ShopID     *int        `json:"store_id"`
+	UserID     *int        `json:"user_id"`
+	Price      money.Money `json:"price"`
+	RecordedAt time.Time   `json:"recorded_at"`
+}
+
+// PriceHistoryFilter narrows down the price points of a product
+type PriceHistoryFilter struct {
+	ProductID int
+	ShopID    *int
+	// From is included and To excluded, either may be nil
+	From *time.Time
+	To   *time.Time
+}
+
+// PriceStats summarizes the price points matching a filter.
+// Avg is rounded half away from zero to the minor unit.
+type PriceStats struct {
+	Count int         `json:"count"`
+	Min   money.Money `json:"min"`
+	Max   money.Money `json:"max"`
+	Avg   money.Money `json:"avg"`
+}
+
+// ProductSort is the field products are ordered by
+type ProductSort string
+
+const (
+	ProductSortTitle   ProductSort = "title"
+	ProductSortPrice   ProductSort = "price"
+	ProductSortCreated ProductSort = "created"
+)
+
+// ProductFilter narrows down and pages the products returned by ListProducts
+type ProductFilter struct {
+	WorkspaceID int
+	Name        string
+	AmountType  string
+	// MinPrice and MaxPrice are in minor units of the workspace currency
+	MinPrice *int64
+	MaxPrice *int64
+
+	Sort ProductSort
+	Desc bool
+	// After is the cursor of the previous page, empty for the first page
+	After string
+	Limit int
+}
+
+// ProductPage is a page of products with the number of products matching the filter
+type ProductPage struct {
+	Products []Product
+	Total    int
+	// NextCursor is empty on the last page
+	NextCursor string
+}
+
+// ListStatus represents the possible status values for a list
+const (
+	ListStatusDeleted   = 0
+	ListStatusActive    = 1
+	ListStatusCompleted = 2
+)
+
+// List represents a shopping list in a workspace
+type List struct {
+	ID          int    `json:"id"`
+	WorkspaceID int    `json:"workspace_id"`
+	UserID      int    `json:"user_id"`
+	Title       string `json:"title"`
+	Status      int    `json:"status"`
+	// Budget is in the workspace currency, nil when the list has no budget
+	Budget      *money.Money `json:"budget"`
+	CompletedAt *time.Time   `json:"completed_at,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	DeletedAt   *time.Time   `json:"deleted_at,omitempty"`
+	// Version is incremented on every change to the list or its items
+	Version int `json:"version"`
+}
+
+// ListItem represents a product in a list with its quantity
+type ListItem struct {
+	ListID       int        `json:"list_id"`
+	ProductID    int        `json:"product_id"`
+	Quantity     int        `json:"quantity"`
+	Checked      bool       `json:"checked"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	ProductTitle string     `json:"name"`
+	// Price is the current catalogue price of the product
+	Price money.Money `json:"price"`
+	// Purchase is what was bought when the item was checked, nil while it is unchecked
+	Purchase *Purchase `json:"purchase,omitempty"`
+	// Category is the category of the product, nil when it has none
+	Category *Category `json:"category,omitempty"`
+}
+
+// PurchaseDetails is what a shopper actually paid for an item, each detail is optional
+type PurchaseDetails struct {
+	UnitPrice *money.Money `json:"unit_price,omitempty"`
+	Quantity  *int         `json:"quantity,omitempty"`
+	ShopID    *int         `json:"store_id,omitempty"`
+}
+
+// Purchase records who checked an item, when, and what they paid
+type Purchase struct {
+	PurchaseDetails
+	CheckedBy *int      `json:"checked_by"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// ItemCheck checks or unchecks a list item. Checking records the purchase, and its unit price in
+// the price history of the product; unchecking removes both.
+type ItemCheck struct {
+	Checked   bool
+	CheckedBy *int
+	// Purchase is nil when the shopper gave no details, an item that is already
+	// checked then keeps the ones it has
+	Purchase *PurchaseDetails
+}
+
+// ItemChange is a partial update of a list item, fields left empty are not changed
+type ItemChange struct {
+	// Quantity replaces the quantity, QuantityDelta adds to it
+	Quantity      *int
+	QuantityDelta int
+	Check         *ItemCheck
+	// Version is the version of the list the change was made on, 0 to apply it to any version.
+	// UpdateListItem sets it to the new version of the list.
+	Version int
+}
+
+// apply returns the quantity after the change, or ErrInvalidQuantity if it would not be positive
+func (c ItemChange) apply(quantity int) (int, error) {
+	if c.Quantity != nil {
+		quantity = *c.Quantity
+	}
+	quantity += c.QuantityDelta
+	if quantity < 1 {
+		return 0, ErrInvalidQuantity
+	}
+	return quantity, nil
+}
+
+// ListWithItems is a list together with its active items
+type ListWithItems struct {
+	List
+	Items []ListItem
+}
+
+// ItemQuantity is a product and the quantity wanted in a list
+type ItemQuantity struct {
+	ProductID int
+	Quantity  int
+	// Check is nil to leave the item checked or unchecked
+	Check *ItemCheck
+}
+
+// MonthlySpending is what a workspace spent on the lists completed in a month
+type MonthlySpending struct {
+	// Month is formatted as 2006-01
+	Month string      `json:"month"`
+	Lists int         `json:"lists"`
+	Total money.Money `json:"total"`
+}
+
+// ListUpdate holds the fields replaced by UpdateList
+type ListUpdate struct {
+	ListID      int
+	WorkspaceID int
+	Title       string
+	Status      int
+	Items       []ItemQuantity
+	// Version is the version the list must still have, 0 to update any version
+	Version int
+}
+
+// ListPatch holds the fields changed by PatchList, nil fields are left unchanged
+type ListPatch struct {
+	ListID int
+	Title  *string
+	Status *int
+	// Version is the version the list must still have, 0 to update any version
+	Version int
+}
+
+// CarryOver completes a list and moves its unchecked items to another active list
+type CarryOver struct {
+	ListID int
+	// Version is the version the list must still have, 0 to complete any version
+	Version int
+	// TargetID is the active list of the same workspace receiving the items, 0 to create NewList
+	TargetID int
+	NewList  *List
+}
+
+// ListMerge moves the items of a list into another list of the same workspace
+type ListMerge struct {
+	TargetID int
+	SourceID int
+	// Version is the version the target list must still have, 0 to merge into any version
+	Version int
+	// DryRun returns the merged list without changing anything
+	DryRun bool
+}
+
+// ListTemplate is a title and products lists are created from, on demand or on a recurrence
+type ListTemplate struct {
+	ID          int
+	WorkspaceID int
+	UserID      int
+	Title       string
+	// Items leaves out the products deleted since they were added to the template
+	Items []TemplateItem
+	// Recurrence is nil for templates that are only used on demand
+	Recurrence *Recurrence
+	// NextRunAt is when the next list is due, nil without recurrence
+	NextRunAt *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+}
+
+// TemplateItem is a product of a template with the quantity to put in the lists
+type TemplateItem struct {
+	ProductID    int
+	Quantity     int
+	ProductTitle string
+}
+
+// Recurrence repeats a template every IntervalWeeks weeks on a weekday, at midnight UTC
+type Recurrence struct {
+	Weekday       time.Weekday
+	IntervalWeeks int
+}
+
+// First returns the first run of the recurrence after now
+func (r Recurrence) First(now time.Time) time.Time {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	days := (int(r.Weekday) - int(day.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return day.AddDate(0, 0, days)
+}
+
+// Next returns the run following run, skipping the runs missed up to now
+func (r Recurrence) Next(run, now time.Time) time.Time {
+	for next := run; ; {
+		next = next.AddDate(0, 0, 7*r.IntervalWeeks)
+		if next.After(now) {
+			return next
+		}
+	}
+}
+
+// Change types of the sync feed
+const (
+	ChangeProduct  = "product"
+	ChangeList     = "list"
+	ChangeListItem = "list_product"
+)
+
+// Change is the current state of a record changed after a sync cursor. Soft deleted records
+// are included with their deleted_at set.
+type Change struct {
+	Type    string    `json:"type"`
+	Deleted bool      `json:"deleted"`
+	Product *Product  `json:"product,omitempty"`
+	List    *List     `json:"list,omitempty"`
+	Item    *ListItem `json:"item,omitempty"`
+}
+
+// ChangePage is a page of the changes of a workspace, oldest first
+type ChangePage struct {
+	Changes []Change
+	// Cursor is the position after the last change, to be passed back for the next changes
+	Cursor string
+	// More is true if there are changes after this page
+	More bool
+}
+
+// IdempotentRequest is a request made with an Idempotency-Key and, once it completed, its response
+type IdempotentRequest struct {
+	UserID int
+	Key    string
+	// Fingerprint identifies the method, path and body of the request
+	Fingerprint string
+	// StatusCode is 0 while the request is in progress
+	StatusCode int
+	Header     map[string][]string
+	Body       []byte
+	CreatedAt  time.Time
+	// ExpiresAt is when the key can be used for another request
+	ExpiresAt time.Time
+}
+
+// UserStore persists users
+type UserStore interface {
+	CreateUser(ctx context.Context, user *User) error
+	GetUserByID(ctx context.Context, id int) (*User, error)
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	SetUserToken(ctx context.Context, userID int, token *string) error
+}
+
+// TokenStore persists refresh tokens
+type TokenStore interface {
+	CreateRefreshToken(ctx context.Context, token *RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, hash string) (*RefreshToken, error)
+	// RotateRefreshToken marks the old token as used and stores its replacement atomically.
+	// It returns ErrTokenUsed if the old token was used or revoked in the meantime.
+	RotateRefreshToken(ctx context.Context, oldID int, next *RefreshToken) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+
+	// RevokeAccessToken rejects the access token with the given ID until expiresAt, and forgets
+	// the revoked tokens that have expired
+	RevokeAccessToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	// RevokeUserTokens rejects every access token issued to the user until now and revokes all their refresh tokens
+	RevokeUserTokens(ctx context.Context, userID int) error
+	// GetUserTokensRevokedAt returns when all tokens of the user were last revoked, or nil
+	GetUserTokensRevokedAt(ctx context.Context, userID int) (*time.Time, error)
+}
+
+// WorkspaceStore persists workspaces and their members
+type WorkspaceStore interface {
+	CreateWorkspace(ctx context.Context, workspace *Workspace) error
+	GetWorkspace(ctx context.Context, id int) (*Workspace, error)
+	// ListWorkspacesForUser returns the workspaces the user owns or is a member of, most recently active first
+	ListWorkspacesForUser(ctx context.Context, userID int, ownership WorkspaceOwnership) ([]WorkspaceSummary, error)
+	UpdateWorkspace(ctx context.Context, id, ownerID int, name string) error
+	// SetWorkspaceCurrency changes the currency of a workspace, it returns ErrCurrencyInUse
+	// if the workspace has products whose prices would be misread
+	SetWorkspaceCurrency(ctx context.Context, id int, currency string) error
+	DeleteWorkspace(ctx context.Context, id, ownerID int) error
+	// GetWorkspaceRole returns the role of the user in the workspace, or ErrNotFound if they have no access
+	GetWorkspaceRole(ctx context.Context, workspaceID, userID int) (Role, error)
+	IsWorkspaceMember(ctx context.Context, workspaceID, userID int) (bool, error)
+	AddWorkspaceMember(ctx context.Context, workspaceID, userID int, role Role) error
+	SetWorkspaceMemberRole(ctx context.Context, workspaceID, userID int, role Role) error
+	RemoveWorkspaceMember(ctx context.Context, workspaceID, userID int) error
+	// TransferWorkspaceOwnership makes a member the owner and keeps the previous owner as an editor, atomically.
+	// It returns ErrNotFound if ownerID does not own the workspace or newOwnerID is not a member of it.
+	TransferWorkspaceOwnership(ctx context.Context, workspaceID, ownerID, newOwnerID int) error
+	ListWorkspaceMembers(ctx context.Context, workspaceID int) ([]Member, error)
+}
+
+// InvitationStore persists workspace invitations
+type InvitationStore interface {
+	CreateInvitation(ctx context.Context, invitation *Invitation) error
+	GetInvitation(ctx context.Context, id int) (*Invitation, error)
+	// GetOpenInvitation returns the pending, unexpired invitation of the email to the workspace, or ErrNotFound
+	GetOpenInvitation(ctx context.Context, workspaceID int, email string) (*Invitation, error)
+	// ListWorkspaceInvitations returns the pending invitations of a workspace, including expired ones
+	ListWorkspaceInvitations(ctx context.Context, workspaceID int) ([]Invitation, error)
+	// ListInvitationsByEmail returns the pending, unexpired invitations sent to the email
+	ListInvitationsByEmail(ctx context.Context, email string) ([]Invitation, error)
+	// AcceptInvitation adds the user to the workspace with the invited role and closes the invitation atomically.
+	// It returns ErrNotFound if the invitation is no longer pending or has expired.
+	AcceptInvitation(ctx context.Context, id, userID int) error
+	// CloseInvitation declines or revokes a pending invitation, or returns ErrNotFound if it is no longer pending
+	CloseInvitation(ctx context.Context, id int, status InvitationStatus) error
+	// AcceptPendingInvitations accepts every open invitation sent to the email, used when the invitee registers
+	AcceptPendingInvitations(ctx context.Context, email string, userID int) ([]Invitation, error)
+}
+
+// CategoryStore persists product categories
+type CategoryStore interface {
+	// CreateCategory returns ErrDuplicate if the workspace already has a category with the name
+	CreateCategory(ctx context.Context, category *Category) error
+	GetCategory(ctx context.Context, id int) (*Category, error)
+	// ListCategories returns the categories of a workspace ordered by position
+	ListCategories(ctx context.Context, workspaceID int) ([]Category, error)
+	// UpdateCategory renames or moves a category, it returns ErrDuplicate if the name is taken
+	UpdateCategory(ctx context.Context, category *Category) error
+	// DeleteCategory soft deletes a category, its products become uncategorized
+	DeleteCategory(ctx context.Context, id int) error
+}
+
+// ShopStore persists shops and their layouts
+type ShopStore interface {
+	CreateShop(ctx context.Context, shop *Shop) error
+	GetShop(ctx context.Context, id int) (*Shop, error)
+	ListShops(ctx context.Context, workspaceID int) ([]Shop, error)
+	UpdateShop(ctx context.Context, shop *Shop) error
+	DeleteShop(ctx context.Context, id int) error
+	// GetShopLayout returns the aisles of a shop in walking order
+	GetShopLayout(ctx context.Context, shopID int) ([]ShopAisle, error)
+	// SetShopLayout replaces the aisles of a shop atomically
+	SetShopLayout(ctx context.Context, shopID int, aisles []ShopAisle) error
+}
+
+// ProductStore persists products
+type ProductStore interface {
+	// CreateProduct stores the product and starts its price history
+	CreateProduct(ctx context.Context, product *Product, source PriceSource) error
+	GetProduct(ctx context.Context, id int) (*Product, error)
+	// ListProducts returns a page of the products of a workspace. It returns ErrInvalidCursor
+	// if filter.After was not issued for the same ordering.
+	ListProducts(ctx context.Context, filter ProductFilter) (*ProductPage, error)
+	// UpdateProduct updates the product and records the price in its history if it changed, atomically.
+	// Unless product.Version is 0 it returns ErrVersionConflict if the product has another version,
+	// and it sets product.Version to the new version.
+	UpdateProduct(ctx context.Context, product *Product, source PriceSource) error
+	// DeleteProduct soft deletes a product, it returns ErrVersionConflict unless version
+	// is 0 or the version of the product
+	DeleteProduct(ctx context.Context, id, version int) error
+
+	// AddPricePoint records a price seen for a product without changing its catalogue price
+	AddPricePoint(ctx context.Context, point *PricePoint) error
+	// ListPriceHistory returns the price points of a product, oldest first
+	ListPriceHistory(ctx context.Context, filter PriceHistoryFilter) ([]PricePoint, error)
+	GetPriceStats(ctx context.Context, filter PriceHistoryFilter) (*PriceStats, error)
+}
+
+// ListStore persists lists and the products in them
+type ListStore interface {
+	CreateList(ctx context.Context, list *List, items []ItemQuantity) error
+	GetList(ctx context.Context, id int) (*List, error)
+	ListItems(ctx context.Context, listID int) ([]ListItem, error)
+	ListListsWithItems(ctx context.Context, workspaceID int) ([]ListWithItems, error)
+	// UpdateList replaces the title, status and items of a list and sets update.Version to the new
+	// version. The deleted status soft deletes the list like SetListStatus. It returns
+	// ErrVersionConflict unless update.Version is 0 or the version of the list.
+	UpdateList(ctx context.Context, update *ListUpdate) error
+	// SetListStatus changes the status of a list, completing a list records when it was completed.
+	// It returns ErrVersionConflict unless version is 0 or the version of the list.
+	SetListStatus(ctx context.Context, listID, status, version int) (*List, error)
+	// PatchList changes the title and status of a list, completing a list records when it was completed.
+	// Deleting a list is left to SetListStatus. It returns ErrVersionConflict unless patch.Version is 0
+	// or the version of the list.
+	PatchList(ctx context.Context, patch ListPatch) (*List, error)
+	// CompleteAndCarryOver completes a list and moves its unchecked items to the target list in one
+	// transaction, returning both lists. A product already in the target list gets the quantities added
+	// up and is left to buy, a checked one is unchecked. It returns ErrVersionConflict unless
+	// carry.Version is 0 or the version of the list, and ErrInactiveList if the target is not an active
+	// list of the same workspace.
+	CompleteAndCarryOver(ctx context.Context, carry CarryOver) (completed, target *List, err error)
+	// MergeLists moves the items of the source list into the target list and soft deletes the source
+	// list in one transaction, returning the target list with its items. A product in both lists gets
+	// the quantities added up and stays checked only if it was checked in both, keeping the purchase
+	// of the target list. It returns ErrNotFound unless both lists exist in the same workspace,
+	// ErrVersionConflict unless merge.Version is 0 or the version of the target list, and
+	// ErrInactiveList if the target list is not active.
+	MergeLists(ctx context.Context, merge ListMerge) (*List, []ListItem, error)
+	// SetListBudget sets or, with nil, removes the budget of a list
+	SetListBudget(ctx context.Context, listID int, budget *money.Money) error
+	// ListMonthlySpending sums the checked items of the lists completed in [from, to) by UTC month, at
+	// the price and quantity bought when those were recorded, or else at the current product price and
+	// the quantity on the list. Months without completed lists are left out.
+	ListMonthlySpending(ctx context.Context, workspaceID int, from, to time.Time) ([]MonthlySpending, error)
+	// RemoveListItem soft deletes a product of a list and returns the version of the list afterwards.
+	// It returns ErrVersionConflict if version is not 0 and the list has another version.
+	RemoveListItem(ctx context.Context, listID, productID, version int) (int, error)
+	// PutListItem adds a product to a list, restoring it unchecked if it was removed, or sets its
+	// quantity if it is already in the list, then applies item.Check if it is set
+	PutListItem(ctx context.Context, listID int, item ItemQuantity) (*ListItem, error)
+	// UpdateListItem changes the quantity and checked state of an active item atomically and sets
+	// change.Version to the new version of the list. It returns ErrNotFound if the product is not in
+	// the list, ErrInvalidQuantity if the quantity would drop below one and ErrVersionConflict if
+	// change.Version is set and the list has another version.
+	UpdateListItem(ctx context.Context, listID, productID int, change *ItemChange) (*ListItem, error)
+}
+
+// TemplateStore persists list templates
+type TemplateStore interface {
+	CreateTemplate(ctx context.Context, template *ListTemplate) error
+	GetTemplate(ctx context.Context, id int) (*ListTemplate, error)
+	ListTemplates(ctx context.Context, workspaceID int) ([]ListTemplate, error)
+	// UpdateTemplate replaces the title, items, recurrence and next run of a template
+	UpdateTemplate(ctx context.Context, template *ListTemplate) error
+	DeleteTemplate(ctx context.Context, id int) error
+	// ListDueTemplates returns the recurring templates whose next run is at or before now
+	ListDueTemplates(ctx context.Context, now time.Time) ([]ListTemplate, error)
+	// AdvanceTemplateRun moves the next run of a template from run to next. It returns ErrNotFound
+	// if the template was deleted or its next run is no longer run, so that a due run is only
+	// claimed once.
+	AdvanceTemplateRun(ctx context.Context, id int, run, next time.Time) error
+}
+
+// SyncStore serves the change feed of workspaces and remembers the sync mutations applied
+type SyncStore interface {
+	// ListChanges returns the changes of a workspace after the cursor, or all of them if it is empty.
+	// Changes are ordered by the change sequence of the workspace, which increases with every write
+	// to its products, lists and list items. It returns ErrInvalidCursor if the cursor was not issued
+	// for the workspace.
+	ListChanges(ctx context.Context, workspaceID int, after string, limit int) (*ChangePage, error)
+	// GetMutationResult returns the result stored for an idempotency key of the user, or ErrNotFound
+	GetMutationResult(ctx context.Context, workspaceID, userID int, key string) ([]byte, error)
+	// SaveMutationResult stores the result of a mutation, or returns ErrDuplicate if the key was used
+	SaveMutationResult(ctx context.Context, workspaceID, userID int, key string, result []byte) error
+}
+
+// IdempotencyStore remembers the requests made with an Idempotency-Key and their responses
+type IdempotencyStore interface {
+	// BeginIdempotentRequest stores a request in progress. If the user already made an unexpired
+	// request with the key it returns that request and ErrDuplicate instead.
+	BeginIdempotentRequest(ctx context.Context, request *IdempotentRequest) (*IdempotentRequest, error)
+	// CompleteIdempotentRequest stores the response of a request in progress
+	CompleteIdempotentRequest(ctx context.Context, request *IdempotentRequest) error
+	// DeleteIdempotentRequest forgets a request so the key can be used again
+	DeleteIdempotentRequest(ctx context.Context, userID int, key string) error
+}
+
+// Stores groups the storage backends injected into the handlers
+type Stores struct {
+	Users       UserStore
+	Tokens      TokenStore
+	Workspaces  WorkspaceStore
+	Invitations InvitationStore
+	Categories  CategoryStore
+	Shops       ShopStore
+	Products    ProductStore
+	Lists       ListStore
+	Templates   TemplateStore
+	Sync        SyncStore
+	Idempotency IdempotencyStore
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestRecurrenceFirst(t *testing.T) {
+	// 2026-10-18 is a Sunday
+	sunday := date(2026, time.October, 18, 12, 0)
+	newYork := time.FixedZone("UTC-5", -5*60*60)
+
+	tests := []struct {
+		name    string
+		weekday time.Weekday
+		now     time.Time
+		want    time.Time
+	}{
+		{"next day", time.Monday, sunday, date(2026, time.October, 19, 0, 0)},
+		{"later in the week", time.Saturday, sunday, date(2026, time.October, 24, 0, 0)},
+		{"same weekday", time.Sunday, sunday, date(2026, time.October, 25, 0, 0)},
+		{"same weekday at midnight", time.Sunday, date(2026, time.October, 18, 0, 0), date(2026, time.October, 25, 0, 0)},
+		{"across months", time.Monday, date(2026, time.October, 31, 9, 0), date(2026, time.November, 2, 0, 0)},
+		// Sunday evening in UTC-5 is already Monday in UTC
+		{"weekday in UTC", time.Monday, time.Date(2026, time.October, 18, 23, 30, 0, 0, newYork), date(2026, time.October, 26, 0, 0)},
+		{"day after in UTC", time.Tuesday, time.Date(2026, time.October, 18, 23, 30, 0, 0, newYork), date(2026, time.October, 20, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Recurrence{Weekday: tt.weekday, IntervalWeeks: 1}
+			if got := r.First(tt.now); !got.Equal(tt.want) {
+				t.Errorf("First(%s) = %s, want %s", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	run := date(2026, time.October, 19, 0, 0)
+
+	tests := []struct {
+		name          string
+		intervalWeeks int
+		now           time.Time
+		want          time.Time
+	}{
+		{"weekly", 1, run.Add(time.Second), date(2026, time.October, 26, 0, 0)},
+		{"every two weeks", 2, run.Add(time.Second), date(2026, time.November, 2, 0, 0)},
+		{"missed runs are skipped", 1, date(2026, time.November, 10, 12, 0), date(2026, time.November, 16, 0, 0)},
+		{"missed runs keep the interval", 3, date(2026, time.December, 1, 0, 0), date(2026, time.December, 21, 0, 0)},
+		{"due now", 1, date(2026, time.October, 26, 0, 0), date(2026, time.November, 2, 0, 0)},
+		{"run ahead of now", 1, run.Add(-time.Hour), date(2026, time.October, 26, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Recurrence{Weekday: time.Monday, IntervalWeeks: tt.intervalWeeks}
+			if got := r.Next(run, tt.now); !got.Equal(tt.want) {
+				t.Errorf("Next(%s, %s) = %s, want %s", run, tt.now, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxCachedEntries bounds the revocation cache, expired entries are dropped once it is reached
+const maxCachedEntries = 10000
+
+// CachedTokens wraps a TokenStore and caches revocation lookups in process memory.
+// Revocations made through the wrapper are visible immediately, revocations made by
+// other server instances are picked up once the cached entry expires.
+type CachedTokens struct {
+	TokenStore
+	ttl time.Duration
+
+	mu           sync.Mutex
+	accessTokens map[string]cachedRevocation
+	users        map[int]cachedUserRevocation
+}
+
+type cachedRevocation struct {
+	revoked   bool
+	expiresAt time.Time
+}
+
+type cachedUserRevocation struct {
+	revokedAt *time.Time
+	expiresAt time.Time
+}
+
+// NewCachedTokens caches the revocation lookups of tokens for ttl
+func NewCachedTokens(tokens TokenStore, ttl time.Duration) *CachedTokens {
+	return &CachedTokens{
+		TokenStore:   tokens,
+		ttl:          ttl,
+		accessTokens: make(map[string]cachedRevocation),
+		users:        make(map[int]cachedUserRevocation),
+	}
+}
+
+func (c *CachedTokens) RevokeAccessToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	if err := c.TokenStore.RevokeAccessToken(ctx, jti, userID, expiresAt); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// A revocation is final, keep it for as long as the token could be presented
+	c.accessTokens[jti] = cachedRevocation{revoked: true, expiresAt: expiresAt}
+	return nil
+}
+
+func (c *CachedTokens) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	cached, ok := c.accessTokens[jti]
+	c.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.revoked, nil
+	}
+
+	revoked, err := c.TokenStore.IsAccessTokenRevoked(ctx, jti)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pruneLocked(now)
+	c.accessTokens[jti] = cachedRevocation{revoked: revoked, expiresAt: now.Add(c.ttl)}
+	return revoked, nil
+}
+
+func (c *CachedTokens) RevokeUserTokens(ctx context.Context, userID int) error {
+	if err := c.TokenStore.RevokeUserTokens(ctx, userID); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Force the next lookup to read the new cutoff from the store
+	delete(c.users, userID)
+	return nil
+}
+
+func (c *CachedTokens) GetUserTokensRevokedAt(ctx context.Context, userID int) (*time.Time, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	cached, ok := c.users[userID]
+	c.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.revokedAt, nil
+	}
+
+	revokedAt, err := c.TokenStore.GetUserTokensRevokedAt(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pruneLocked(now)
+	c.users[userID] = cachedUserRevocation{revokedAt: revokedAt, expiresAt: now.Add(c.ttl)}
+	return revokedAt, nil
+}
+
+// pruneLocked drops expired entries once the cache is full, the caller must hold c.mu
+func (c *CachedTokens) pruneLocked(now time.Time) {
+	if len(c.accessTokens)+len(c.users) < maxCachedEntries {
+		return
+	}
+
+	for jti, cached := range c.accessTokens {
+		if !now.Before(cached.expiresAt) {
+			delete(c.accessTokens, jti)
+		}
+	}
+	for userID, cached := range c.users {
+		if !now.Before(cached.expiresAt) {
+			delete(c.users, userID)
+		}
+	}
+}
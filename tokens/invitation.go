@@ -0,0 +1,73 @@
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"shopping_list/config"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// InvitationClaims are the claims carried by the workspace invitation tokens sent by email
+type InvitationClaims struct {
+	InvitationID int    `json:"inv"`
+	Email        string `json:"email"`
+	jwt.StandardClaims
+}
+
+// invitationAudience keeps invitation tokens from being accepted as access tokens and the other way around
+func invitationAudience() string {
+	return config.JWT.Audience + ":invitation"
+}
+
+// Valid validates the expiry with the configured leeway, and the issuer and audience
+func (c *InvitationClaims) Valid() error {
+	if c.ExpiresAt == 0 || time.Now().After(time.Unix(c.ExpiresAt, 0).Add(config.JWT.Leeway)) {
+		return ErrExpired
+	}
+	if c.Issuer != config.JWT.Issuer {
+		return ErrInvalidIssuer
+	}
+	if c.Audience != invitationAudience() {
+		return ErrInvalidAudience
+	}
+	return nil
+}
+
+// SignInvitation generates a signed token for an invitation that expires with it
+func SignInvitation(invitationID int, email string, expiresAt time.Time) (string, error) {
+	claims := &InvitationClaims{
+		InvitationID: invitationID,
+		Email:        email,
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    config.JWT.Issuer,
+			Audience:  invitationAudience(),
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(config.JWT.Secret)
+}
+
+// ParseInvitation verifies the signature and claims of an invitation token and returns its claims
+func ParseInvitation(tokenString string) (*InvitationClaims, error) {
+	claims := &InvitationClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		// Validate the algorithm
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return config.JWT.Secret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
@@ -0,0 +1,112 @@
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"shopping_list/config"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	ErrInvalidIssuer   = errors.New("token has an invalid issuer")
+	ErrInvalidAudience = errors.New("token has an invalid audience")
+	ErrExpired         = errors.New("token is expired")
+	ErrNotValidYet     = errors.New("token is not valid yet")
+)
+
+// Claims are the claims carried by the access tokens
+type Claims struct {
+	Email string `json:"email"`
+	// SessionID is the refresh token family the access token was issued for
+	SessionID string `json:"sid,omitempty"`
+	// IssuedAtMicro is the issue time in microseconds, "iat" only has seconds and a token issued
+	// in the same second as a "log out all devices" must still be told apart from it
+	IssuedAtMicro int64 `json:"iat_us,omitempty"`
+	jwt.StandardClaims
+}
+
+// Valid validates the time based claims with the configured leeway, and the issuer and audience
+func (c *Claims) Valid() error {
+	now := time.Now()
+	leeway := config.JWT.Leeway
+
+	if c.ExpiresAt == 0 || now.After(time.Unix(c.ExpiresAt, 0).Add(leeway)) {
+		return ErrExpired
+	}
+	if c.NotBefore != 0 && now.Add(leeway).Before(time.Unix(c.NotBefore, 0)) {
+		return ErrNotValidYet
+	}
+	if c.IssuedAt != 0 && now.Add(leeway).Before(time.Unix(c.IssuedAt, 0)) {
+		return ErrNotValidYet
+	}
+	if c.Issuer != config.JWT.Issuer {
+		return ErrInvalidIssuer
+	}
+	if c.Audience != config.JWT.Audience {
+		return ErrInvalidAudience
+	}
+	return nil
+}
+
+// IssuedAtTime returns when the token was issued, to the second for tokens without IssuedAtMicro
+func (c *Claims) IssuedAtTime() time.Time {
+	if c.IssuedAtMicro != 0 {
+		return time.UnixMicro(c.IssuedAtMicro)
+	}
+	return time.Unix(c.IssuedAt, 0)
+}
+
+// UserID returns the ID of the user the token was issued to, carried in the "sub" claim
+func (c *Claims) UserID() (int, error) {
+	return strconv.Atoi(c.Subject)
+}
+
+// Sign generates a signed access token with a unique ID for the given user and refresh token family
+func Sign(userID int, email, sessionID string) (string, error) {
+	jti, err := NewID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := &Claims{
+		Email:         email,
+		SessionID:     sessionID,
+		IssuedAtMicro: now.UnixMicro(),
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			Subject:   strconv.Itoa(userID),
+			Issuer:    config.JWT.Issuer,
+			Audience:  config.JWT.Audience,
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(config.JWT.Expiry).Unix(),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(config.JWT.Secret)
+}
+
+// Parse verifies the signature and claims of a token and returns its claims
+func Parse(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		// Validate the algorithm
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return config.JWT.Secret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
@@ -2,35 +2,56 @@ package workspaces
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
-	"shopping_list/db"
 	"shopping_list/middleware"
+	"shopping_list/store"
 
 	"github.com/gorilla/mux"
 )
 
+// MemberRoleRequest represents the request body for adding a member or changing their role
+type MemberRoleRequest struct {
+	Role store.Role `json:"role"`
+}
+
 func AddUserToWorkspace(w http.ResponseWriter, r *http.Request) {
-	// Get user ID from the token
-	loggedInUserID, err := middleware.ExtractUserIDFromToken(r)
-	if err != nil {
+	// Get the authenticated user from the request context
+	loggedInUserID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	// Get workspace ID from URL parameters
 	vars := mux.Vars(r)
-	workspaceID := vars["workspace_id"]
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
 	userID, err := strconv.Atoi(vars["user_id"])
 	if err != nil {
 		http.Error(w, "Invalid user ID", http.StatusBadRequest)
 		return
 	}
 
+	// The role is optional and defaults to editor
+	req := MemberRoleRequest{Role: store.RoleEditor}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if !store.ValidMemberRole(req.Role) {
+		http.Error(w, "Invalid role", http.StatusBadRequest)
+		return
+	}
+
 	// Check if the user exists
-	var userExists int
-	err = db.DB.QueryRow("SELECT id FROM users WHERE id = ? AND deleted_at IS NULL", userID).Scan(&userExists)
+	_, err = stores.Users.GetUserByID(r.Context(), userID)
 	if err != nil {
 		http.Error(w, "User not found", http.StatusNotFound)
 		return
@@ -43,15 +64,18 @@ func AddUserToWorkspace(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if the user is already part of the workspace
-	var existingUserID int
-	err = db.DB.QueryRow("SELECT user_id FROM workspace_users WHERE user_id = ? AND workspace_id = ? AND deleted_at IS NULL", userID, workspaceID).Scan(&existingUserID)
-	if err == nil {
+	isMember, err := stores.Workspaces.IsWorkspaceMember(r.Context(), workspaceID, userID)
+	if err != nil {
+		http.Error(w, "Error checking workspace membership", http.StatusInternalServerError)
+		return
+	}
+	if isMember {
 		http.Error(w, "User is already part of this workspace", http.StatusConflict)
 		return
 	}
 
 	// Add the user to the workspace
-	_, err = db.DB.Exec("INSERT INTO workspace_users (user_id, workspace_id) VALUES (?, ?)", userID, workspaceID)
+	err = stores.Workspaces.AddWorkspaceMember(r.Context(), workspaceID, userID, req.Role)
 	if err != nil {
 		http.Error(w, "Error adding user to workspace", http.StatusInternalServerError)
 		return
@@ -65,3 +89,47 @@ func AddUserToWorkspace(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
+
+// UpdateMemberRole changes the role of a member of the workspace
+func UpdateMemberRole(w http.ResponseWriter, r *http.Request) {
+	// Get workspace ID and user ID from URL parameters
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+	userID, err := strconv.Atoi(vars["user_id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req MemberRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if !store.ValidMemberRole(req.Role) {
+		http.Error(w, "Invalid role", http.StatusBadRequest)
+		return
+	}
+
+	err = stores.Workspaces.SetWorkspaceMemberRole(r.Context(), workspaceID, userID, req.Role)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "User is not part of this workspace", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error updating member role", http.StatusInternalServerError)
+		return
+	}
+
+	response := defaultResponse{
+		Data:   "Member role successfully updated",
+		Status: "Success",
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -3,10 +3,10 @@ package workspaces
 import (
 	"encoding/json"
 	"net/http"
-	"time"
+	"strings"
 
-	"shopping_list/db"
 	"shopping_list/middleware"
+	"shopping_list/money"
 )
 
 func CreateWorkspace(w http.ResponseWriter, r *http.Request) {
@@ -16,30 +16,29 @@ func CreateWorkspace(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user ID from the token
-	userID, err := middleware.ExtractUserIDFromToken(r)
-	if err != nil {
+	// Get the authenticated user from the request context
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Insert the new workspace into the database
-	result, err := db.DB.Exec("INSERT INTO workspaces (name, user_id, created_at, updated_at) VALUES (?, ?, ?, ?)", workspace.Name, userID, time.Now(), time.Now())
-	if err != nil {
-		http.Error(w, "Error creating workspace", http.StatusInternalServerError)
-		return
+	// Prices are kept in the minor unit of the workspace currency
+	if workspace.Currency == "" {
+		workspace.Currency = money.DefaultCurrency
 	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		http.Error(w, "Error retrieving workspace ID", http.StatusInternalServerError)
+	workspace.Currency = strings.ToUpper(workspace.Currency)
+	if !money.ValidCurrency(workspace.Currency) {
+		http.Error(w, "Invalid currency, expected an ISO 4217 code such as USD", http.StatusBadRequest)
 		return
 	}
 
-	workspace.ID = int(id)
+	// Insert the new workspace into the database
 	workspace.UserID = userID
-	workspace.CreatedAt = time.Now()
-	workspace.UpdatedAt = time.Now()
+	if err := stores.Workspaces.CreateWorkspace(r.Context(), &workspace); err != nil {
+		http.Error(w, "Error creating workspace", http.StatusInternalServerError)
+		return
+	}
 
 	response := defaultResponse{
 		Data:   workspace,
@@ -3,9 +3,8 @@ package workspaces
 import (
 	"encoding/json"
 	"net/http"
-	"time"
+	"strconv"
 
-	"shopping_list/db"
 	"shopping_list/middleware"
 
 	"github.com/gorilla/mux"
@@ -14,17 +13,21 @@ import (
 func DeleteWorkspace(w http.ResponseWriter, r *http.Request) {
 	// Get workspace ID from URL parameters
 	vars := mux.Vars(r)
-	workspaceID := vars["workspace_id"]
-
-	// Get user ID from the token
-	userID, err := middleware.ExtractUserIDFromToken(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
 	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get the authenticated user from the request context
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	// Soft delete the workspace by setting deleted_at
-	_, err = db.DB.Exec("UPDATE workspaces SET deleted_at = ? WHERE id = ? AND user_id = ?", time.Now(), workspaceID, userID)
+	err = stores.Workspaces.DeleteWorkspace(r.Context(), workspaceID, userID)
 	if err != nil {
 		http.Error(w, "Error deleting workspace", http.StatusInternalServerError)
 		return
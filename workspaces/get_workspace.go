@@ -3,27 +3,21 @@ package workspaces
 import (
 	"encoding/json"
 	"net/http"
-
-	"shopping_list/db"
-	"shopping_list/middleware"
+	"strconv"
 
 	"github.com/gorilla/mux"
 )
 
 func GetWorkspace(w http.ResponseWriter, r *http.Request) {
-	// Get user ID from the token
-	userID, err := middleware.ExtractUserIDFromToken(r)
+	// Get workspace ID from URL parameters
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
 	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
 		return
 	}
 
-	// Get workspace ID from URL parameters
-	vars := mux.Vars(r)
-	workspaceID := vars["workspace_id"]
-
-	var workspace Workspace
-	err = db.DB.QueryRow("SELECT id, name, created_at, updated_at, deleted_at, user_id FROM workspaces WHERE id = ? AND user_id = ? AND deleted_at IS NULL", workspaceID, userID).Scan(&workspace.ID, &workspace.Name, &workspace.CreatedAt, &workspace.UpdatedAt, &workspace.DeletedAt, &workspace.UserID)
+	workspace, err := stores.Workspaces.GetWorkspace(r.Context(), workspaceID)
 	if err != nil {
 		http.Error(w, "Workspace not found", http.StatusNotFound)
 		return
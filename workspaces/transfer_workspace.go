@@ -0,0 +1,108 @@
+package workspaces
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"shopping_list/middleware"
+	"shopping_list/store"
+
+	"github.com/gorilla/mux"
+)
+
+// TransferWorkspaceRequest represents the request body for transferring the ownership of a workspace
+type TransferWorkspaceRequest struct {
+	UserID int `json:"user_id"`
+}
+
+// TransferWorkspace hands the workspace to one of its members. The previous owner stays as an editor.
+func TransferWorkspace(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated user from the request context
+	loggedInUserID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get workspace ID from URL parameters
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	var req TransferWorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == 0 {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if req.UserID == loggedInUserID {
+		http.Error(w, "You already own this workspace", http.StatusBadRequest)
+		return
+	}
+
+	err = stores.Workspaces.TransferWorkspaceOwnership(r.Context(), workspaceID, loggedInUserID, req.UserID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "The new owner must be a member of this workspace", http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error transferring workspace", http.StatusInternalServerError)
+		return
+	}
+
+	workspace, err := stores.Workspaces.GetWorkspace(r.Context(), workspaceID)
+	if err != nil {
+		http.Error(w, "Error fetching workspace", http.StatusInternalServerError)
+		return
+	}
+
+	response := defaultResponse{
+		Data:   workspace,
+		Status: "Success",
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// LeaveWorkspace removes the authenticated member from the workspace.
+// The owner has to transfer or delete the workspace instead.
+func LeaveWorkspace(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated user from the request context
+	loggedInUserID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get workspace ID from URL parameters
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	role, _ := middleware.WorkspaceRoleFromContext(r.Context())
+	if role == store.RoleOwner {
+		http.Error(w, "The owner cannot leave the workspace, transfer the ownership or delete the workspace instead", http.StatusConflict)
+		return
+	}
+
+	if err := stores.Workspaces.RemoveWorkspaceMember(r.Context(), workspaceID, loggedInUserID); err != nil {
+		http.Error(w, "Error leaving workspace", http.StatusInternalServerError)
+		return
+	}
+
+	response := defaultResponse{
+		Data:   "Successfully left the workspace",
+		Status: "Success",
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,170 @@
+package workspaces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"shopping_list/middleware"
+	"shopping_list/store"
+
+	"github.com/gorilla/mux"
+)
+
+// setup injects a memory store with a workspace of the owner, an editor of it and a user who is not a member
+func setup(t *testing.T) (s *store.Stores, workspace *store.Workspace, owner, editor, stranger *store.User) {
+	t.Helper()
+	ctx := context.Background()
+	s = store.NewMemory()
+	SetStores(s)
+	middleware.SetStores(s)
+
+	owner = &store.User{Email: "owner@example.com", Name: "Owner"}
+	editor = &store.User{Email: "editor@example.com", Name: "Editor"}
+	stranger = &store.User{Email: "stranger@example.com", Name: "Stranger"}
+	for _, user := range []*store.User{owner, editor, stranger} {
+		if err := s.Users.CreateUser(ctx, user); err != nil {
+			t.Fatalf("CreateUser: %s", err)
+		}
+	}
+	workspace = &store.Workspace{Name: "home", UserID: owner.ID}
+	if err := s.Workspaces.CreateWorkspace(ctx, workspace); err != nil {
+		t.Fatalf("CreateWorkspace: %s", err)
+	}
+	if err := s.Workspaces.AddWorkspaceMember(ctx, workspace.ID, editor.ID, store.RoleEditor); err != nil {
+		t.Fatalf("AddWorkspaceMember: %s", err)
+	}
+	return s, workspace, owner, editor, stranger
+}
+
+// serve sends a request as the user through the workspace checks of the route
+func serve(action string, permission middleware.Permission, handler http.HandlerFunc, workspace *store.Workspace, user *store.User, body string) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	router.HandleFunc("/workspaces/{workspace_id}/"+action, middleware.WorkspaceMiddleware(middleware.RequirePermission(permission, handler))).Methods(http.MethodPost)
+
+	path := fmt.Sprintf("/workspaces/%d/%s", workspace.ID, action)
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	r = r.WithContext(middleware.WithPrincipal(r.Context(), &middleware.Principal{UserID: user.ID, Email: user.Email, Name: user.Name}))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func transfer(workspace *store.Workspace, user *store.User, newOwnerID int) *httptest.ResponseRecorder {
+	body := fmt.Sprintf(`{"user_id":%d}`, newOwnerID)
+	return serve("transfer", middleware.PermTransferOwner, TransferWorkspace, workspace, user, body)
+}
+
+func leave(workspace *store.Workspace, user *store.User) *httptest.ResponseRecorder {
+	return serve("leave", middleware.PermViewWorkspace, LeaveWorkspace, workspace, user, "")
+}
+
+// role returns the role of the user in the workspace, or "" if they have no access
+func role(t *testing.T, s *store.Stores, workspace *store.Workspace, user *store.User) store.Role {
+	t.Helper()
+	role, err := s.Workspaces.GetWorkspaceRole(context.Background(), workspace.ID, user.ID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("GetWorkspaceRole: %s", err)
+	}
+	return role
+}
+
+func TestTransferWorkspace(t *testing.T) {
+	s, workspace, owner, editor, _ := setup(t)
+
+	if w := transfer(workspace, owner, editor.ID); w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if got := role(t, s, workspace, editor); got != store.RoleOwner {
+		t.Errorf("role of the new owner = %q, want %q", got, store.RoleOwner)
+	}
+	// The previous owner stays as an editor and can no longer transfer the workspace
+	if got := role(t, s, workspace, owner); got != store.RoleEditor {
+		t.Errorf("role of the previous owner = %q, want %q", got, store.RoleEditor)
+	}
+	if w := transfer(workspace, owner, owner.ID); w.Code != http.StatusForbidden {
+		t.Errorf("transfer by the previous owner status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestTransferWorkspaceRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		// newOwner returns the user the workspace is transferred to
+		newOwner func(owner, editor, stranger *store.User) int
+		status   int
+	}{
+		{"to a non-member", func(owner, editor, stranger *store.User) int { return stranger.ID }, http.StatusUnprocessableEntity},
+		{"to an unknown user", func(owner, editor, stranger *store.User) int { return stranger.ID + 1 }, http.StatusUnprocessableEntity},
+		{"to the owner", func(owner, editor, stranger *store.User) int { return owner.ID }, http.StatusBadRequest},
+		{"to nobody", func(owner, editor, stranger *store.User) int { return 0 }, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, workspace, owner, editor, stranger := setup(t)
+			if w := transfer(workspace, owner, tt.newOwner(owner, editor, stranger)); w.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if got := role(t, s, workspace, owner); got != store.RoleOwner {
+				t.Errorf("role of the owner = %q, want %q", got, store.RoleOwner)
+			}
+			if got := role(t, s, workspace, stranger); got != "" {
+				t.Errorf("role of the non-member = %q, want none", got)
+			}
+		})
+	}
+}
+
+func TestTransferWorkspaceByEditor(t *testing.T) {
+	s, workspace, owner, editor, _ := setup(t)
+	if w := transfer(workspace, editor, editor.ID); w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if got := role(t, s, workspace, owner); got != store.RoleOwner {
+		t.Errorf("role of the owner = %q, want %q", got, store.RoleOwner)
+	}
+}
+
+func TestLeaveWorkspace(t *testing.T) {
+	s, workspace, owner, editor, stranger := setup(t)
+
+	if w := leave(workspace, owner); w.Code != http.StatusConflict {
+		t.Errorf("owner leaving status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if got := role(t, s, workspace, owner); got != store.RoleOwner {
+		t.Errorf("role of the owner = %q, want %q", got, store.RoleOwner)
+	}
+
+	if w := leave(workspace, stranger); w.Code != http.StatusForbidden {
+		t.Errorf("non-member leaving status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	if w := leave(workspace, editor); w.Code != http.StatusOK {
+		t.Fatalf("editor leaving status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if got := role(t, s, workspace, editor); got != "" {
+		t.Errorf("role of the editor who left = %q, want none", got)
+	}
+}
+
+func TestLeaveWorkspaceAfterTransfer(t *testing.T) {
+	s, workspace, owner, editor, _ := setup(t)
+	if w := transfer(workspace, owner, editor.ID); w.Code != http.StatusOK {
+		t.Fatalf("transfer status = %d: %s", w.Code, w.Body)
+	}
+
+	// The new owner is held to the workspace, the previous one is free to go
+	if w := leave(workspace, editor); w.Code != http.StatusConflict {
+		t.Errorf("new owner leaving status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if w := leave(workspace, owner); w.Code != http.StatusOK {
+		t.Fatalf("previous owner leaving status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if got := role(t, s, workspace, owner); got != "" {
+		t.Errorf("role of the previous owner = %q, want none", got)
+	}
+}
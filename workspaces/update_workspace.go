@@ -2,11 +2,14 @@ package workspaces
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"time"
+	"strconv"
+	"strings"
 
-	"shopping_list/db"
 	"shopping_list/middleware"
+	"shopping_list/money"
+	"shopping_list/store"
 
 	"github.com/gorilla/mux"
 )
@@ -18,24 +21,48 @@ func UpdateWorkspace(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user ID from the token
-	userID, err := middleware.ExtractUserIDFromToken(r)
-	if err != nil {
+	// Get the authenticated user from the request context
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	// Get workspace ID from URL parameters
 	vars := mux.Vars(r)
-	workspaceID := vars["workspace_id"]
-
-	// Update the workspace in the database
-	_, err = db.DB.Exec("UPDATE workspaces SET name = ?, updated_at = ? WHERE id = ? AND user_id = ? AND deleted_at IS NULL", workspace.Name, time.Now(), workspaceID, userID)
+	workspaceID, err := strconv.Atoi(vars["workspace_id"])
 	if err != nil {
-		http.Error(w, "Error updating workspace", http.StatusInternalServerError)
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
 		return
 	}
 
+	// The currency can only change while no product is priced in the current one
+	if workspace.Currency != "" {
+		workspace.Currency = strings.ToUpper(workspace.Currency)
+		if !money.ValidCurrency(workspace.Currency) {
+			http.Error(w, "Invalid currency, expected an ISO 4217 code such as USD", http.StatusBadRequest)
+			return
+		}
+		err := stores.Workspaces.SetWorkspaceCurrency(r.Context(), workspaceID, workspace.Currency)
+		if errors.Is(err, store.ErrCurrencyInUse) {
+			http.Error(w, "The currency cannot change while the workspace has products", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error updating workspace", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Update the workspace in the database, a request changing only the currency keeps the name
+	if workspace.Name != "" || workspace.Currency == "" {
+		err = stores.Workspaces.UpdateWorkspace(r.Context(), workspaceID, userID, workspace.Name)
+		if err != nil {
+			http.Error(w, "Error updating workspace", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	response := defaultResponse{
 		Data:   workspace,
 		Status: "Success",